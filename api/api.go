@@ -7,12 +7,18 @@ import (
 
 type Api struct {
 	devicesHdl handler.DevicesHandler
+	groupsHdl  handler.GroupsHandler
+	eventsHdl  handler.DeviceEventsHandler
+	backupHdl  handler.BackupHandler
 	srvInfoHdl srv_info_hdl.SrvInfoHandler
 }
 
-func New(devicesHdl handler.DevicesHandler, srvInfoHdl srv_info_hdl.SrvInfoHandler) *Api {
+func New(devicesHdl handler.DevicesHandler, groupsHdl handler.GroupsHandler, eventsHdl handler.DeviceEventsHandler, backupHdl handler.BackupHandler, srvInfoHdl srv_info_hdl.SrvInfoHandler) *Api {
 	return &Api{
 		devicesHdl: devicesHdl,
+		groupsHdl:  groupsHdl,
+		eventsHdl:  eventsHdl,
+		backupHdl:  backupHdl,
 		srvInfoHdl: srvInfoHdl,
 	}
 }
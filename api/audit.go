@@ -0,0 +1,10 @@
+package api
+
+import (
+	"context"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+)
+
+func (a *Api) GetAuditLog(ctx context.Context, filter lib_model.AuditLogFilter) ([]lib_model.AuditEntry, error) {
+	return a.devicesHdl.GetAuditLog(ctx, filter)
+}
@@ -0,0 +1,15 @@
+package api
+
+import (
+	"context"
+	"errors"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"io"
+)
+
+func (a *Api) GetBackup(ctx context.Context) (io.ReadCloser, int64, error) {
+	if a.backupHdl == nil {
+		return nil, 0, lib_model.NewInternalError(errors.New("backup not supported by database"))
+	}
+	return a.backupHdl.Backup(ctx)
+}
@@ -9,14 +9,22 @@ func (a *Api) GetDevice(ctx context.Context, id string) (lib_model.Device, error
 	return a.devicesHdl.Get(ctx, id)
 }
 
-func (a *Api) GetDevices(ctx context.Context, filter lib_model.DevicesFilter) (map[string]lib_model.Device, error) {
+func (a *Api) GetDevices(ctx context.Context, filter lib_model.DevicesFilter) ([]lib_model.Device, int, error) {
 	return a.devicesHdl.GetAll(ctx, filter)
 }
 
-func (a *Api) DeleteDevice(ctx context.Context, id string) error {
-	return a.devicesHdl.Delete(ctx, id)
+func (a *Api) DeleteDevice(ctx context.Context, id, ifMatch string) error {
+	return a.devicesHdl.Delete(ctx, id, ifMatch)
 }
 
-func (a *Api) UpdateDeviceUserData(ctx context.Context, id string, userDataBase lib_model.DeviceUserDataBase) error {
-	return a.devicesHdl.SetUserData(ctx, id, userDataBase)
+func (a *Api) GetDeviceStateHistory(ctx context.Context, id string, filter lib_model.DeviceStateHistoryFilter) ([]lib_model.DeviceStateTransition, error) {
+	return a.devicesHdl.GetStateHistory(ctx, id, filter)
+}
+
+func (a *Api) PatchDeviceUserData(ctx context.Context, id, ifMatch string, patch lib_model.DeviceUserDataPatch) error {
+	return a.devicesHdl.PatchUserData(ctx, id, ifMatch, patch)
+}
+
+func (a *Api) UpdateDeviceUserData(ctx context.Context, id, ifMatch string, userDataBase lib_model.DeviceUserDataBase) error {
+	return a.devicesHdl.SetUserData(ctx, id, ifMatch, userDataBase)
 }
@@ -0,0 +1,15 @@
+package api
+
+import (
+	"context"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+)
+
+func (a *Api) GetDeviceEvents(ctx context.Context, filter lib_model.DevicesFilter) (<-chan lib_model.DeviceEvent, error) {
+	id, events := a.eventsHdl.Subscribe(filter)
+	go func() {
+		<-ctx.Done()
+		a.eventsHdl.Unsubscribe(id)
+	}()
+	return events, nil
+}
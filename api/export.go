@@ -0,0 +1,14 @@
+package api
+
+import (
+	"context"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+)
+
+func (a *Api) Export(ctx context.Context) (lib_model.Export, error) {
+	return a.devicesHdl.Export(ctx)
+}
+
+func (a *Api) Import(ctx context.Context, mode lib_model.ImportMode, export lib_model.Export) (lib_model.ImportResult, error) {
+	return a.devicesHdl.Import(ctx, mode, export)
+}
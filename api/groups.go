@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+)
+
+func (a *Api) GetGroups(ctx context.Context) ([]lib_model.Group, error) {
+	return a.groupsHdl.GetAll(ctx)
+}
+
+func (a *Api) GetGroup(ctx context.Context, id string) (lib_model.Group, error) {
+	return a.groupsHdl.Get(ctx, id)
+}
+
+func (a *Api) CreateGroup(ctx context.Context, groupBase lib_model.GroupBase) (string, error) {
+	return a.groupsHdl.Create(ctx, groupBase)
+}
+
+func (a *Api) UpdateGroup(ctx context.Context, id string, groupBase lib_model.GroupBase) error {
+	return a.groupsHdl.Update(ctx, id, groupBase)
+}
+
+func (a *Api) DeleteGroup(ctx context.Context, id string) error {
+	return a.groupsHdl.Delete(ctx, id)
+}
+
+func (a *Api) GetGroupDevices(ctx context.Context, id string) ([]lib_model.Device, error) {
+	if _, err := a.groupsHdl.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	devices, _, err := a.devicesHdl.GetAll(ctx, lib_model.DevicesFilter{Group: id})
+	return devices, err
+}
+
+func (a *Api) SetGroupDevices(ctx context.Context, id string, deviceIDs []string) error {
+	return a.groupsHdl.SetDevices(ctx, id, deviceIDs)
+}
+
+func (a *Api) AddGroupDevice(ctx context.Context, id, deviceID string) error {
+	return a.groupsHdl.AddDevice(ctx, id, deviceID)
+}
+
+func (a *Api) RemoveGroupDevice(ctx context.Context, id, deviceID string) error {
+	return a.groupsHdl.RemoveDevice(ctx, id, deviceID)
+}
@@ -0,0 +1,14 @@
+package api
+
+import (
+	"context"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+)
+
+func (a *Api) GetRefs(ctx context.Context) ([]lib_model.RefInfo, error) {
+	return a.devicesHdl.GetRefs(ctx)
+}
+
+func (a *Api) DeleteRefDevices(ctx context.Context, ref string) error {
+	return a.devicesHdl.DeleteRef(ctx, ref)
+}
@@ -0,0 +1,14 @@
+package api
+
+import (
+	"context"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+)
+
+func (a *Api) GetTrash(ctx context.Context) ([]lib_model.DeletedDevice, error) {
+	return a.devicesHdl.GetTrash(ctx)
+}
+
+func (a *Api) RestoreDevice(ctx context.Context, id string) error {
+	return a.devicesHdl.Restore(ctx, id)
+}
@@ -0,0 +1,73 @@
+package backup_hdl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/SENERGY-Platform/mgw-device-manager/util/db"
+	"io"
+	"os"
+	"path"
+	"sync"
+)
+
+type Handler struct {
+	db      *sql.DB
+	workDir string
+	mu      sync.Mutex
+}
+
+// New creates a handler that writes snapshots to temporary files in workDir, which should reside on the same file system as the database.
+func New(db *sql.DB, workDir string) *Handler {
+	return &Handler{
+		db:      db,
+		workDir: workDir,
+	}
+}
+
+// Backup creates a snapshot of the database, the snapshot file is removed when the returned reader is closed.
+func (h *Handler) Backup(ctx context.Context) (io.ReadCloser, int64, error) {
+	if !h.mu.TryLock() {
+		return nil, 0, lib_model.NewResourceBusyError(errors.New("backup in progress"))
+	}
+	dir, err := os.MkdirTemp(h.workDir, "backup_")
+	if err != nil {
+		h.mu.Unlock()
+		return nil, 0, lib_model.NewInternalError(fmt.Errorf("create backup: %s", err))
+	}
+	cleanup := func() {
+		_ = os.RemoveAll(dir)
+		h.mu.Unlock()
+	}
+	p := path.Join(dir, "snapshot.db")
+	if err = db.Backup(ctx, h.db, p); err != nil {
+		cleanup()
+		return nil, 0, lib_model.NewInternalError(fmt.Errorf("create backup: %s", err))
+	}
+	file, err := os.Open(p)
+	if err != nil {
+		cleanup()
+		return nil, 0, lib_model.NewInternalError(fmt.Errorf("create backup: %s", err))
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		cleanup()
+		return nil, 0, lib_model.NewInternalError(fmt.Errorf("create backup: %s", err))
+	}
+	return &snapshot{File: file, cleanup: cleanup}, info.Size(), nil
+}
+
+type snapshot struct {
+	*os.File
+	cleanup func()
+	once    sync.Once
+}
+
+func (s *snapshot) Close() error {
+	err := s.File.Close()
+	s.once.Do(s.cleanup)
+	return err
+}
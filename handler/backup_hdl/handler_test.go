@@ -0,0 +1,52 @@
+package backup_hdl
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/mgw-device-manager/util/db"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestHandler_Backup(t *testing.T) {
+	dir := t.TempDir()
+	testDB, err := db.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer testDB.Close()
+	if _, err = testDB.Exec("CREATE TABLE a (id TEXT NOT NULL);"); err != nil {
+		t.Fatal(err)
+	}
+	workDir := t.TempDir()
+	h := New(testDB, workDir)
+	rc, size, err := h.Backup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = h.Backup(context.Background()); err == nil {
+		t.Error("expected error")
+	}
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(b)) != size || size == 0 {
+		t.Error("expected", size, "bytes, got", len(b))
+	}
+	if err = rc.Close(); err != nil {
+		t.Error(err)
+	}
+	entries, err := os.ReadDir(workDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Error("snapshot not removed", entries)
+	}
+	rc, _, err = h.Backup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = rc.Close()
+}
@@ -0,0 +1,58 @@
+package devices_hdl
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/SENERGY-Platform/mgw-device-manager/util"
+	"time"
+)
+
+func (h *Handler) GetAuditLog(ctx context.Context, filter lib_model.AuditLogFilter) ([]lib_model.AuditEntry, error) {
+	if h.auditStgHdl == nil {
+		return nil, lib_model.NewInternalError(errors.New("audit log not supported by storage"))
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return nil, lib_model.NewInvalidInputError(errors.New("end of time range before start"))
+	}
+	if filter.Limit < 0 {
+		return nil, lib_model.NewInvalidInputError(errors.New("invalid limit"))
+	}
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	entries, err := h.auditStgHdl.ReadAuditLog(ctxWt, filter)
+	if err != nil {
+		return nil, fmt.Errorf("get audit log: %s", err)
+	}
+	return entries, nil
+}
+
+// auditing returns true if the storage handler supports the audit log and the change was initiated by a user.
+func (h *Handler) auditing(ctx context.Context) bool {
+	if h.auditStgHdl == nil {
+		return false
+	}
+	_, ok := util.RequestInfoFromContext(ctx)
+	return ok
+}
+
+// audit records a change of the user data, changes not initiated by a user, e.g. via device messages, are not recorded.
+func (h *Handler) audit(ctx context.Context, tx driver.Tx, action lib_model.AuditAction, id string, before, after *lib_model.DeviceUserData, timestamp time.Time) error {
+	if !h.auditing(ctx) {
+		return nil
+	}
+	info, _ := util.RequestInfoFromContext(ctx)
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	return h.auditStgHdl.CreateAuditEntry(ctxWt, tx, lib_model.AuditEntry{
+		Timestamp: timestamp,
+		RequestID: info.ID,
+		Caller:    info.Caller,
+		Action:    action,
+		DeviceID:  id,
+		Before:    before,
+		After:     after,
+	})
+}
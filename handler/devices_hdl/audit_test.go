@@ -0,0 +1,117 @@
+package devices_hdl
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/SENERGY-Platform/mgw-device-manager/util"
+	"testing"
+	"time"
+)
+
+func TestHandler_audit(t *testing.T) {
+	id := "1"
+	deviceData := lib_model.DeviceDataBase{ID: id, Ref: "a", Name: "test", Type: "x"}
+	stgHdl := &stgHdlAuditMock{
+		stgHdlTrashMock: stgHdlTrashMock{
+			stgHdlTxMock: stgHdlTxMock{stgHdlStatesMock: stgHdlStatesMock{
+				stgHdlMock: stgHdlMock{devices: make(map[string]lib_model.DeviceBase)},
+				states:     make(map[string]handler.DeviceStateData),
+			}},
+			trash: make(map[string]lib_model.DeletedDevice),
+		},
+	}
+	h := New(stgHdl, time.Second)
+	if err := h.Put(context.Background(), deviceData, lib_model.Online); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SetUserData(context.Background(), id, "", lib_model.DeviceUserDataBase{Name: "lamp"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(stgHdl.entries) != 0 {
+		t.Fatal("expected no entries for changes without request info, got", len(stgHdl.entries))
+	}
+	ctx := util.ContextWithRequestInfo(context.Background(), util.RequestInfo{ID: "req", Caller: "user"})
+	t.Run("set user data", func(t *testing.T) {
+		if err := h.SetUserData(ctx, id, "", lib_model.DeviceUserDataBase{Name: "kitchen lamp"}); err != nil {
+			t.Fatal(err)
+		}
+		if len(stgHdl.entries) != 1 {
+			t.Fatal("expected 1 entry, got", len(stgHdl.entries))
+		}
+		entry := stgHdl.entries[0]
+		if entry.Action != lib_model.AuditSetUserData || entry.DeviceID != id || entry.RequestID != "req" || entry.Caller != "user" {
+			t.Error("invalid entry", entry)
+		}
+		if entry.Before == nil || entry.Before.Name != "lamp" || entry.After == nil || entry.After.Name != "kitchen lamp" {
+			t.Error("invalid user data", entry)
+		}
+		if stgHdl.noDeadlineC != 0 {
+			t.Error("expected transaction with deadline")
+		}
+	})
+	t.Run("delete", func(t *testing.T) {
+		if err := h.Delete(ctx, id, ""); err != nil {
+			t.Fatal(err)
+		}
+		if len(stgHdl.entries) != 2 {
+			t.Fatal("expected 2 entries, got", len(stgHdl.entries))
+		}
+		entry := stgHdl.entries[1]
+		if entry.Action != lib_model.AuditDelete || entry.Before == nil || entry.Before.Name != "kitchen lamp" || entry.After != nil {
+			t.Error("invalid entry", entry)
+		}
+		if stgHdl.noDeadlineC != 0 {
+			t.Error("expected transactions with deadline")
+		}
+	})
+	t.Run("restore", func(t *testing.T) {
+		if err := h.Restore(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+		if len(stgHdl.entries) != 3 {
+			t.Fatal("expected 3 entries, got", len(stgHdl.entries))
+		}
+		entry := stgHdl.entries[2]
+		if entry.Action != lib_model.AuditRestore || entry.Before != nil || entry.After == nil || entry.After.Name != "kitchen lamp" {
+			t.Error("invalid entry", entry)
+		}
+		if stgHdl.noDeadlineC != 0 {
+			t.Error("expected transactions with deadline")
+		}
+	})
+	t.Run("get audit log", func(t *testing.T) {
+		entries, err := h.GetAuditLog(context.Background(), lib_model.AuditLogFilter{DeviceID: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 3 {
+			t.Error("expected 3 entries, got", len(entries))
+		}
+		_, err = h.GetAuditLog(context.Background(), lib_model.AuditLogFilter{From: time.Now(), To: time.Now().Add(-time.Hour)})
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+type stgHdlAuditMock struct {
+	stgHdlTrashMock
+	entries []lib_model.AuditEntry
+}
+
+func (m *stgHdlAuditMock) CreateAuditEntry(_ context.Context, _ driver.Tx, entry lib_model.AuditEntry) error {
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func (m *stgHdlAuditMock) ReadAuditLog(_ context.Context, filter lib_model.AuditLogFilter) ([]lib_model.AuditEntry, error) {
+	var entries []lib_model.AuditEntry
+	for _, entry := range m.entries {
+		if filter.DeviceID == "" || entry.DeviceID == filter.DeviceID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
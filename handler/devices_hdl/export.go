@@ -0,0 +1,170 @@
+package devices_hdl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"time"
+)
+
+func (h *Handler) Export(ctx context.Context) (lib_model.Export, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	deviceBases, _, err := h.stgHdl.ReadAll(ctxWt, lib_model.DevicesFilter{})
+	if err != nil {
+		return lib_model.Export{}, fmt.Errorf("export devices: %s", err)
+	}
+	devices := make([]lib_model.ExportDevice, 0, len(deviceBases))
+	for _, deviceBase := range deviceBases {
+		devices = append(devices, lib_model.ExportDevice{
+			DeviceData: deviceBase.DeviceData,
+			UserData:   deviceBase.UserData,
+		})
+	}
+	return lib_model.Export{
+		Version: lib_model.ExportVersion,
+		Created: time.Now().UTC(),
+		Devices: devices,
+	}, nil
+}
+
+// Import applies an export document in one storage transaction according to the import mode.
+func (h *Handler) Import(ctx context.Context, mode lib_model.ImportMode, export lib_model.Export) (lib_model.ImportResult, error) {
+	if err := validateImport(mode, export); err != nil {
+		return lib_model.ImportResult{}, lib_model.NewInvalidInputError(err)
+	}
+	h.lock()
+	defer h.unlock()
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	deviceBases, _, err := h.stgHdl.ReadAll(ctxWt, lib_model.DevicesFilter{})
+	if err != nil {
+		return lib_model.ImportResult{}, fmt.Errorf("import devices: %s", err)
+	}
+	existing := make(map[string]lib_model.DeviceBase)
+	for _, deviceBase := range deviceBases {
+		existing[deviceBase.ID] = deviceBase
+	}
+	ctxWt2, cf2 := context.WithTimeout(ctx, h.timeout)
+	defer cf2()
+	tx, err := h.stgHdl.BeginTransaction(ctxWt2)
+	if err != nil {
+		return lib_model.ImportResult{}, fmt.Errorf("import devices: %s", err)
+	}
+	defer tx.Rollback()
+	timestamp := time.Now().UTC()
+	var result lib_model.ImportResult
+	var created, updated, deleted []lib_model.DeviceBase
+	imported := make(map[string]struct{})
+	for _, item := range export.Devices {
+		imported[item.ID] = struct{}{}
+		device, ok := existing[item.ID]
+		if !ok && mode == lib_model.ImportUserData {
+			result.Skipped++
+			continue
+		}
+		var before *lib_model.DeviceUserData
+		if ok {
+			before = &device.UserData
+		}
+		if mode != lib_model.ImportUserData {
+			if ok {
+				device.DeviceDataBase = item.DeviceDataBase
+				device.Updated = timestamp
+				err = h.stgHdl.Update(ctxWt2, tx, device.DeviceData)
+			} else {
+				device.DeviceData = item.DeviceData
+				if device.Created.IsZero() {
+					device.Created = timestamp
+				}
+				err = h.stgHdl.Create(ctxWt2, tx, device.DeviceData)
+			}
+			if err != nil {
+				return lib_model.ImportResult{}, fmt.Errorf("import devices: %s", err)
+			}
+		}
+		device.UserData = lib_model.DeviceUserData{
+			DeviceUserDataBase: item.UserData.DeviceUserDataBase,
+			Updated:            timestamp,
+		}
+		if err = h.stgHdl.UpdateUserData(ctxWt2, tx, device.ID, device.UserData); err != nil {
+			return lib_model.ImportResult{}, fmt.Errorf("import devices: %s", err)
+		}
+		if err = h.audit(ctxWt2, tx, lib_model.AuditSetUserData, device.ID, before, &device.UserData, timestamp); err != nil {
+			return lib_model.ImportResult{}, fmt.Errorf("import devices: %s", err)
+		}
+		if ok {
+			updated = append(updated, device)
+		} else {
+			created = append(created, device)
+		}
+	}
+	if mode == lib_model.ImportReplace {
+		for _, deviceBase := range deviceBases {
+			if _, ok := imported[deviceBase.ID]; ok {
+				continue
+			}
+			if err = h.remove(ctxWt2, tx, deviceBase); err != nil {
+				return lib_model.ImportResult{}, fmt.Errorf("import devices: %s", err)
+			}
+			deleted = append(deleted, deviceBase)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return lib_model.ImportResult{}, fmt.Errorf("import devices: %s", err)
+	}
+	for _, device := range created {
+		h.publishEvent(lib_model.EventCreated, h.newDevice(device), timestamp)
+	}
+	for _, device := range updated {
+		if sItem, ok := h.states[device.ID]; ok {
+			sItem.ref = device.Ref
+			sItem.typ = device.Type
+			h.states[device.ID] = sItem
+		}
+		h.publishEvent(lib_model.EventUpdated, h.newDevice(device), timestamp)
+	}
+	for _, device := range deleted {
+		h.applyDelete(device, timestamp)
+	}
+	result.Created = len(created)
+	result.Updated = len(updated)
+	result.Deleted = len(deleted)
+	return result, nil
+}
+
+func validateImport(mode lib_model.ImportMode, export lib_model.Export) error {
+	switch mode {
+	case lib_model.ImportUserData, lib_model.ImportMerge, lib_model.ImportReplace:
+	default:
+		return fmt.Errorf("invalid import mode '%s'", mode)
+	}
+	if export.Version != lib_model.ExportVersion {
+		return fmt.Errorf("unsupported export version '%d'", export.Version)
+	}
+	ids := make(map[string]struct{})
+	for i, device := range export.Devices {
+		if device.ID == "" {
+			return fmt.Errorf("device %d: empty id", i)
+		}
+		if _, ok := ids[device.ID]; ok {
+			return fmt.Errorf("device %d (%s): duplicate id", i, device.ID)
+		}
+		ids[device.ID] = struct{}{}
+		if mode != lib_model.ImportUserData {
+			if err := validateDeviceData(device.DeviceDataBase); err != nil {
+				return fmt.Errorf("device %d (%s): %s", i, device.ID, err)
+			}
+		}
+		if err := validateAttributes(device.UserData.Attributes); err != nil {
+			return fmt.Errorf("device %d (%s): user data: %s", i, device.ID, err)
+		}
+	}
+	if mode == lib_model.ImportReplace && len(export.Devices) == 0 {
+		return errors.New("replace with empty import")
+	}
+	return nil
+}
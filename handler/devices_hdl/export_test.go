@@ -0,0 +1,133 @@
+package devices_hdl
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/SENERGY-Platform/mgw-device-manager/util"
+	"testing"
+	"time"
+)
+
+func TestHandler_Import(t *testing.T) {
+	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
+	newHandler := func() (*Handler, *stgHdlTrashMock) {
+		stgHdl := &stgHdlTrashMock{
+			stgHdlTxMock: stgHdlTxMock{stgHdlStatesMock: stgHdlStatesMock{
+				stgHdlMock: stgHdlMock{devices: make(map[string]lib_model.DeviceBase)},
+				states:     make(map[string]handler.DeviceStateData),
+			}},
+			trash: make(map[string]lib_model.DeletedDevice),
+		}
+		h := New(stgHdl, time.Second)
+		for _, dID := range []string{"1", "2"} {
+			if err := h.Put(context.Background(), lib_model.DeviceDataBase{ID: dID, Ref: "a", Name: "test", Type: "x"}, lib_model.Online); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return h, stgHdl
+	}
+	export := lib_model.Export{
+		Version: lib_model.ExportVersion,
+		Devices: []lib_model.ExportDevice{
+			{
+				DeviceData: lib_model.DeviceData{DeviceDataBase: lib_model.DeviceDataBase{ID: "1", Ref: "a", Name: "new", Type: "x"}},
+				UserData:   lib_model.DeviceUserData{DeviceUserDataBase: lib_model.DeviceUserDataBase{Name: "lamp"}},
+			},
+			{
+				DeviceData: lib_model.DeviceData{DeviceDataBase: lib_model.DeviceDataBase{ID: "3", Ref: "b", Type: "y"}},
+				UserData:   lib_model.DeviceUserData{DeviceUserDataBase: lib_model.DeviceUserDataBase{Name: "sensor"}},
+			},
+		},
+	}
+	t.Run("user data", func(t *testing.T) {
+		h, stgHdl := newHandler()
+		result, err := h.Import(context.Background(), lib_model.ImportUserData, export)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != (lib_model.ImportResult{Updated: 1, Skipped: 1}) {
+			t.Error("invalid result", result)
+		}
+		if device := stgHdl.devices["1"]; device.Name != "test" || device.UserData.Name != "lamp" {
+			t.Error("invalid device", device)
+		}
+		if len(stgHdl.devices) != 2 {
+			t.Error("expected 2 devices, got", len(stgHdl.devices))
+		}
+	})
+	t.Run("merge", func(t *testing.T) {
+		h, stgHdl := newHandler()
+		result, err := h.Import(context.Background(), lib_model.ImportMerge, export)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != (lib_model.ImportResult{Created: 1, Updated: 1}) {
+			t.Error("invalid result", result)
+		}
+		if device := stgHdl.devices["1"]; device.Name != "new" || device.UserData.Name != "lamp" {
+			t.Error("invalid device", device)
+		}
+		if device := stgHdl.devices["3"]; device.Created.IsZero() || device.UserData.Name != "sensor" {
+			t.Error("invalid device", device)
+		}
+		if len(stgHdl.devices) != 3 {
+			t.Error("expected 3 devices, got", len(stgHdl.devices))
+		}
+	})
+	t.Run("replace", func(t *testing.T) {
+		h, stgHdl := newHandler()
+		result, err := h.Import(context.Background(), lib_model.ImportReplace, export)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != (lib_model.ImportResult{Created: 1, Updated: 1, Deleted: 1}) {
+			t.Error("invalid result", result)
+		}
+		if _, ok := stgHdl.devices["2"]; ok {
+			t.Error("not deleted")
+		}
+		if _, ok := h.states["2"]; ok {
+			t.Error("state not removed")
+		}
+		if stgHdl.noDeadlineC != 0 {
+			t.Error("expected transaction with deadline")
+		}
+		if len(stgHdl.devices) != 2 {
+			t.Error("expected 2 devices, got", len(stgHdl.devices))
+		}
+	})
+	t.Run("export", func(t *testing.T) {
+		h, _ := newHandler()
+		e, err := h.Export(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Version != lib_model.ExportVersion || len(e.Devices) != 2 {
+			t.Error("invalid export", e)
+		}
+	})
+	t.Run("invalid input", func(t *testing.T) {
+		h, _ := newHandler()
+		if _, err := h.Import(context.Background(), "test", export); err == nil {
+			t.Error("expected error")
+		}
+		if _, err := h.Import(context.Background(), lib_model.ImportMerge, lib_model.Export{Version: 0, Devices: export.Devices}); err == nil {
+			t.Error("expected error")
+		}
+		invalid := lib_model.Export{
+			Version: lib_model.ExportVersion,
+			Devices: []lib_model.ExportDevice{{DeviceData: lib_model.DeviceData{DeviceDataBase: lib_model.DeviceDataBase{ID: "1", Ref: "a"}}}},
+		}
+		if _, err := h.Import(context.Background(), lib_model.ImportMerge, invalid); err == nil {
+			t.Error("expected error")
+		}
+		if _, err := h.Import(context.Background(), lib_model.ImportUserData, invalid); err != nil {
+			t.Error(err)
+		}
+		duplicate := lib_model.Export{Version: lib_model.ExportVersion, Devices: append(export.Devices, export.Devices[0])}
+		if _, err := h.Import(context.Background(), lib_model.ImportMerge, duplicate); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
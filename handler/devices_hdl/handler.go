@@ -2,34 +2,92 @@ package devices_hdl
 
 import (
 	"context"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"github.com/SENERGY-Platform/mgw-device-manager/handler"
 	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
 type stateItem struct {
-	ref   string
-	value lib_model.DeviceState
+	ref       string
+	typ       string
+	value     lib_model.DeviceState
+	updated   time.Time
+	lastSeen  time.Time
+	lastKnown bool
 }
 
 type Handler struct {
-	stgHdl  handler.DevicesStorageHandler
-	timeout time.Duration
-	states  map[string]stateItem
-	mu      sync.RWMutex
+	stgHdl       handler.DevicesStorageHandler
+	statesStgHdl handler.DeviceStatesStorageHandler
+	trashStgHdl  handler.DeviceTrashStorageHandler
+	auditStgHdl  handler.DeviceAuditStorageHandler
+	eventsHdl    handler.DeviceEventsHandler
+	timeout      time.Duration
+	states       map[string]stateItem
+	syncs        map[string]map[string]struct{} // ids of devices announced during a running sync per reference
+	syncDelete   bool
+	refs         map[string]refItem
+	mu           sync.RWMutex // guards the in-memory maps
+	putMu        sync.RWMutex // held shared by Put and exclusively by all other write operations
+	devLocks     *deviceLocks
 }
 
 func New(stgHdl handler.DevicesStorageHandler, timeout time.Duration) *Handler {
+	statesStgHdl, _ := stgHdl.(handler.DeviceStatesStorageHandler)
+	trashStgHdl, _ := stgHdl.(handler.DeviceTrashStorageHandler)
+	auditStgHdl, _ := stgHdl.(handler.DeviceAuditStorageHandler)
 	return &Handler{
-		stgHdl:  stgHdl,
-		timeout: timeout,
-		states:  make(map[string]stateItem),
+		stgHdl:       stgHdl,
+		statesStgHdl: statesStgHdl,
+		trashStgHdl:  trashStgHdl,
+		auditStgHdl:  auditStgHdl,
+		timeout:      timeout,
+		states:       make(map[string]stateItem),
+		syncs:        make(map[string]map[string]struct{}),
+		refs:         make(map[string]refItem),
+		devLocks:     newDeviceLocks(),
 	}
 }
 
+func (h *Handler) SetEventsHandler(eventsHdl handler.DeviceEventsHandler) {
+	h.eventsHdl = eventsHdl
+}
+
+// RestoreStates loads the last known device states from storage if supported by the storage handler.
+func (h *Handler) RestoreStates(ctx context.Context) error {
+	if h.statesStgHdl == nil {
+		return nil
+	}
+	h.lock()
+	defer h.unlock()
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	states, err := h.statesStgHdl.ReadStates(ctxWt)
+	if err != nil {
+		return fmt.Errorf("restore device states: %s", err)
+	}
+	for id, state := range states {
+		if _, ok := h.states[id]; ok {
+			continue
+		}
+		h.states[id] = stateItem{
+			ref:       state.Ref,
+			typ:       state.Type,
+			value:     state.Value,
+			updated:   state.Updated,
+			lastSeen:  state.LastSeen,
+			lastKnown: true,
+		}
+	}
+	return nil
+}
+
 func (h *Handler) Put(ctx context.Context, deviceData lib_model.DeviceDataBase, state lib_model.DeviceState) error {
 	if err := validateDeviceData(deviceData); err != nil {
 		return lib_model.NewInvalidInputError(err)
@@ -37,39 +95,119 @@ func (h *Handler) Put(ctx context.Context, deviceData lib_model.DeviceDataBase,
 	if err := validateState(state); err != nil {
 		return lib_model.NewInvalidInputError(err)
 	}
-	h.mu.Lock()
-	defer h.mu.Unlock()
+	h.putMu.RLock()
+	defer h.putMu.RUnlock()
+	unlock := h.devLocks.lock(deviceData.ID)
+	defer unlock()
+	h.mu.RLock()
+	sItem, ok := h.states[deviceData.ID]
+	h.mu.RUnlock()
 	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
 	defer cf()
+	var existing *lib_model.DeviceBase
 	device, err := h.stgHdl.Read(ctxWt, deviceData.ID)
 	if err != nil {
 		var nfe *lib_model.NotFoundError
 		if !errors.As(err, &nfe) {
 			return fmt.Errorf("put device: %s", err)
 		}
-		ctxWt2, cf2 := context.WithTimeout(ctx, h.timeout)
-		defer cf2()
-		err = h.stgHdl.Create(ctxWt2, nil, lib_model.DeviceData{
-			DeviceDataBase: deviceData,
-			Created:        time.Now().UTC(),
-		})
+	} else {
+		existing = &device
+	}
+	timestamp := time.Now().UTC()
+	item, err := h.put(ctx, nil, existing, deviceData, state, sItem, ok, timestamp)
+	if err != nil {
+		return fmt.Errorf("put device: %s", err)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.applyPut(item, timestamp)
+	return nil
+}
+
+// ApplyBatch applies set and delete messages of a reference in one storage transaction. Invalid messages and
+// deletions of unknown devices are skipped, the returned slice holds the error or nil for each message.
+// If the transaction fails no message is applied and an error is returned. Existing devices are read before the
+// transaction is started.
+func (h *Handler) ApplyBatch(ctx context.Context, ref string, messages []lib_model.DeviceMessage) ([]error, error) {
+	errs := make([]error, len(messages))
+	ids := make(map[string]struct{})
+	for i, msg := range messages {
+		if err := validateMessage(ref, msg); err != nil {
+			errs[i] = lib_model.NewInvalidInputError(err)
+			continue
+		}
+		if _, ok := ids[msg.DeviceID]; ok {
+			errs[i] = lib_model.NewInvalidInputError(errors.New("duplicate device id"))
+			continue
+		}
+		ids[msg.DeviceID] = struct{}{}
+	}
+	h.lock()
+	defer h.unlock()
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	existing := make(map[string]lib_model.DeviceBase)
+	if len(ids) > 0 {
+		idList := make([]string, 0, len(ids))
+		for id := range ids {
+			idList = append(idList, id)
+		}
+		devices, _, err := h.stgHdl.ReadAll(ctxWt, lib_model.DevicesFilter{IDs: idList})
 		if err != nil {
-			return fmt.Errorf("put device: %s", err)
+			return nil, fmt.Errorf("apply batch: %s", err)
 		}
-	} else {
-		device.DeviceDataBase = deviceData
-		device.Updated = time.Now().UTC()
-		ctxWt2, cf2 := context.WithTimeout(ctx, h.timeout)
-		defer cf2()
-		if err = h.stgHdl.Update(ctxWt2, nil, device.DeviceData); err != nil {
-			return fmt.Errorf("put device: %s", err)
+		for _, device := range devices {
+			existing[device.ID] = device
 		}
 	}
-	h.states[deviceData.ID] = stateItem{
-		ref:   deviceData.Ref,
-		value: state,
+	tx, err := h.stgHdl.BeginTransaction(ctxWt)
+	if err != nil {
+		return nil, fmt.Errorf("apply batch: %s", err)
 	}
-	return nil
+	defer tx.Rollback()
+	timestamp := time.Now().UTC()
+	putItems := make(map[int]putItem)
+	deleted := make(map[int]lib_model.DeviceBase)
+	for i, msg := range messages {
+		if errs[i] != nil {
+			continue
+		}
+		device, found := existing[msg.DeviceID]
+		switch msg.Method {
+		case lib_model.Set:
+			var dPtr *lib_model.DeviceBase
+			if found {
+				dPtr = &device
+			}
+			sItem, ok := h.states[msg.DeviceID]
+			item, err := h.put(ctxWt, tx, dPtr, newDeviceDataBase(ref, msg), msg.Data.State, sItem, ok, timestamp)
+			if err != nil {
+				return nil, fmt.Errorf("apply batch: put device (%s): %s", msg.DeviceID, err)
+			}
+			putItems[i] = item
+		case lib_model.Delete:
+			if !found {
+				errs[i] = lib_model.NewNotFoundError(errors.New("device not found"))
+				continue
+			}
+			if err = h.delete(ctxWt, tx, device); err != nil {
+				return nil, fmt.Errorf("apply batch: delete device (%s): %s", msg.DeviceID, err)
+			}
+			deleted[i] = device
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("apply batch: %s", err)
+	}
+	for i := range messages {
+		if item, ok := putItems[i]; ok {
+			h.applyPut(item, timestamp)
+		} else if device, ok := deleted[i]; ok {
+			h.applyDelete(device, timestamp)
+		}
+	}
+	return errs, nil
 }
 
 func (h *Handler) Get(ctx context.Context, id string) (lib_model.Device, error) {
@@ -81,56 +219,122 @@ func (h *Handler) Get(ctx context.Context, id string) (lib_model.Device, error)
 	if err != nil {
 		return lib_model.Device{}, fmt.Errorf("get device: %s", err)
 	}
-	return lib_model.Device{
-		DeviceBase: device,
-		State:      h.getState(id),
-	}, nil
+	return h.newDevice(device), nil
 }
 
-func (h *Handler) GetAll(ctx context.Context, filter lib_model.DevicesFilter) (map[string]lib_model.Device, error) {
+func (h *Handler) GetAll(ctx context.Context, filter lib_model.DevicesFilter) ([]lib_model.Device, int, error) {
+	if err := validateDevicesFilter(filter); err != nil {
+		return nil, 0, lib_model.NewInvalidInputError(err)
+	}
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
 	defer cf()
-	deviceBases, err := h.stgHdl.ReadAll(ctxWt, filter)
+	if h.statesStgHdl == nil && filter.State != "" {
+		return h.getAllByState(ctxWt, filter)
+	}
+	deviceBases, total, err := h.stgHdl.ReadAll(ctxWt, filter)
 	if err != nil {
-		return nil, fmt.Errorf("get devices: %s", err)
+		return nil, 0, fmt.Errorf("get devices: %s", err)
 	}
-	devices := make(map[string]lib_model.Device)
-	for id, deviceBase := range deviceBases {
-		state := h.getState(id)
-		if filter.State != "" && state != filter.State {
-			continue
-		}
-		devices[id] = lib_model.Device{
-			DeviceBase: deviceBase,
-			State:      state,
+	devices := make([]lib_model.Device, 0, len(deviceBases))
+	for _, deviceBase := range deviceBases {
+		devices = append(devices, h.newDevice(deviceBase))
+	}
+	return devices, total, nil
+}
+
+// getAllByState applies the state filter for storage handlers without state support. All devices matching the
+// remaining filter are read and the pagination is applied after filtering by state.
+func (h *Handler) getAllByState(ctx context.Context, filter lib_model.DevicesFilter) ([]lib_model.Device, int, error) {
+	limit, offset := filter.Limit, filter.Offset
+	filter.Limit, filter.Offset = 0, 0
+	deviceBases, _, err := h.stgHdl.ReadAll(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("get devices: %s", err)
+	}
+	var devices []lib_model.Device
+	for _, deviceBase := range deviceBases {
+		if device := h.newDevice(deviceBase); device.State == filter.State {
+			devices = append(devices, device)
 		}
 	}
-	return devices, nil
+	total := len(devices)
+	if offset >= total {
+		return []lib_model.Device{}, total, nil
+	}
+	devices = devices[offset:]
+	if limit > 0 && limit < len(devices) {
+		devices = devices[:limit]
+	}
+	return devices, total, nil
 }
 
-func (h *Handler) SetUserData(ctx context.Context, id string, userDataBase lib_model.DeviceUserDataBase) error {
+// SetUserData replaces the user data of a device. If ifMatch is not empty the user data is only replaced if the
+// entity tag of the device matches.
+func (h *Handler) SetUserData(ctx context.Context, id, ifMatch string, userDataBase lib_model.DeviceUserDataBase) error {
 	if err := validateAttributes(userDataBase.Attributes); err != nil {
 		return lib_model.NewInvalidInputError(err)
 	}
-	h.mu.Lock()
-	defer h.mu.Unlock()
+	return h.updateUserData(ctx, id, ifMatch, func(_ lib_model.DeviceUserDataBase) lib_model.DeviceUserDataBase {
+		return userDataBase
+	})
+}
+
+// PatchUserData applies a user data patch to the user data of a device, unchanged fields and attributes are retained.
+// If ifMatch is not empty the patch is only applied if the entity tag of the device matches.
+func (h *Handler) PatchUserData(ctx context.Context, id, ifMatch string, patch lib_model.DeviceUserDataPatch) error {
+	if patch.ClearAttributes && len(patch.Attributes) > 0 {
+		return lib_model.NewInvalidInputError(errors.New("clearing attributes can not be combined with attribute changes"))
+	}
+	for key := range patch.Attributes {
+		if key == "" {
+			return lib_model.NewInvalidInputError(errors.New("empty attribute key"))
+		}
+	}
+	return h.updateUserData(ctx, id, ifMatch, func(userDataBase lib_model.DeviceUserDataBase) lib_model.DeviceUserDataBase {
+		return applyUserDataPatch(userDataBase, patch)
+	})
+}
+
+func (h *Handler) updateUserData(ctx context.Context, id, ifMatch string, update func(lib_model.DeviceUserDataBase) lib_model.DeviceUserDataBase) error {
+	h.lock()
+	defer h.unlock()
 	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
 	defer cf()
-	_, err := h.stgHdl.Read(ctxWt, id)
+	device, err := h.stgHdl.Read(ctxWt, id)
 	if err != nil {
 		return fmt.Errorf("set device user data: %s", err)
 	}
+	if !matchETag(ifMatch, device) {
+		return lib_model.NewPreconditionFailedError(errors.New("set device user data: entity tag does not match"))
+	}
+	before := device.UserData
+	device.UserData = lib_model.DeviceUserData{
+		DeviceUserDataBase: update(device.UserData.DeviceUserDataBase),
+		Updated:            time.Now().UTC(),
+	}
 	ctxWt2, cf2 := context.WithTimeout(ctx, h.timeout)
 	defer cf2()
-	err = h.stgHdl.UpdateUserData(ctxWt2, nil, id, lib_model.DeviceUserData{
-		DeviceUserDataBase: userDataBase,
-		Updated:            time.Now().UTC(),
-	})
-	if err != nil {
+	var tx driver.Tx
+	if h.auditing(ctx) {
+		if tx, err = h.stgHdl.BeginTransaction(ctxWt2); err != nil {
+			return fmt.Errorf("set device user data: %s", err)
+		}
+		defer tx.Rollback()
+	}
+	if err = h.stgHdl.UpdateUserData(ctxWt2, tx, id, device.UserData); err != nil {
 		return fmt.Errorf("set device user data: %s", err)
 	}
+	if tx != nil {
+		if err = h.audit(ctx, tx, lib_model.AuditSetUserData, id, &before, &device.UserData, device.UserData.Updated); err != nil {
+			return fmt.Errorf("set device user data: %s", err)
+		}
+		if err = tx.Commit(); err != nil {
+			return fmt.Errorf("set device user data: %s", err)
+		}
+	}
+	h.publishEvent(lib_model.EventUpdated, h.newDevice(device), device.UserData.Updated)
 	return nil
 }
 
@@ -138,35 +342,411 @@ func (h *Handler) SetStates(ctx context.Context, ref string, state lib_model.Dev
 	if err := validateState(state); err != nil {
 		return lib_model.NewInvalidInputError(err)
 	}
-	h.mu.Lock()
-	defer h.mu.Unlock()
+	h.lock()
+	defer h.unlock()
+	timestamp := time.Now().UTC()
+	changed := make(map[string]stateItem)
 	for id, sItem := range h.states {
-		if sItem.ref == ref {
+		if sItem.ref == ref && sItem.value != state {
 			sItem.value = state
+			sItem.updated = timestamp
+			changed[id] = sItem
+		}
+	}
+	if h.statesStgHdl != nil && len(changed) > 0 {
+		if err := h.storeStates(ctx, changed, lib_model.StateSrcLastWill); err != nil {
+			return fmt.Errorf("set device states: %s", err)
+		}
+	}
+	h.refs[ref] = refItem{lastActivity: timestamp, lastWill: true}
+	for id, sItem := range h.states {
+		if sItem.ref == ref {
+			cItem, ok := changed[id]
+			if ok {
+				sItem = cItem
+			}
+			sItem.lastKnown = false
 			h.states[id] = sItem
+			if ok {
+				h.publishStateEvent(id, sItem, timestamp)
+			}
 		}
 	}
 	return nil
 }
 
-func (h *Handler) Delete(ctx context.Context, id string) error {
-	h.mu.Lock()
-	defer h.mu.Unlock()
+// Delete removes a device. If ifMatch is not empty the device is only removed if its entity tag matches.
+func (h *Handler) Delete(ctx context.Context, id, ifMatch string) error {
+	h.lock()
+	defer h.unlock()
 	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
 	defer cf()
-	if err := h.stgHdl.Delete(ctxWt, nil, id); err != nil {
+	device, err := h.stgHdl.Read(ctxWt, id)
+	if err != nil {
 		return fmt.Errorf("delete device: %s", err)
 	}
-	delete(h.states, id)
+	if ifMatch != "" && !matchETag(ifMatch, device) {
+		return lib_model.NewPreconditionFailedError(errors.New("delete device: entity tag does not match"))
+	}
+	if err = h.delete(ctx, nil, device); err != nil {
+		return fmt.Errorf("delete device: %s", err)
+	}
+	h.applyDelete(device, time.Now().UTC())
 	return nil
 }
 
-func (h *Handler) getState(id string) string {
-	sItem, ok := h.states[id]
-	if !ok || sItem.value == "" {
-		return lib_model.NotAvailable
+func (h *Handler) GetStateHistory(ctx context.Context, id string, filter lib_model.DeviceStateHistoryFilter) ([]lib_model.DeviceStateTransition, error) {
+	if h.statesStgHdl == nil {
+		return nil, lib_model.NewInternalError(errors.New("state history not supported by storage"))
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return nil, lib_model.NewInvalidInputError(errors.New("end of time range before start"))
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	if _, err := h.stgHdl.Read(ctxWt, id); err != nil {
+		return nil, fmt.Errorf("get device state history: %s", err)
+	}
+	ctxWt2, cf2 := context.WithTimeout(ctx, h.timeout)
+	defer cf2()
+	transitions, err := h.statesStgHdl.ReadStateHistory(ctxWt2, id, filter)
+	if err != nil {
+		return nil, fmt.Errorf("get device state history: %s", err)
+	}
+	for i := range transitions {
+		if transitions[i].State == "" {
+			transitions[i].State = lib_model.NotAvailable
+		}
+	}
+	return transitions, nil
+}
+
+// CountDevices returns the number of devices per state, type and reference.
+func (h *Handler) CountDevices(ctx context.Context) ([]handler.DeviceCount, error) {
+	if h.statesStgHdl == nil {
+		return nil, lib_model.NewInternalError(errors.New("device counts not supported by storage"))
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	counts, err := h.statesStgHdl.CountDevices(ctxWt)
+	if err != nil {
+		return nil, fmt.Errorf("count devices: %s", err)
+	}
+	return counts, nil
+}
+
+type putItem struct {
+	device       lib_model.DeviceBase
+	eventType    lib_model.DeviceEventType
+	sItem        stateItem
+	stateChanged bool
+}
+
+// put stores the device data and state based on the current state item, changes are applied to the in-memory
+// states via applyPut.
+func (h *Handler) put(ctx context.Context, tx driver.Tx, existing *lib_model.DeviceBase, deviceData lib_model.DeviceDataBase, state lib_model.DeviceState, sItem stateItem, ok bool, timestamp time.Time) (putItem, error) {
+	var device lib_model.DeviceBase
+	var err error
+	eventType := lib_model.EventUpdated
+	if existing == nil {
+		device = lib_model.DeviceBase{
+			DeviceData: lib_model.DeviceData{
+				DeviceDataBase: deviceData,
+				Created:        timestamp,
+			},
+		}
+		if device.UserData, err = h.create(ctx, tx, device.DeviceData); err != nil {
+			return putItem{}, err
+		}
+		eventType = lib_model.EventCreated
+	} else {
+		device = *existing
+		if deviceDataEqual(device.DeviceDataBase, deviceData) {
+			eventType = ""
+		} else {
+			device.DeviceDataBase = deviceData
+			device.Updated = timestamp
+			ctxWt2, cf2 := context.WithTimeout(ctx, h.timeout)
+			defer cf2()
+			if err = h.stgHdl.Update(ctxWt2, tx, device.DeviceData); err != nil {
+				return putItem{}, err
+			}
+		}
+	}
+	stateChanged := !ok || sItem.value != state
+	if stateChanged {
+		sItem.updated = timestamp
+		if h.statesStgHdl != nil {
+			source := lib_model.StateSrcMessage
+			if sItem.lastKnown {
+				source = lib_model.StateSrcRefresh
+			}
+			ctxWt3, cf3 := context.WithTimeout(ctx, h.timeout)
+			defer cf3()
+			err = h.statesStgHdl.UpdateState(ctxWt3, tx, deviceData.ID, lib_model.DeviceStateTransition{
+				State:     state,
+				Source:    source,
+				Timestamp: sItem.updated,
+			})
+			if err != nil {
+				return putItem{}, err
+			}
+		}
+	}
+	if h.statesStgHdl != nil {
+		ctxWt4, cf4 := context.WithTimeout(ctx, h.timeout)
+		defer cf4()
+		if err = h.statesStgHdl.UpdateLastSeen(ctxWt4, tx, deviceData.ID, timestamp); err != nil {
+			return putItem{}, err
+		}
+	}
+	sItem.ref = deviceData.Ref
+	sItem.typ = deviceData.Type
+	sItem.value = state
+	sItem.lastSeen = timestamp
+	sItem.lastKnown = false
+	return putItem{
+		device:       device,
+		eventType:    eventType,
+		sItem:        sItem,
+		stateChanged: stateChanged,
+	}, nil
+}
+
+// create stores a new device and restores its user data from the trash, both writes run in one transaction.
+func (h *Handler) create(ctx context.Context, tx driver.Tx, deviceData lib_model.DeviceData) (lib_model.DeviceUserData, error) {
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	if h.trashStgHdl != nil && tx == nil {
+		var err error
+		if tx, err = h.stgHdl.BeginTransaction(ctxWt); err != nil {
+			return lib_model.DeviceUserData{}, err
+		}
+		defer tx.Rollback()
+		userData, err := h.create(ctxWt, tx, deviceData)
+		if err != nil {
+			return lib_model.DeviceUserData{}, err
+		}
+		return userData, tx.Commit()
+	}
+	if err := h.stgHdl.Create(ctxWt, tx, deviceData); err != nil {
+		return lib_model.DeviceUserData{}, err
+	}
+	return h.restoreUserData(ctx, tx, deviceData.ID)
+}
+
+// lock acquires exclusive access, waiting for running Put calls to finish.
+func (h *Handler) lock() {
+	h.putMu.Lock()
+	h.mu.Lock()
+}
+
+func (h *Handler) unlock() {
+	h.mu.Unlock()
+	h.putMu.Unlock()
+}
+
+func (h *Handler) applyPut(item putItem, timestamp time.Time) {
+	h.states[item.device.ID] = item.sItem
+	if announced, ok := h.syncs[item.device.Ref]; ok {
+		announced[item.device.ID] = struct{}{}
+	}
+	h.refs[item.device.Ref] = refItem{lastActivity: timestamp}
+	if item.eventType != "" {
+		h.publishEvent(item.eventType, h.newDevice(item.device), timestamp)
+	} else if item.stateChanged {
+		h.publishStateEvent(item.device.ID, item.sItem, timestamp)
+	}
+}
+
+// delete removes the device from storage, changes are applied to the in-memory states via applyDelete.
+func (h *Handler) delete(ctx context.Context, tx driver.Tx, device lib_model.DeviceBase) error {
+	if (h.trashStgHdl != nil || h.auditing(ctx)) && tx == nil {
+		ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+		defer cf()
+		tx, err := h.stgHdl.BeginTransaction(ctxWt)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if err = h.remove(ctxWt, tx, device); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+	return h.remove(ctx, tx, device)
+}
+
+// remove deletes the device from storage, the device is moved to the trash if supported by the storage handler.
+func (h *Handler) remove(ctx context.Context, tx driver.Tx, device lib_model.DeviceBase) error {
+	timestamp := time.Now().UTC()
+	if h.trashStgHdl != nil {
+		ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+		defer cf()
+		err := h.trashStgHdl.CreateTrashItem(ctxWt, tx, lib_model.DeletedDevice{
+			DeviceData: device.DeviceData,
+			UserData:   device.UserData,
+			Deleted:    timestamp,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := h.audit(ctx, tx, lib_model.AuditDelete, device.ID, &device.UserData, nil, timestamp); err != nil {
+		return err
+	}
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	return h.stgHdl.Delete(ctxWt, tx, device.ID)
+}
+
+func (h *Handler) applyDelete(device lib_model.DeviceBase, timestamp time.Time) {
+	h.publishEvent(lib_model.EventDeleted, h.newDevice(device), timestamp)
+	delete(h.states, device.ID)
+}
+
+func (h *Handler) storeStates(ctx context.Context, states map[string]stateItem, source lib_model.DeviceStateSource) error {
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	tx, err := h.stgHdl.BeginTransaction(ctxWt)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for id, sItem := range states {
+		err = h.statesStgHdl.UpdateState(ctxWt, tx, id, lib_model.DeviceStateTransition{
+			State:     sItem.value,
+			Source:    source,
+			Timestamp: sItem.updated,
+		})
+		if err != nil {
+			return err
+		}
 	}
-	return sItem.value
+	return tx.Commit()
+}
+
+func (h *Handler) publishEvent(eventType lib_model.DeviceEventType, device lib_model.Device, timestamp time.Time) {
+	if h.eventsHdl == nil {
+		return
+	}
+	h.eventsHdl.Publish(lib_model.DeviceEvent{
+		Type:       eventType,
+		DeviceID:   device.ID,
+		Ref:        device.Ref,
+		DeviceType: device.Type,
+		State:      device.State,
+		Device:     &device,
+		Timestamp:  timestamp,
+	})
+}
+
+func (h *Handler) publishStateEvent(id string, sItem stateItem, timestamp time.Time) {
+	if h.eventsHdl == nil {
+		return
+	}
+	state := sItem.value
+	if state == "" {
+		state = lib_model.NotAvailable
+	}
+	h.eventsHdl.Publish(lib_model.DeviceEvent{
+		Type:       lib_model.EventStateChanged,
+		DeviceID:   id,
+		Ref:        sItem.ref,
+		DeviceType: sItem.typ,
+		State:      state,
+		Timestamp:  timestamp,
+	})
+}
+
+func (h *Handler) newDevice(deviceBase lib_model.DeviceBase) lib_model.Device {
+	device := lib_model.Device{
+		DeviceBase: deviceBase,
+		State:      lib_model.NotAvailable,
+	}
+	sItem, ok := h.states[deviceBase.ID]
+	if !ok {
+		return device
+	}
+	if sItem.value != "" {
+		device.State = sItem.value
+	}
+	device.StateUpdated = sItem.updated
+	device.StateLastKnown = sItem.lastKnown
+	device.LastSeen = sItem.lastSeen
+	return device
+}
+
+// applyUserDataPatch returns a copy of the user data with the changes of the patch, the order of existing attributes
+// is retained and new attributes are appended ordered by key.
+func applyUserDataPatch(userDataBase lib_model.DeviceUserDataBase, patch lib_model.DeviceUserDataPatch) lib_model.DeviceUserDataBase {
+	if patch.Name != nil {
+		userDataBase.Name = *patch.Name
+	}
+	if patch.ClearAttributes {
+		userDataBase.Attributes = nil
+		return userDataBase
+	}
+	if len(patch.Attributes) == 0 {
+		return userDataBase
+	}
+	attributes := make([]lib_model.DeviceAttribute, 0, len(userDataBase.Attributes)+len(patch.Attributes))
+	existing := make(map[string]struct{})
+	for _, attr := range userDataBase.Attributes {
+		existing[attr.Key] = struct{}{}
+		value, ok := patch.Attributes[attr.Key]
+		if !ok {
+			attributes = append(attributes, attr)
+			continue
+		}
+		if value != nil {
+			attributes = append(attributes, lib_model.DeviceAttribute{Key: attr.Key, Value: *value})
+		}
+	}
+	var keys []string
+	for key, value := range patch.Attributes {
+		if _, ok := existing[key]; !ok && value != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		attributes = append(attributes, lib_model.DeviceAttribute{Key: key, Value: *patch.Attributes[key]})
+	}
+	userDataBase.Attributes = attributes
+	return userDataBase
+}
+
+// matchETag returns true if ifMatch is empty or one of the entity tags of an If-Match header value matches the device.
+func matchETag(ifMatch string, device lib_model.DeviceBase) bool {
+	if ifMatch == "" {
+		return true
+	}
+	eTag := lib_model.DeviceETag(device)
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == eTag {
+			return true
+		}
+	}
+	return false
+}
+
+// deviceDataEqual compares device data treating nil and empty attributes as equal.
+func deviceDataEqual(a, b lib_model.DeviceDataBase) bool {
+	if a.ID != b.ID || a.Ref != b.Ref || a.Name != b.Name || a.Type != b.Type || len(a.Attributes) != len(b.Attributes) {
+		return false
+	}
+	for i, attr := range a.Attributes {
+		if attr != b.Attributes[i] {
+			return false
+		}
+	}
+	return true
 }
 
 func validateDeviceData(dBase lib_model.DeviceDataBase) error {
@@ -182,6 +762,61 @@ func validateDeviceData(dBase lib_model.DeviceDataBase) error {
 	return validateAttributes(dBase.Attributes)
 }
 
+func validateMessage(ref string, msg lib_model.DeviceMessage) error {
+	switch msg.Method {
+	case lib_model.Set:
+		if msg.Data == nil {
+			return errors.New("missing data")
+		}
+		if err := validateDeviceData(newDeviceDataBase(ref, msg)); err != nil {
+			return err
+		}
+		return validateState(msg.Data.State)
+	case lib_model.Delete:
+		if msg.DeviceID == "" {
+			return errors.New("empty id")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown method '%s'", msg.Method)
+	}
+}
+
+func newDeviceDataBase(ref string, msg lib_model.DeviceMessage) lib_model.DeviceDataBase {
+	return lib_model.DeviceDataBase{
+		ID:         msg.DeviceID,
+		Ref:        ref,
+		Name:       msg.Data.Name,
+		Type:       msg.Data.Type,
+		Attributes: msg.Data.Attributes,
+	}
+}
+
+func validateDevicesFilter(filter lib_model.DevicesFilter) error {
+	switch filter.SortBy {
+	case "", lib_model.SortByID, lib_model.SortByName, lib_model.SortByUserName, lib_model.SortByType, lib_model.SortByCreated, lib_model.SortByUpdated, lib_model.SortByState:
+	default:
+		return errors.New("invalid sort field")
+	}
+	if filter.Limit < 0 {
+		return errors.New("invalid limit")
+	}
+	if filter.Offset < 0 {
+		return errors.New("invalid offset")
+	}
+	for _, attrFilter := range filter.Attributes {
+		if attrFilter.Key == "" {
+			return errors.New("empty attribute key")
+		}
+	}
+	for _, attrFilter := range filter.UserAttributes {
+		if attrFilter.Key == "" {
+			return errors.New("empty user attribute key")
+		}
+	}
+	return nil
+}
+
 func validateAttributes(attrs []lib_model.DeviceAttribute) error {
 	for _, attr := range attrs {
 		if attr.Key == "" {
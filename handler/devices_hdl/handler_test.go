@@ -4,10 +4,17 @@ import (
 	"context"
 	"database/sql/driver"
 	"errors"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler/mem_storage_hdl"
 	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
 	"github.com/SENERGY-Platform/mgw-device-manager/util"
 	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 )
 
 var id = "1"
@@ -81,6 +88,27 @@ func TestHandler_Put(t *testing.T) {
 			t.Error("expected\n", lib_model.Offline, "got\n", sItem.value)
 		}
 	})
+	t.Run("unchanged", func(t *testing.T) {
+		eventsHdl := &eventsHdlMock{}
+		h.SetEventsHandler(eventsHdl)
+		defer h.SetEventsHandler(nil)
+		deviceData2 := deviceData.DeviceDataBase
+		deviceData2.Attributes = nil
+		if err := h.Put(context.Background(), deviceData2, lib_model.Offline); err != nil {
+			t.Fatal(err)
+		}
+		eTag := lib_model.DeviceETag(stgHdl.devices[id])
+		deviceData2.Attributes = []lib_model.DeviceAttribute{}
+		if err := h.Put(context.Background(), deviceData2, lib_model.Offline); err != nil {
+			t.Fatal(err)
+		}
+		if s := lib_model.DeviceETag(stgHdl.devices[id]); s != eTag {
+			t.Error("expected\n", eTag, "got\n", s)
+		}
+		if len(eventsHdl.events) != 1 || eventsHdl.events[0].Type != lib_model.EventUpdated {
+			t.Error("expected one update event, got", eventsHdl.events)
+		}
+	})
 	t.Run("invalid input", func(t *testing.T) {
 		t.Run("device data", func(t *testing.T) {
 			err := h.Put(context.Background(), lib_model.DeviceDataBase{}, "")
@@ -97,6 +125,40 @@ func TestHandler_Put(t *testing.T) {
 	})
 }
 
+func TestHandler_Put_concurrent(t *testing.T) {
+	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
+	h := New(mem_storage_hdl.New(), time.Second)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			dd := deviceData.DeviceDataBase
+			dd.ID = strconv.Itoa(i)
+			for _, s := range []lib_model.DeviceState{lib_model.Online, lib_model.Offline, lib_model.Online} {
+				if err := h.Put(context.Background(), dd, s); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := h.SetStates(context.Background(), deviceData.Ref, lib_model.Offline); err != nil {
+			t.Error(err)
+		}
+	}()
+	wg.Wait()
+	_, total, err := h.GetAll(context.Background(), lib_model.DevicesFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 10 || len(h.states) != 10 {
+		t.Error("expected 10 devices, got", total, len(h.states))
+	}
+}
+
 func TestHandler_Get(t *testing.T) {
 	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
 	stgHdl := &stgHdlMock{devices: make(map[string]lib_model.DeviceBase)}
@@ -133,13 +195,16 @@ func TestHandler_GetAll(t *testing.T) {
 	stgHdl := &stgHdlMock{devices: make(map[string]lib_model.DeviceBase)}
 	h := New(stgHdl, 0)
 	t.Run("no entries", func(t *testing.T) {
-		devices, err := h.GetAll(context.Background(), lib_model.DevicesFilter{})
+		devices, total, err := h.GetAll(context.Background(), lib_model.DevicesFilter{})
 		if err != nil {
 			t.Error(err)
 		}
 		if len(devices) != 0 {
 			t.Error("expected 0 entries")
 		}
+		if total != 0 {
+			t.Error("expected total 0, got", total)
+		}
 	})
 	t.Run("with entries", func(t *testing.T) {
 		stgHdl.devices[id] = lib_model.DeviceBase{DeviceData: deviceData}
@@ -149,20 +214,72 @@ func TestHandler_GetAll(t *testing.T) {
 				value: state,
 			},
 		}
-		devices, err := h.GetAll(context.Background(), lib_model.DevicesFilter{})
+		devices, total, err := h.GetAll(context.Background(), lib_model.DevicesFilter{})
 		if err != nil {
-			t.Error(err)
+			t.Fatal(err)
 		}
 		if len(devices) != 1 {
-			t.Error("expected 1 entry")
+			t.Fatal("expected 1 entry")
 		}
-		if devices[id].State != state {
-			t.Error("expected\n", state, "got\n", devices[id].State)
+		if total != 1 {
+			t.Error("expected total 1, got", total)
+		}
+		if devices[0].State != state {
+			t.Error("expected\n", state, "got\n", devices[0].State)
+		}
+		t.Run("state filter", func(t *testing.T) {
+			devices, total, err = h.GetAll(context.Background(), lib_model.DevicesFilter{State: lib_model.Offline})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(devices) != 0 || total != 0 {
+				t.Error("expected 0 entries")
+			}
+		})
+	})
+	t.Run("state filter with pagination", func(t *testing.T) {
+		stgHdl := &stgHdlMock{devices: make(map[string]lib_model.DeviceBase)}
+		h := New(stgHdl, 0)
+		h.states = make(map[string]stateItem)
+		for i := 0; i < 10; i++ {
+			dID := strconv.Itoa(i)
+			stgHdl.devices[dID] = lib_model.DeviceBase{DeviceData: lib_model.DeviceData{DeviceDataBase: lib_model.DeviceDataBase{ID: dID}}}
+			if i%2 == 0 {
+				h.states[dID] = stateItem{value: lib_model.Online}
+			}
+		}
+		devices, total, err := h.GetAll(context.Background(), lib_model.DevicesFilter{State: lib_model.Online, Limit: 2, Offset: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 5 {
+			t.Error("expected total 5, got", total)
+		}
+		if len(devices) != 2 {
+			t.Fatal("expected 2 entries, got", len(devices))
+		}
+		if devices[0].ID != "2" || devices[1].ID != "4" {
+			t.Error("expected devices 2 and 4, got", devices[0].ID, devices[1].ID)
+		}
+		devices, total, err = h.GetAll(context.Background(), lib_model.DevicesFilter{State: lib_model.Online, Offset: 5})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(devices) != 0 || total != 5 {
+			t.Error("expected 0 entries and total 5, got", len(devices), total)
+		}
+	})
+	t.Run("invalid filter", func(t *testing.T) {
+		filters := []lib_model.DevicesFilter{{SortBy: "test"}, {Limit: -1}, {Offset: -1}}
+		for _, filter := range filters {
+			if _, _, err := h.GetAll(context.Background(), filter); err == nil {
+				t.Error("expected error")
+			}
 		}
 	})
 	t.Run("error", func(t *testing.T) {
 		stgHdl.getAllErr = errors.New("test error")
-		_, err := h.GetAll(context.Background(), lib_model.DevicesFilter{})
+		_, _, err := h.GetAll(context.Background(), lib_model.DevicesFilter{})
 		if err == nil {
 			t.Error("expected error")
 		}
@@ -183,7 +300,7 @@ func TestHandler_UpdateUserData(t *testing.T) {
 		},
 	}
 	t.Run("does not exist", func(t *testing.T) {
-		if err := h.SetUserData(context.Background(), id, userDataBase); err == nil {
+		if err := h.SetUserData(context.Background(), id, "", userDataBase); err == nil {
 			t.Error("expected error")
 		}
 		if len(stgHdl.devices) != 0 {
@@ -192,7 +309,7 @@ func TestHandler_UpdateUserData(t *testing.T) {
 	})
 	t.Run("exists", func(t *testing.T) {
 		stgHdl.devices[id] = lib_model.DeviceBase{DeviceData: deviceData}
-		if err := h.SetUserData(context.Background(), id, userDataBase); err != nil {
+		if err := h.SetUserData(context.Background(), id, "", userDataBase); err != nil {
 			t.Error(err)
 		}
 		device := stgHdl.devices[id]
@@ -203,14 +320,93 @@ func TestHandler_UpdateUserData(t *testing.T) {
 			t.Error("updated timestamp is zero")
 		}
 	})
+	t.Run("if match", func(t *testing.T) {
+		eTag := lib_model.DeviceETag(stgHdl.devices[id])
+		if err := h.SetUserData(context.Background(), id, eTag, lib_model.DeviceUserDataBase{Name: "a"}); err != nil {
+			t.Error(err)
+		}
+		err := h.SetUserData(context.Background(), id, eTag, lib_model.DeviceUserDataBase{Name: "b"})
+		var pfe *lib_model.PreconditionFailedError
+		if !errors.As(err, &pfe) {
+			t.Error("expected PreconditionFailedError, got", err)
+		}
+		if stgHdl.devices[id].UserData.Name != "a" {
+			t.Error("user data overwritten")
+		}
+		if err = h.SetUserData(context.Background(), id, "*", lib_model.DeviceUserDataBase{Name: "c"}); err != nil {
+			t.Error(err)
+		}
+	})
 	t.Run("invalid input", func(t *testing.T) {
-		err := h.SetUserData(context.Background(), id, lib_model.DeviceUserDataBase{Attributes: []lib_model.DeviceAttribute{{Value: "test"}}})
+		err := h.SetUserData(context.Background(), id, "", lib_model.DeviceUserDataBase{Attributes: []lib_model.DeviceAttribute{{Value: "test"}}})
 		if err == nil {
 			t.Error("expected error")
 		}
 	})
 }
 
+func TestHandler_PatchUserData(t *testing.T) {
+	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
+	stgHdl := &stgHdlMock{devices: make(map[string]lib_model.DeviceBase)}
+	h := New(stgHdl, 0)
+	stgHdl.devices[id] = lib_model.DeviceBase{
+		DeviceData: deviceData,
+		UserData: lib_model.DeviceUserData{
+			DeviceUserDataBase: lib_model.DeviceUserDataBase{
+				Name:       "test",
+				Attributes: []lib_model.DeviceAttribute{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}, {Key: "c", Value: "3"}},
+			},
+		},
+	}
+	strPtr := func(s string) *string { return &s }
+	t.Run("change attributes", func(t *testing.T) {
+		err := h.PatchUserData(context.Background(), id, "", lib_model.DeviceUserDataPatch{
+			Attributes: map[string]*string{"b": nil, "c": strPtr("x"), "e": strPtr("5"), "d": strPtr("4")},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		a := lib_model.DeviceUserDataBase{
+			Name:       "test",
+			Attributes: []lib_model.DeviceAttribute{{Key: "a", Value: "1"}, {Key: "c", Value: "x"}, {Key: "d", Value: "4"}, {Key: "e", Value: "5"}},
+		}
+		if b := stgHdl.devices[id].UserData.DeviceUserDataBase; !reflect.DeepEqual(a, b) {
+			t.Error("expected\n", a, "got\n", b)
+		}
+	})
+	t.Run("change name", func(t *testing.T) {
+		if err := h.PatchUserData(context.Background(), id, "", lib_model.DeviceUserDataPatch{Name: strPtr("lamp")}); err != nil {
+			t.Fatal(err)
+		}
+		userData := stgHdl.devices[id].UserData
+		if userData.Name != "lamp" || len(userData.Attributes) != 4 {
+			t.Error("invalid user data", userData)
+		}
+	})
+	t.Run("clear attributes", func(t *testing.T) {
+		if err := h.PatchUserData(context.Background(), id, "", lib_model.DeviceUserDataPatch{ClearAttributes: true}); err != nil {
+			t.Fatal(err)
+		}
+		userData := stgHdl.devices[id].UserData
+		if userData.Name != "lamp" || len(userData.Attributes) != 0 {
+			t.Error("invalid user data", userData)
+		}
+	})
+	t.Run("invalid input", func(t *testing.T) {
+		if err := h.PatchUserData(context.Background(), id, "", lib_model.DeviceUserDataPatch{Attributes: map[string]*string{"": strPtr("x")}}); err == nil {
+			t.Error("expected error")
+		}
+		if err := h.PatchUserData(context.Background(), id, "", lib_model.DeviceUserDataPatch{Attributes: map[string]*string{"a": nil}, ClearAttributes: true}); err == nil {
+			t.Error("expected error")
+		}
+	})
+	t.Run("does not exist", func(t *testing.T) {
+		if err := h.PatchUserData(context.Background(), "test", "", lib_model.DeviceUserDataPatch{Name: strPtr("lamp")}); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
 func TestHandler_SetStates(t *testing.T) {
 	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
 	h := New(nil, 0)
@@ -240,18 +436,258 @@ func TestHandler_SetStates(t *testing.T) {
 	})
 }
 
+func TestHandler_RestoreStates(t *testing.T) {
+	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
+	timestamp := time.Now().UTC()
+	stgHdl := &stgHdlStatesMock{
+		stgHdlMock: stgHdlMock{devices: map[string]lib_model.DeviceBase{id: {DeviceData: deviceData}}},
+		states: map[string]handler.DeviceStateData{
+			id: {
+				Ref:     deviceData.Ref,
+				Value:   lib_model.Online,
+				Updated: timestamp,
+			},
+		},
+	}
+	h := New(stgHdl, time.Second)
+	if err := h.RestoreStates(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	device, err := h.Get(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.State != lib_model.Online {
+		t.Error("expected\n", lib_model.Online, "got\n", device.State)
+	}
+	if !device.StateUpdated.Equal(timestamp) {
+		t.Error("expected\n", timestamp, "got\n", device.StateUpdated)
+	}
+	if !device.StateLastKnown {
+		t.Error("expected last known state")
+	}
+	t.Run("confirm state", func(t *testing.T) {
+		if err = h.Put(context.Background(), deviceData.DeviceDataBase, lib_model.Online); err != nil {
+			t.Fatal(err)
+		}
+		device, err = h.Get(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if device.StateLastKnown {
+			t.Error("expected confirmed state")
+		}
+		if !device.StateUpdated.Equal(timestamp) {
+			t.Error("expected\n", timestamp, "got\n", device.StateUpdated)
+		}
+	})
+	t.Run("change state", func(t *testing.T) {
+		if err = h.Put(context.Background(), deviceData.DeviceDataBase, lib_model.Offline); err != nil {
+			t.Fatal(err)
+		}
+		if stgHdl.states[id].Value != lib_model.Offline {
+			t.Error("expected\n", lib_model.Offline, "got\n", stgHdl.states[id].Value)
+		}
+		if !stgHdl.states[id].Updated.After(timestamp) {
+			t.Error("state timestamp not updated")
+		}
+		if stgHdl.states[id].LastSeen.IsZero() {
+			t.Error("last seen timestamp is zero")
+		}
+	})
+	t.Run("set states", func(t *testing.T) {
+		if err = h.SetStates(context.Background(), deviceData.Ref, lib_model.Online); err != nil {
+			t.Fatal(err)
+		}
+		if stgHdl.states[id].Value != lib_model.Online {
+			t.Error("expected\n", lib_model.Online, "got\n", stgHdl.states[id].Value)
+		}
+		if stgHdl.commitC != 1 {
+			t.Error("expected 1 commit, got", stgHdl.commitC)
+		}
+	})
+	t.Run("state history", func(t *testing.T) {
+		transitions, err := h.GetStateHistory(context.Background(), id, lib_model.DeviceStateHistoryFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sources := []lib_model.DeviceStateSource{lib_model.StateSrcMessage, lib_model.StateSrcLastWill}
+		if len(transitions) != len(sources) {
+			t.Fatal("expected", len(sources), "entries, got", len(transitions))
+		}
+		for i, source := range sources {
+			if transitions[i].Source != source {
+				t.Error("expected\n", source, "got\n", transitions[i].Source)
+			}
+		}
+		if _, err = h.GetStateHistory(context.Background(), "test", lib_model.DeviceStateHistoryFilter{}); err == nil {
+			t.Error("expected error")
+		}
+	})
+	t.Run("count devices", func(t *testing.T) {
+		counts, err := h.CountDevices(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []handler.DeviceCount{{State: lib_model.Online, Type: deviceData.Type, Ref: deviceData.Ref, Count: 1}}
+		if !reflect.DeepEqual(expected, counts) {
+			t.Error("expected\n", expected, "got\n", counts)
+		}
+		if stgHdl.noDeadlineC != 0 {
+			t.Error("expected count with deadline")
+		}
+	})
+}
+
+func TestHandler_events(t *testing.T) {
+	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
+	stgHdl := &stgHdlMock{devices: make(map[string]lib_model.DeviceBase)}
+	eventsHdl := &eventsHdlMock{}
+	h := New(stgHdl, 0)
+	h.SetEventsHandler(eventsHdl)
+	if err := h.Put(context.Background(), deviceData.DeviceDataBase, lib_model.Online); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Put(context.Background(), deviceData.DeviceDataBase, lib_model.Online); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SetStates(context.Background(), deviceData.Ref, lib_model.Offline); err != nil {
+		t.Fatal(err)
+	}
+	deviceData2 := deviceData
+	deviceData2.Name = "test2"
+	if err := h.Put(context.Background(), deviceData2.DeviceDataBase, lib_model.Offline); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SetUserData(context.Background(), id, "", lib_model.DeviceUserDataBase{Name: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Delete(context.Background(), id, ""); err != nil {
+		t.Fatal(err)
+	}
+	eventTypes := []lib_model.DeviceEventType{lib_model.EventCreated, lib_model.EventStateChanged, lib_model.EventUpdated, lib_model.EventUpdated, lib_model.EventDeleted}
+	if len(eventsHdl.events) != len(eventTypes) {
+		t.Fatal("expected", len(eventTypes), "events, got", len(eventsHdl.events))
+	}
+	for i, eventType := range eventTypes {
+		event := eventsHdl.events[i]
+		if event.Type != eventType {
+			t.Error("expected\n", eventType, "got\n", event.Type)
+		}
+		if event.DeviceID != id || event.Ref != deviceData.Ref || event.DeviceType != deviceData.Type {
+			t.Error("invalid event", event)
+		}
+		if event.Type != lib_model.EventStateChanged && event.Device == nil {
+			t.Error("missing device")
+		}
+	}
+	if eventsHdl.events[1].State != lib_model.Offline {
+		t.Error("expected\n", lib_model.Offline, "got\n", eventsHdl.events[1].State)
+	}
+	if eventsHdl.events[3].Device.UserData.Name != "test" {
+		t.Error("expected\n", "test", "got\n", eventsHdl.events[3].Device.UserData.Name)
+	}
+}
+
+func TestHandler_ApplyBatch(t *testing.T) {
+	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
+	stgHdl := &stgHdlTxMock{stgHdlStatesMock: stgHdlStatesMock{
+		stgHdlMock: stgHdlMock{devices: map[string]lib_model.DeviceBase{"2": {DeviceData: lib_model.DeviceData{DeviceDataBase: lib_model.DeviceDataBase{ID: "2", Ref: "test", Type: "test"}}}}},
+		states:     make(map[string]handler.DeviceStateData),
+	}}
+	eventsHdl := &eventsHdlMock{}
+	h := New(stgHdl, time.Second)
+	h.SetEventsHandler(eventsHdl)
+	messages := []lib_model.DeviceMessage{
+		{Method: lib_model.Set, DeviceID: "1", Data: &lib_model.DeviceMessageData{Name: "test", State: lib_model.Online, Type: "test"}},
+		{Method: lib_model.Set, DeviceID: "3"},
+		{Method: lib_model.Set, DeviceID: "1", Data: &lib_model.DeviceMessageData{Name: "test", State: lib_model.Offline, Type: "test"}},
+		{Method: lib_model.Delete, DeviceID: "2"},
+		{Method: lib_model.Delete, DeviceID: "4"},
+		{Method: "test", DeviceID: "5"},
+	}
+	errs, err := h.ApplyBatch(context.Background(), "test", messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != len(messages) {
+		t.Fatal("expected", len(messages), "errors, got", len(errs))
+	}
+	for i, failed := range []bool{false, true, true, false, true, true} {
+		if failed != (errs[i] != nil) {
+			t.Error("item", i, "unexpected error state:", errs[i])
+		}
+	}
+	if stgHdl.commitC != 1 {
+		t.Error("expected one commit, got", stgHdl.commitC)
+	}
+	if stgHdl.noDeadlineC != 0 {
+		t.Error("expected transaction with deadline")
+	}
+	if stgHdl.readC != 0 {
+		t.Error("expected devices to be read before the transaction, got", stgHdl.readC, "reads")
+	}
+	if _, ok := stgHdl.devices["1"]; !ok {
+		t.Error("not created")
+	}
+	if _, ok := stgHdl.devices["2"]; ok {
+		t.Error("not deleted")
+	}
+	if sItem := h.states["1"]; sItem.value != lib_model.Online || sItem.ref != "test" {
+		t.Error("invalid state", sItem)
+	}
+	if len(eventsHdl.events) != 2 || eventsHdl.events[0].Type != lib_model.EventCreated || eventsHdl.events[1].Type != lib_model.EventDeleted {
+		t.Error("invalid events", eventsHdl.events)
+	}
+	t.Run("storage error", func(t *testing.T) {
+		stgHdl.createErr = errors.New("test")
+		eventsHdl.events = nil
+		_, err := h.ApplyBatch(context.Background(), "test", []lib_model.DeviceMessage{
+			{Method: lib_model.Set, DeviceID: "1", Data: &lib_model.DeviceMessageData{Name: "test", State: lib_model.Offline, Type: "test"}},
+			{Method: lib_model.Set, DeviceID: "6", Data: &lib_model.DeviceMessageData{Name: "test", State: lib_model.Online, Type: "test"}},
+		})
+		if err == nil {
+			t.Error("expected error")
+		}
+		if stgHdl.commitC != 1 {
+			t.Error("unexpected commit")
+		}
+		if h.states["1"].value != lib_model.Online {
+			t.Error("state changed")
+		}
+		if _, ok := h.states["6"]; ok {
+			t.Error("state added")
+		}
+		if len(eventsHdl.events) > 0 {
+			t.Error("unexpected events", eventsHdl.events)
+		}
+	})
+}
+
 func TestHandler_Delete(t *testing.T) {
 	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
 	stgHdl := &stgHdlMock{devices: make(map[string]lib_model.DeviceBase)}
 	h := New(stgHdl, 0)
 	t.Run("does not exist", func(t *testing.T) {
-		if err := h.Delete(context.Background(), id); err == nil {
+		if err := h.Delete(context.Background(), id, ""); err == nil {
 			t.Error("expected error")
 		}
 	})
+	t.Run("if match", func(t *testing.T) {
+		stgHdl.devices[id] = lib_model.DeviceBase{DeviceData: deviceData}
+		if err := h.Delete(context.Background(), id, "\"test\""); err == nil {
+			t.Error("expected error")
+		}
+		if len(stgHdl.devices) != 1 {
+			t.Error("expected 1 entry")
+		}
+		if err := h.Delete(context.Background(), id, "\"test\", "+lib_model.DeviceETag(stgHdl.devices[id])); err != nil {
+			t.Error(err)
+		}
+	})
 	t.Run("exists", func(t *testing.T) {
 		stgHdl.devices[id] = lib_model.DeviceBase{DeviceData: deviceData}
-		if err := h.Delete(context.Background(), id); err != nil {
+		if err := h.Delete(context.Background(), id, ""); err != nil {
 			t.Error(err)
 		}
 		if len(stgHdl.devices) != 0 {
@@ -356,6 +792,7 @@ func Test_validateState(t *testing.T) {
 type stgHdlMock struct {
 	devices   map[string]lib_model.DeviceBase
 	getAllErr error
+	readC     int
 }
 
 func (m *stgHdlMock) BeginTransaction(_ context.Context) (driver.Tx, error) {
@@ -374,6 +811,7 @@ func (m *stgHdlMock) Create(_ context.Context, tx driver.Tx, dBase lib_model.Dev
 }
 
 func (m *stgHdlMock) Read(_ context.Context, id string) (lib_model.DeviceBase, error) {
+	m.readC++
 	device, ok := m.devices[id]
 	if !ok {
 		return device, lib_model.NewNotFoundError(errors.New("not found"))
@@ -381,11 +819,29 @@ func (m *stgHdlMock) Read(_ context.Context, id string) (lib_model.DeviceBase, e
 	return device, nil
 }
 
-func (m *stgHdlMock) ReadAll(_ context.Context, _ lib_model.DevicesFilter) (map[string]lib_model.DeviceBase, error) {
+func (m *stgHdlMock) ReadAll(_ context.Context, filter lib_model.DevicesFilter) ([]lib_model.DeviceBase, int, error) {
 	if m.getAllErr != nil {
-		return nil, m.getAllErr
+		return nil, 0, m.getAllErr
+	}
+	var devices []lib_model.DeviceBase
+	for _, device := range m.devices {
+		if len(filter.IDs) > 0 && !slices.Contains(filter.IDs, device.ID) {
+			continue
+		}
+		devices = append(devices, device)
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].ID < devices[j].ID
+	})
+	total := len(devices)
+	if filter.Offset >= total {
+		return nil, total, nil
+	}
+	devices = devices[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(devices) {
+		devices = devices[:filter.Limit]
 	}
-	return m.devices, nil
+	return devices, total, nil
 }
 
 func (m *stgHdlMock) Update(_ context.Context, tx driver.Tx, dBase lib_model.DeviceData) error {
@@ -425,3 +881,112 @@ func (m *stgHdlMock) Delete(_ context.Context, tx driver.Tx, id string) error {
 	delete(m.devices, id)
 	return nil
 }
+
+type stgHdlStatesMock struct {
+	stgHdlMock
+	states  map[string]handler.DeviceStateData
+	history []lib_model.DeviceStateTransition
+	commitC int
+	// noDeadlineC counts transactions started without a deadline
+	noDeadlineC int
+}
+
+func (m *stgHdlStatesMock) BeginTransaction(ctx context.Context) (driver.Tx, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		m.noDeadlineC++
+	}
+	return &txMock{commit: func() { m.commitC++ }}, nil
+}
+
+func (m *stgHdlStatesMock) ReadStates(_ context.Context) (map[string]handler.DeviceStateData, error) {
+	return m.states, nil
+}
+
+func (m *stgHdlStatesMock) UpdateState(_ context.Context, _ driver.Tx, id string, transition lib_model.DeviceStateTransition) error {
+	device, ok := m.devices[id]
+	if !ok {
+		return lib_model.NewNotFoundError(errors.New("not found"))
+	}
+	m.states[id] = handler.DeviceStateData{
+		Ref:     device.Ref,
+		Value:   transition.State,
+		Updated: transition.Timestamp,
+	}
+	m.history = append(m.history, transition)
+	return nil
+}
+
+func (m *stgHdlStatesMock) UpdateLastSeen(_ context.Context, _ driver.Tx, id string, lastSeen time.Time) error {
+	state := m.states[id]
+	state.LastSeen = lastSeen
+	m.states[id] = state
+	return nil
+}
+
+func (m *stgHdlStatesMock) ReadStateHistory(_ context.Context, _ string, _ lib_model.DeviceStateHistoryFilter) ([]lib_model.DeviceStateTransition, error) {
+	return m.history, nil
+}
+
+func (m *stgHdlStatesMock) CountDevices(ctx context.Context) ([]handler.DeviceCount, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		m.noDeadlineC++
+	}
+	var counts []handler.DeviceCount
+	for id, device := range m.devices {
+		state := m.states[id].Value
+		if state == "" {
+			state = lib_model.NotAvailable
+		}
+		counts = append(counts, handler.DeviceCount{State: state, Type: device.Type, Ref: device.Ref, Count: 1})
+	}
+	return counts, nil
+}
+
+type stgHdlTxMock struct {
+	stgHdlStatesMock
+	createErr error
+}
+
+func (m *stgHdlTxMock) Create(ctx context.Context, _ driver.Tx, dBase lib_model.DeviceData) error {
+	if m.createErr != nil {
+		return m.createErr
+	}
+	return m.stgHdlMock.Create(ctx, nil, dBase)
+}
+
+func (m *stgHdlTxMock) Update(ctx context.Context, _ driver.Tx, dBase lib_model.DeviceData) error {
+	return m.stgHdlMock.Update(ctx, nil, dBase)
+}
+
+func (m *stgHdlTxMock) Delete(ctx context.Context, _ driver.Tx, id string) error {
+	return m.stgHdlMock.Delete(ctx, nil, id)
+}
+
+type txMock struct {
+	commit func()
+}
+
+func (m *txMock) Commit() error {
+	m.commit()
+	return nil
+}
+
+func (m *txMock) Rollback() error {
+	return nil
+}
+
+type eventsHdlMock struct {
+	events []lib_model.DeviceEvent
+}
+
+func (m *eventsHdlMock) Publish(event lib_model.DeviceEvent) {
+	m.events = append(m.events, event)
+}
+
+func (m *eventsHdlMock) Subscribe(_ lib_model.DevicesFilter) (string, <-chan lib_model.DeviceEvent) {
+	panic("not implemented")
+}
+
+func (m *eventsHdlMock) Unsubscribe(_ string) {
+	panic("not implemented")
+}
@@ -0,0 +1,40 @@
+package devices_hdl
+
+import "sync"
+
+// deviceLocks provides a mutex per device id, mutexes are removed when no longer in use.
+type deviceLocks struct {
+	mu    sync.Mutex
+	locks map[string]*deviceLock
+}
+
+type deviceLock struct {
+	mu    sync.Mutex
+	users int
+}
+
+func newDeviceLocks() *deviceLocks {
+	return &deviceLocks{locks: make(map[string]*deviceLock)}
+}
+
+// lock acquires the mutex of the device and returns a function to release it.
+func (l *deviceLocks) lock(id string) func() {
+	l.mu.Lock()
+	dl, ok := l.locks[id]
+	if !ok {
+		dl = &deviceLock{}
+		l.locks[id] = dl
+	}
+	dl.users++
+	l.mu.Unlock()
+	dl.mu.Lock()
+	return func() {
+		dl.mu.Unlock()
+		l.mu.Lock()
+		dl.users--
+		if dl.users == 0 {
+			delete(l.locks, id)
+		}
+		l.mu.Unlock()
+	}
+}
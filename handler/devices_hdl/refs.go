@@ -0,0 +1,117 @@
+package devices_hdl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"sort"
+	"time"
+)
+
+type refItem struct {
+	lastActivity time.Time
+	lastWill     bool
+}
+
+// GetRefs returns an overview of all references that own devices or have sent messages since startup.
+func (h *Handler) GetRefs(ctx context.Context) ([]lib_model.RefInfo, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	deviceBases, _, err := h.stgHdl.ReadAll(ctxWt, lib_model.DevicesFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("get refs: %s", err)
+	}
+	refs := make(map[string]*lib_model.RefInfo)
+	getRef := func(ref string) *lib_model.RefInfo {
+		refInfo, ok := refs[ref]
+		if !ok {
+			refInfo = &lib_model.RefInfo{
+				Ref:    ref,
+				States: make(map[lib_model.DeviceState]int),
+				Types:  make(map[string]int),
+			}
+			refs[ref] = refInfo
+		}
+		return refInfo
+	}
+	for _, deviceBase := range deviceBases {
+		device := h.newDevice(deviceBase)
+		refInfo := getRef(device.Ref)
+		refInfo.Devices++
+		refInfo.States[device.State]++
+		refInfo.Types[device.Type]++
+		if device.LastSeen.After(refInfo.LastActivity) {
+			refInfo.LastActivity = device.LastSeen
+		}
+	}
+	for ref, rItem := range h.refs {
+		refInfo := getRef(ref)
+		if rItem.lastActivity.After(refInfo.LastActivity) {
+			refInfo.LastActivity = rItem.lastActivity
+		}
+		refInfo.LastWill = rItem.lastWill
+	}
+	refInfos := make([]lib_model.RefInfo, 0, len(refs))
+	for _, refInfo := range refs {
+		refInfos = append(refInfos, *refInfo)
+	}
+	sort.Slice(refInfos, func(i, j int) bool {
+		return refInfos[i].Ref < refInfos[j].Ref
+	})
+	return refInfos, nil
+}
+
+// DeleteRef deletes all devices of a reference in one storage transaction.
+func (h *Handler) DeleteRef(ctx context.Context, ref string) error {
+	if ref == "" {
+		return lib_model.NewInvalidInputError(errors.New("empty reference"))
+	}
+	h.lock()
+	defer h.unlock()
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	devices, _, err := h.stgHdl.ReadAll(ctxWt, lib_model.DevicesFilter{Ref: ref})
+	if err != nil {
+		return fmt.Errorf("delete ref devices: %s", err)
+	}
+	if len(devices) > 0 {
+		if err = h.deleteDevices(ctx, devices); err != nil {
+			return fmt.Errorf("delete ref devices: %s", err)
+		}
+	}
+	delete(h.refs, ref)
+	delete(h.syncs, ref)
+	return nil
+}
+
+func (h *Handler) setRefActivity(ref string, timestamp time.Time) {
+	rItem := h.refs[ref]
+	rItem.lastActivity = timestamp
+	h.refs[ref] = rItem
+}
+
+func (h *Handler) deleteDevices(ctx context.Context, devices []lib_model.DeviceBase) error {
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	tx, err := h.stgHdl.BeginTransaction(ctxWt)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, device := range devices {
+		if err = h.remove(ctxWt, tx, device); err != nil {
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	timestamp := time.Now().UTC()
+	for _, device := range devices {
+		h.applyDelete(device, timestamp)
+	}
+	return nil
+}
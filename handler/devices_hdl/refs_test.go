@@ -0,0 +1,98 @@
+package devices_hdl
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/SENERGY-Platform/mgw-device-manager/util"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHandler_GetRefs(t *testing.T) {
+	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
+	stgHdl := &stgHdlMock{devices: make(map[string]lib_model.DeviceBase)}
+	h := New(stgHdl, time.Second)
+	devices := []lib_model.DeviceDataBase{
+		{ID: "1", Ref: "a", Type: "x"},
+		{ID: "2", Ref: "a", Type: "y"},
+		{ID: "3", Ref: "b", Type: "x"},
+	}
+	for _, device := range devices {
+		if err := h.Put(context.Background(), device, lib_model.Online); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.SetStates(context.Background(), "b", lib_model.Offline); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SetStates(context.Background(), "c", lib_model.Offline); err != nil {
+		t.Fatal(err)
+	}
+	refs, err := h.GetRefs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 3 {
+		t.Fatal("expected 3 refs, got", len(refs))
+	}
+	a := []lib_model.RefInfo{
+		{Ref: "a", Devices: 2, States: map[lib_model.DeviceState]int{lib_model.Online: 2}, Types: map[string]int{"x": 1, "y": 1}},
+		{Ref: "b", Devices: 1, States: map[lib_model.DeviceState]int{lib_model.Offline: 1}, Types: map[string]int{"x": 1}, LastWill: true},
+		{Ref: "c", States: map[lib_model.DeviceState]int{}, Types: map[string]int{}, LastWill: true},
+	}
+	for i := range refs {
+		if refs[i].LastActivity.IsZero() {
+			t.Error("last activity is zero", refs[i])
+		}
+		refs[i].LastActivity = time.Time{}
+	}
+	if !reflect.DeepEqual(a, refs) {
+		t.Error("expected\n", a, "got\n", refs)
+	}
+	t.Run("device message after last will", func(t *testing.T) {
+		if err = h.Put(context.Background(), devices[2], lib_model.Online); err != nil {
+			t.Fatal(err)
+		}
+		refs, err = h.GetRefs(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if refs[1].LastWill {
+			t.Error("last will not reset")
+		}
+	})
+}
+
+func TestHandler_DeleteRef(t *testing.T) {
+	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
+	stgHdl := &stgHdlTxMock{stgHdlStatesMock: stgHdlStatesMock{
+		stgHdlMock: stgHdlMock{devices: make(map[string]lib_model.DeviceBase)},
+		states:     make(map[string]handler.DeviceStateData),
+	}}
+	h := New(stgHdl, time.Second)
+	for _, id := range []string{"1", "2"} {
+		if err := h.Put(context.Background(), lib_model.DeviceDataBase{ID: id, Ref: "a", Type: "x"}, lib_model.Online); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.DeleteRef(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+	if len(stgHdl.devices) > 0 {
+		t.Error("devices not deleted")
+	}
+	if stgHdl.noDeadlineC != 0 {
+		t.Error("expected transaction with deadline")
+	}
+	if len(h.states) > 0 {
+		t.Error("states not removed")
+	}
+	if _, ok := h.refs["a"]; ok {
+		t.Error("ref not removed")
+	}
+	if err := h.DeleteRef(context.Background(), ""); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,102 @@
+package devices_hdl
+
+import (
+	"context"
+	"fmt"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/SENERGY-Platform/mgw-device-manager/util"
+	"time"
+)
+
+const logPrefix = "[devices-hdl]"
+
+type TTLConfig struct {
+	Default time.Duration
+	Types   map[string]time.Duration
+	Refs    map[string]time.Duration
+	// Stale devices are set to lib_model.Stale instead of lib_model.Offline.
+	Stale bool
+}
+
+// getTTL returns the ttl of a device, type specific values take precedence over ref specific values.
+func (c TTLConfig) getTTL(typ, ref string) time.Duration {
+	if ttl, ok := c.Types[typ]; ok {
+		return ttl
+	}
+	if ttl, ok := c.Refs[ref]; ok {
+		return ttl
+	}
+	return c.Default
+}
+
+func (c TTLConfig) enabled() bool {
+	if c.Default > 0 {
+		return true
+	}
+	for _, ttl := range c.Types {
+		if ttl > 0 {
+			return true
+		}
+	}
+	for _, ttl := range c.Refs {
+		if ttl > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// RunSweeper periodically sets online devices that have not been seen within their ttl to offline or stale. Blocks until ctx is done.
+func (h *Handler) RunSweeper(ctx context.Context, interval time.Duration, ttlConf TTLConfig) {
+	if !ttlConf.enabled() || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := h.sweep(ctx, ttlConf); err != nil {
+				util.Logger.Errorf("%s %s", logPrefix, err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (h *Handler) sweep(ctx context.Context, ttlConf TTLConfig) error {
+	state := lib_model.Offline
+	if ttlConf.Stale {
+		state = lib_model.Stale
+	}
+	h.lock()
+	defer h.unlock()
+	timestamp := time.Now().UTC()
+	expired := make(map[string]stateItem)
+	for id, sItem := range h.states {
+		if sItem.value != lib_model.Online || sItem.lastSeen.IsZero() {
+			continue
+		}
+		ttl := ttlConf.getTTL(sItem.typ, sItem.ref)
+		if ttl > 0 && timestamp.Sub(sItem.lastSeen) > ttl {
+			sItem.value = state
+			sItem.updated = timestamp
+			expired[id] = sItem
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+	if h.statesStgHdl != nil {
+		if err := h.storeStates(ctx, expired, lib_model.StateSrcTTL); err != nil {
+			return fmt.Errorf("set expired device states: %s", err)
+		}
+	}
+	for id, sItem := range expired {
+		h.states[id] = sItem
+		h.publishStateEvent(id, sItem, timestamp)
+		util.Logger.Infof("%s set device state (%s): %s", logPrefix, id, state)
+	}
+	return nil
+}
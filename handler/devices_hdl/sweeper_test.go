@@ -0,0 +1,63 @@
+package devices_hdl
+
+import (
+	"context"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/SENERGY-Platform/mgw-device-manager/util"
+	"testing"
+	"time"
+)
+
+func TestTTLConfig_getTTL(t *testing.T) {
+	c := TTLConfig{
+		Default: time.Minute,
+		Types:   map[string]time.Duration{"a": time.Second},
+		Refs:    map[string]time.Duration{"b": time.Hour},
+	}
+	if ttl := c.getTTL("a", "b"); ttl != time.Second {
+		t.Error("expected\n", time.Second, "got\n", ttl)
+	}
+	if ttl := c.getTTL("test", "b"); ttl != time.Hour {
+		t.Error("expected\n", time.Hour, "got\n", ttl)
+	}
+	if ttl := c.getTTL("test", "test"); ttl != time.Minute {
+		t.Error("expected\n", time.Minute, "got\n", ttl)
+	}
+}
+
+func TestHandler_sweep(t *testing.T) {
+	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
+	h := New(nil, 0)
+	h.states = map[string]stateItem{
+		"1": {ref: "test", typ: "test", value: lib_model.Online, lastSeen: time.Now().Add(-time.Hour)},
+		"2": {ref: "test", typ: "test", value: lib_model.Online, lastSeen: time.Now()},
+		"3": {ref: "test", typ: "test2", value: lib_model.Online, lastSeen: time.Now().Add(-time.Hour)},
+		"4": {ref: "test", typ: "test", value: lib_model.Offline, lastSeen: time.Now().Add(-time.Hour)},
+	}
+	if err := h.sweep(context.Background(), TTLConfig{Types: map[string]time.Duration{"test": time.Minute}}); err != nil {
+		t.Fatal(err)
+	}
+	if h.states["1"].value != lib_model.Offline {
+		t.Error("expected\n", lib_model.Offline, "got\n", h.states["1"].value)
+	}
+	if h.states["1"].updated.IsZero() {
+		t.Error("state timestamp not updated")
+	}
+	if h.states["2"].value != lib_model.Online {
+		t.Error("expected\n", lib_model.Online, "got\n", h.states["2"].value)
+	}
+	if h.states["3"].value != lib_model.Online {
+		t.Error("expected\n", lib_model.Online, "got\n", h.states["3"].value)
+	}
+	t.Run("stale", func(t *testing.T) {
+		if err := h.sweep(context.Background(), TTLConfig{Default: time.Minute, Stale: true}); err != nil {
+			t.Fatal(err)
+		}
+		if h.states["3"].value != lib_model.Stale {
+			t.Error("expected\n", lib_model.Stale, "got\n", h.states["3"].value)
+		}
+		if h.states["4"].value != lib_model.Offline {
+			t.Error("expected\n", lib_model.Offline, "got\n", h.states["4"].value)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package devices_hdl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/SENERGY-Platform/mgw-device-manager/util"
+	"time"
+)
+
+// SetSyncDelete sets whether devices not announced during a sync are deleted instead of set to lib_model.Stale.
+func (h *Handler) SetSyncDelete(syncDelete bool) {
+	h.syncDelete = syncDelete
+}
+
+// BeginSync starts a sync window for a reference, a running window of the reference is discarded.
+func (h *Handler) BeginSync(_ context.Context, ref string) error {
+	if ref == "" {
+		return lib_model.NewInvalidInputError(errors.New("empty reference"))
+	}
+	h.lock()
+	defer h.unlock()
+	h.syncs[ref] = make(map[string]struct{})
+	h.setRefActivity(ref, time.Now().UTC())
+	return nil
+}
+
+// EndSync closes the sync window of a reference. Devices of the reference that were not announced during the
+// window are either deleted or set to lib_model.Stale.
+func (h *Handler) EndSync(ctx context.Context, ref string) error {
+	h.lock()
+	defer h.unlock()
+	announced, ok := h.syncs[ref]
+	if !ok {
+		return lib_model.NewInvalidInputError(fmt.Errorf("no sync in progress for '%s'", ref))
+	}
+	delete(h.syncs, ref)
+	h.setRefActivity(ref, time.Now().UTC())
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	devices, _, err := h.stgHdl.ReadAll(ctxWt, lib_model.DevicesFilter{Ref: ref})
+	if err != nil {
+		return fmt.Errorf("end sync: %s", err)
+	}
+	var missing []lib_model.DeviceBase
+	for _, device := range devices {
+		if _, ok := announced[device.ID]; !ok {
+			missing = append(missing, device)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if h.syncDelete {
+		err = h.deleteDevices(ctx, missing)
+	} else {
+		err = h.markMissing(ctx, missing)
+	}
+	if err != nil {
+		return fmt.Errorf("end sync: %s", err)
+	}
+	return nil
+}
+
+func (h *Handler) markMissing(ctx context.Context, devices []lib_model.DeviceBase) error {
+	timestamp := time.Now().UTC()
+	changed := make(map[string]stateItem)
+	for _, device := range devices {
+		sItem, ok := h.states[device.ID]
+		if ok && sItem.value == lib_model.Stale {
+			continue
+		}
+		sItem.ref = device.Ref
+		sItem.typ = device.Type
+		sItem.value = lib_model.Stale
+		sItem.updated = timestamp
+		changed[device.ID] = sItem
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	if h.statesStgHdl != nil {
+		if err := h.storeStates(ctx, changed, lib_model.StateSrcSync); err != nil {
+			return err
+		}
+	}
+	for id, sItem := range changed {
+		h.states[id] = sItem
+		h.publishStateEvent(id, sItem, timestamp)
+		util.Logger.Infof("%s set device state (%s): %s", logPrefix, id, lib_model.Stale)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package devices_hdl
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/SENERGY-Platform/mgw-device-manager/util"
+	"testing"
+	"time"
+)
+
+func TestHandler_sync(t *testing.T) {
+	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
+	newDeviceData := func(id string) lib_model.DeviceDataBase {
+		return lib_model.DeviceDataBase{ID: id, Ref: "test", Name: "test", Type: "test"}
+	}
+	t.Run("mark", func(t *testing.T) {
+		stgHdl := &stgHdlMock{devices: make(map[string]lib_model.DeviceBase)}
+		h := New(stgHdl, time.Second)
+		for _, id := range []string{"1", "2", "3"} {
+			if err := h.Put(context.Background(), newDeviceData(id), lib_model.Online); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := h.BeginSync(context.Background(), "test"); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.Put(context.Background(), newDeviceData("1"), lib_model.Online); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.Put(context.Background(), newDeviceData("3"), lib_model.Offline); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.EndSync(context.Background(), "test"); err != nil {
+			t.Fatal(err)
+		}
+		for id, state := range map[string]lib_model.DeviceState{"1": lib_model.Online, "2": lib_model.Stale, "3": lib_model.Offline} {
+			if h.states[id].value != state {
+				t.Error(id, "expected\n", state, "got\n", h.states[id].value)
+			}
+		}
+		if len(stgHdl.devices) != 3 {
+			t.Error("unexpected deletion")
+		}
+		t.Run("no sync in progress", func(t *testing.T) {
+			if err := h.EndSync(context.Background(), "test"); err == nil {
+				t.Error("expected error")
+			}
+		})
+	})
+	t.Run("delete", func(t *testing.T) {
+		stgHdl := &stgHdlTxMock{stgHdlStatesMock: stgHdlStatesMock{
+			stgHdlMock: stgHdlMock{devices: make(map[string]lib_model.DeviceBase)},
+			states:     make(map[string]handler.DeviceStateData),
+		}}
+		eventsHdl := &eventsHdlMock{}
+		h := New(stgHdl, time.Second)
+		h.SetEventsHandler(eventsHdl)
+		h.SetSyncDelete(true)
+		for _, id := range []string{"1", "2"} {
+			if err := h.Put(context.Background(), newDeviceData(id), lib_model.Online); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := h.BeginSync(context.Background(), "test"); err != nil {
+			t.Fatal(err)
+		}
+		_, err := h.ApplyBatch(context.Background(), "test", []lib_model.DeviceMessage{
+			{Method: lib_model.Set, DeviceID: "1", Data: &lib_model.DeviceMessageData{Name: "test", State: lib_model.Online, Type: "test"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		eventsHdl.events = nil
+		if err = h.EndSync(context.Background(), "test"); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := stgHdl.devices["1"]; !ok {
+			t.Error("announced device deleted")
+		}
+		if _, ok := stgHdl.devices["2"]; ok {
+			t.Error("not deleted")
+		}
+		if _, ok := h.states["2"]; ok {
+			t.Error("state not removed")
+		}
+		if stgHdl.commitC != 2 {
+			t.Error("expected 2 commits, got", stgHdl.commitC)
+		}
+		if len(eventsHdl.events) != 1 || eventsHdl.events[0].Type != lib_model.EventDeleted || eventsHdl.events[0].DeviceID != "2" {
+			t.Error("invalid events", eventsHdl.events)
+		}
+	})
+}
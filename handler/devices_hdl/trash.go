@@ -0,0 +1,125 @@
+package devices_hdl
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/SENERGY-Platform/mgw-device-manager/util"
+	"time"
+)
+
+func (h *Handler) GetTrash(ctx context.Context) ([]lib_model.DeletedDevice, error) {
+	if h.trashStgHdl == nil {
+		return nil, lib_model.NewInternalError(errors.New("trash not supported by storage"))
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	devices, err := h.trashStgHdl.ReadTrash(ctxWt)
+	if err != nil {
+		return nil, fmt.Errorf("get trash: %s", err)
+	}
+	return devices, nil
+}
+
+// Restore recreates a deleted device with its user data, the state of the device is unknown until the next device message.
+func (h *Handler) Restore(ctx context.Context, id string) error {
+	if h.trashStgHdl == nil {
+		return lib_model.NewInternalError(errors.New("trash not supported by storage"))
+	}
+	h.lock()
+	defer h.unlock()
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	item, err := h.trashStgHdl.ReadTrashItem(ctxWt, id)
+	if err != nil {
+		return fmt.Errorf("restore device: %s", err)
+	}
+	ctxWt2, cf2 := context.WithTimeout(ctx, h.timeout)
+	defer cf2()
+	if _, err = h.stgHdl.Read(ctxWt2, id); err == nil {
+		return lib_model.NewResourceBusyError(fmt.Errorf("restore device: device '%s' exists", id))
+	}
+	ctxWt3, cf3 := context.WithTimeout(ctx, h.timeout)
+	defer cf3()
+	tx, err := h.stgHdl.BeginTransaction(ctxWt3)
+	if err != nil {
+		return fmt.Errorf("restore device: %s", err)
+	}
+	defer tx.Rollback()
+	if err = h.stgHdl.Create(ctxWt3, tx, item.DeviceData); err != nil {
+		return fmt.Errorf("restore device: %s", err)
+	}
+	if _, err = h.restoreUserData(ctxWt3, tx, id); err != nil {
+		return fmt.Errorf("restore device: %s", err)
+	}
+	timestamp := time.Now().UTC()
+	if err = h.audit(ctxWt3, tx, lib_model.AuditRestore, id, nil, &item.UserData, timestamp); err != nil {
+		return fmt.Errorf("restore device: %s", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("restore device: %s", err)
+	}
+	h.publishEvent(lib_model.EventCreated, h.newDevice(lib_model.DeviceBase{
+		DeviceData: item.DeviceData,
+		UserData:   item.UserData,
+	}), timestamp)
+	return nil
+}
+
+// RunTrashPurger periodically removes devices from the trash that have been deleted longer than the purge period. Blocks until ctx is done.
+func (h *Handler) RunTrashPurger(ctx context.Context, interval, period time.Duration) {
+	if h.trashStgHdl == nil || interval <= 0 || period <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+			n, err := h.trashStgHdl.PurgeTrash(ctxWt, time.Now().Add(-period))
+			cf()
+			if err != nil {
+				util.Logger.Errorf("%s purge trash: %s", logPrefix, err)
+				continue
+			}
+			if n > 0 {
+				util.Logger.Infof("%s purged %d devices from trash", logPrefix, n)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// restoreUserData applies the user data of a deleted device to a recreated device and removes the device from the trash.
+func (h *Handler) restoreUserData(ctx context.Context, tx driver.Tx, id string) (lib_model.DeviceUserData, error) {
+	if h.trashStgHdl == nil {
+		return lib_model.DeviceUserData{}, nil
+	}
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	item, err := h.trashStgHdl.ReadTrashItem(ctxWt, id)
+	if err != nil {
+		var nfe *lib_model.NotFoundError
+		if errors.As(err, &nfe) {
+			return lib_model.DeviceUserData{}, nil
+		}
+		return lib_model.DeviceUserData{}, err
+	}
+	ctxWt2, cf2 := context.WithTimeout(ctx, h.timeout)
+	defer cf2()
+	if err = h.stgHdl.UpdateUserData(ctxWt2, tx, id, item.UserData); err != nil {
+		return lib_model.DeviceUserData{}, err
+	}
+	ctxWt3, cf3 := context.WithTimeout(ctx, h.timeout)
+	defer cf3()
+	if err = h.trashStgHdl.DeleteTrashItem(ctxWt3, tx, id); err != nil {
+		return lib_model.DeviceUserData{}, err
+	}
+	return item.UserData, nil
+}
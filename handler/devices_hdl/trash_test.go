@@ -0,0 +1,136 @@
+package devices_hdl
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/SENERGY-Platform/mgw-device-manager/util"
+	"testing"
+	"time"
+)
+
+func TestHandler_trash(t *testing.T) {
+	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
+	stgHdl := &stgHdlTrashMock{
+		stgHdlTxMock: stgHdlTxMock{stgHdlStatesMock: stgHdlStatesMock{
+			stgHdlMock: stgHdlMock{devices: make(map[string]lib_model.DeviceBase)},
+			states:     make(map[string]handler.DeviceStateData),
+		}},
+		trash: make(map[string]lib_model.DeletedDevice),
+	}
+	eventsHdl := &eventsHdlMock{}
+	h := New(stgHdl, time.Second)
+	h.SetEventsHandler(eventsHdl)
+	if err := h.Put(context.Background(), deviceData.DeviceDataBase, lib_model.Online); err != nil {
+		t.Fatal(err)
+	}
+	userData := lib_model.DeviceUserDataBase{Name: "kitchen lamp", Attributes: []lib_model.DeviceAttribute{{Key: "room", Value: "kitchen"}}}
+	if err := h.SetUserData(context.Background(), id, "", userData); err != nil {
+		t.Fatal(err)
+	}
+	t.Run("delete", func(t *testing.T) {
+		if err := h.Delete(context.Background(), id, ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := stgHdl.devices[id]; ok {
+			t.Error("not deleted")
+		}
+		devices, err := h.GetTrash(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(devices) != 1 || devices[0].ID != id || devices[0].UserData.Name != userData.Name || devices[0].Deleted.IsZero() {
+			t.Error("invalid trash", devices)
+		}
+	})
+	t.Run("put re-associates user data", func(t *testing.T) {
+		commitC := stgHdl.commitC
+		if err := h.Put(context.Background(), deviceData.DeviceDataBase, lib_model.Online); err != nil {
+			t.Fatal(err)
+		}
+		if stgHdl.commitC != commitC+1 {
+			t.Error("expected device creation and user data restore in one transaction")
+		}
+		if device := stgHdl.devices[id]; device.UserData.Name != userData.Name || len(device.UserData.Attributes) != 1 {
+			t.Error("user data not restored", device.UserData)
+		}
+		if len(stgHdl.trash) > 0 {
+			t.Error("trash not cleared")
+		}
+		if event := eventsHdl.events[len(eventsHdl.events)-1]; event.Type != lib_model.EventCreated || event.Device.UserData.Name != userData.Name {
+			t.Error("invalid event", event)
+		}
+	})
+	t.Run("restore", func(t *testing.T) {
+		if err := h.Delete(context.Background(), id, ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.Restore(context.Background(), id); err != nil {
+			t.Fatal(err)
+		}
+		device, ok := stgHdl.devices[id]
+		if !ok {
+			t.Fatal("not restored")
+		}
+		if device.Name != deviceData.Name || device.UserData.Name != userData.Name {
+			t.Error("invalid device", device)
+		}
+		if len(stgHdl.trash) > 0 {
+			t.Error("trash not cleared")
+		}
+		if err := h.Restore(context.Background(), id); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+type stgHdlTrashMock struct {
+	stgHdlTxMock
+	trash map[string]lib_model.DeletedDevice
+}
+
+func (m *stgHdlTrashMock) UpdateUserData(ctx context.Context, _ driver.Tx, id string, userData lib_model.DeviceUserData) error {
+	return m.stgHdlMock.UpdateUserData(ctx, nil, id, userData)
+}
+
+func (m *stgHdlTrashMock) CreateTrashItem(_ context.Context, _ driver.Tx, device lib_model.DeletedDevice) error {
+	m.trash[device.ID] = device
+	return nil
+}
+
+func (m *stgHdlTrashMock) ReadTrashItem(_ context.Context, id string) (lib_model.DeletedDevice, error) {
+	device, ok := m.trash[id]
+	if !ok {
+		return lib_model.DeletedDevice{}, lib_model.NewNotFoundError(errors.New("not found"))
+	}
+	return device, nil
+}
+
+func (m *stgHdlTrashMock) ReadTrash(_ context.Context) ([]lib_model.DeletedDevice, error) {
+	var devices []lib_model.DeletedDevice
+	for _, device := range m.trash {
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+func (m *stgHdlTrashMock) DeleteTrashItem(_ context.Context, _ driver.Tx, id string) error {
+	if _, ok := m.trash[id]; !ok {
+		return lib_model.NewNotFoundError(errors.New("not found"))
+	}
+	delete(m.trash, id)
+	return nil
+}
+
+func (m *stgHdlTrashMock) PurgeTrash(_ context.Context, before time.Time) (int, error) {
+	var n int
+	for id, device := range m.trash {
+		if device.Deleted.Before(before) {
+			delete(m.trash, id)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,95 @@
+package events_hdl
+
+import (
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/SENERGY-Platform/mgw-device-manager/util"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+const logPrefix = "[events-hdl]"
+
+type subscription struct {
+	filter   lib_model.DevicesFilter
+	messages chan lib_model.DeviceEvent
+	dropped  atomic.Uint64
+}
+
+type Handler struct {
+	buffer        int
+	subscriptions map[string]*subscription
+	count         uint64
+	mu            sync.RWMutex
+}
+
+func New(buffer int) *Handler {
+	return &Handler{
+		buffer:        buffer,
+		subscriptions: make(map[string]*subscription),
+	}
+}
+
+// Publish passes an event to all matching subscriptions without blocking, events are dropped for subscribers whose buffer is full.
+// A warning is logged when a subscription starts dropping events and the number of dropped events once delivery resumes.
+func (h *Handler) Publish(event lib_model.DeviceEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for id, sub := range h.subscriptions {
+		if !match(sub.filter, event) {
+			continue
+		}
+		select {
+		case sub.messages <- event:
+			if n := sub.dropped.Swap(0); n > 0 {
+				util.Logger.Warningf("%s subscription %s: dropped %d events", logPrefix, id, n)
+			}
+		default:
+			if sub.dropped.Add(1) == 1 {
+				util.Logger.Warningf("%s subscription %s: buffer full, dropping events", logPrefix, id)
+			}
+		}
+	}
+}
+
+func (h *Handler) Subscribe(filter lib_model.DevicesFilter) (string, <-chan lib_model.DeviceEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.count++
+	id := strconv.FormatUint(h.count, 10)
+	sub := &subscription{
+		filter:   filter,
+		messages: make(chan lib_model.DeviceEvent, h.buffer),
+	}
+	h.subscriptions[id] = sub
+	return id, sub.messages
+}
+
+func (h *Handler) Unsubscribe(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if sub, ok := h.subscriptions[id]; ok {
+		if n := sub.dropped.Load(); n > 0 {
+			util.Logger.Warningf("%s subscription %s: dropped %d events", logPrefix, id, n)
+		}
+		close(sub.messages)
+		delete(h.subscriptions, id)
+	}
+}
+
+func match(filter lib_model.DevicesFilter, event lib_model.DeviceEvent) bool {
+	if len(filter.IDs) > 0 && !slices.Contains(filter.IDs, event.DeviceID) {
+		return false
+	}
+	if filter.State != "" && filter.State != event.State {
+		return false
+	}
+	if filter.Type != "" && filter.Type != event.DeviceType {
+		return false
+	}
+	if filter.Ref != "" && filter.Ref != event.Ref {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package events_hdl
+
+import (
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/SENERGY-Platform/mgw-device-manager/util"
+	"reflect"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
+	h := New(1)
+	id, events := h.Subscribe(lib_model.DevicesFilter{Ref: "test"})
+	id2, events2 := h.Subscribe(lib_model.DevicesFilter{})
+	a := lib_model.DeviceEvent{
+		Type:       lib_model.EventStateChanged,
+		DeviceID:   "1",
+		Ref:        "test",
+		DeviceType: "test",
+		State:      lib_model.Online,
+	}
+	b := lib_model.DeviceEvent{
+		Type:     lib_model.EventStateChanged,
+		DeviceID: "2",
+		Ref:      "test2",
+	}
+	h.Publish(a)
+	h.Publish(b)
+	if len(events) != 1 {
+		t.Fatal("expected 1 event, got", len(events))
+	}
+	if e := <-events; !reflect.DeepEqual(a, e) {
+		t.Error("expected\n", a, "got\n", e)
+	}
+	t.Run("buffer full", func(t *testing.T) {
+		if len(events2) != 1 {
+			t.Fatal("expected 1 event, got", len(events2))
+		}
+		if e := <-events2; !reflect.DeepEqual(a, e) {
+			t.Error("expected\n", a, "got\n", e)
+		}
+		if n := h.subscriptions[id2].dropped.Load(); n != 1 {
+			t.Error("expected 1 dropped event, got", n)
+		}
+		h.Publish(b)
+		if n := h.subscriptions[id2].dropped.Load(); n != 0 {
+			t.Error("expected dropped events to be reset, got", n)
+		}
+		<-events2
+	})
+	t.Run("unsubscribe", func(t *testing.T) {
+		h.Unsubscribe(id)
+		h.Unsubscribe(id2)
+		if _, ok := <-events; ok {
+			t.Error("channel not closed")
+		}
+		if len(h.subscriptions) != 0 {
+			t.Error("expected 0 subscriptions")
+		}
+		h.Publish(a)
+	})
+}
+
+func Test_match(t *testing.T) {
+	event := lib_model.DeviceEvent{
+		DeviceID:   "1",
+		Ref:        "ref",
+		DeviceType: "type",
+		State:      lib_model.Online,
+	}
+	tests := []struct {
+		name   string
+		filter lib_model.DevicesFilter
+		want   bool
+	}{
+		{"empty", lib_model.DevicesFilter{}, true},
+		{"ids", lib_model.DevicesFilter{IDs: []string{"2", "1"}}, true},
+		{"ids no match", lib_model.DevicesFilter{IDs: []string{"2"}}, false},
+		{"state", lib_model.DevicesFilter{State: lib_model.Online}, true},
+		{"state no match", lib_model.DevicesFilter{State: lib_model.Offline}, false},
+		{"type", lib_model.DevicesFilter{Type: "type"}, true},
+		{"type no match", lib_model.DevicesFilter{Type: "test"}, false},
+		{"ref", lib_model.DevicesFilter{Ref: "ref"}, true},
+		{"ref no match", lib_model.DevicesFilter{Ref: "test"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := match(tt.filter, event); got != tt.want {
+				t.Error("expected", tt.want, "got", got)
+			}
+		})
+	}
+}
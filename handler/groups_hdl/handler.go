@@ -0,0 +1,188 @@
+package groups_hdl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/google/uuid"
+	"slices"
+	"sync"
+	"time"
+)
+
+type Handler struct {
+	stgHdl        handler.GroupsStorageHandler
+	devicesStgHdl handler.DevicesStorageHandler
+	timeout       time.Duration
+	mu            sync.RWMutex
+}
+
+func New(stgHdl handler.GroupsStorageHandler, devicesStgHdl handler.DevicesStorageHandler, timeout time.Duration) *Handler {
+	return &Handler{
+		stgHdl:        stgHdl,
+		devicesStgHdl: devicesStgHdl,
+		timeout:       timeout,
+	}
+}
+
+func (h *Handler) Create(ctx context.Context, groupBase lib_model.GroupBase) (string, error) {
+	if err := validateGroupBase(groupBase); err != nil {
+		return "", lib_model.NewInvalidInputError(err)
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", lib_model.NewInternalError(fmt.Errorf("create group: %s", err))
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	err = h.stgHdl.CreateGroup(ctxWt, nil, lib_model.Group{
+		ID:        id.String(),
+		GroupBase: groupBase,
+		Created:   time.Now().UTC(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("create group: %s", err)
+	}
+	return id.String(), nil
+}
+
+func (h *Handler) Get(ctx context.Context, id string) (lib_model.Group, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	group, err := h.stgHdl.ReadGroup(ctxWt, id)
+	if err != nil {
+		return lib_model.Group{}, fmt.Errorf("get group: %s", err)
+	}
+	return group, nil
+}
+
+func (h *Handler) GetAll(ctx context.Context) ([]lib_model.Group, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	groups, err := h.stgHdl.ReadGroups(ctxWt)
+	if err != nil {
+		return nil, fmt.Errorf("get groups: %s", err)
+	}
+	return groups, nil
+}
+
+func (h *Handler) Update(ctx context.Context, id string, groupBase lib_model.GroupBase) error {
+	if err := validateGroupBase(groupBase); err != nil {
+		return lib_model.NewInvalidInputError(err)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	err := h.stgHdl.UpdateGroup(ctxWt, nil, lib_model.Group{
+		ID:        id,
+		GroupBase: groupBase,
+		Updated:   time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("update group: %s", err)
+	}
+	return nil
+}
+
+func (h *Handler) Delete(ctx context.Context, id string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	if err := h.stgHdl.DeleteGroup(ctxWt, nil, id); err != nil {
+		return fmt.Errorf("delete group: %s", err)
+	}
+	return nil
+}
+
+func (h *Handler) SetDevices(ctx context.Context, id string, deviceIDs []string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.setDevices(ctx, id, deviceIDs); err != nil {
+		return fmt.Errorf("set group devices: %s", err)
+	}
+	return nil
+}
+
+func (h *Handler) AddDevice(ctx context.Context, id, deviceID string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	group, err := h.stgHdl.ReadGroup(ctxWt, id)
+	if err != nil {
+		return fmt.Errorf("add group device: %s", err)
+	}
+	if slices.Contains(group.DeviceIDs, deviceID) {
+		return nil
+	}
+	if err = h.setDevices(ctx, id, append(group.DeviceIDs, deviceID)); err != nil {
+		return fmt.Errorf("add group device: %s", err)
+	}
+	return nil
+}
+
+func (h *Handler) RemoveDevice(ctx context.Context, id, deviceID string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	group, err := h.stgHdl.ReadGroup(ctxWt, id)
+	if err != nil {
+		return fmt.Errorf("remove group device: %s", err)
+	}
+	i := slices.Index(group.DeviceIDs, deviceID)
+	if i < 0 {
+		return lib_model.NewNotFoundError(fmt.Errorf("remove group device: device '%s' not a member", deviceID))
+	}
+	ctxWt2, cf2 := context.WithTimeout(ctx, h.timeout)
+	defer cf2()
+	if err = h.stgHdl.UpdateGroupDevices(ctxWt2, nil, id, slices.Delete(group.DeviceIDs, i, i+1)); err != nil {
+		return fmt.Errorf("remove group device: %s", err)
+	}
+	return nil
+}
+
+func (h *Handler) setDevices(ctx context.Context, id string, deviceIDs []string) error {
+	ctxWt, cf := context.WithTimeout(ctx, h.timeout)
+	defer cf()
+	if _, err := h.stgHdl.ReadGroup(ctxWt, id); err != nil {
+		return err
+	}
+	if len(deviceIDs) > 0 {
+		ctxWt2, cf2 := context.WithTimeout(ctx, h.timeout)
+		defer cf2()
+		devices, _, err := h.devicesStgHdl.ReadAll(ctxWt2, lib_model.DevicesFilter{IDs: deviceIDs})
+		if err != nil {
+			return err
+		}
+		existing := make(map[string]struct{})
+		for _, device := range devices {
+			existing[device.ID] = struct{}{}
+		}
+		for _, deviceID := range deviceIDs {
+			if _, ok := existing[deviceID]; !ok {
+				return lib_model.NewNotFoundError(fmt.Errorf("device '%s' not found", deviceID))
+			}
+		}
+	}
+	ctxWt3, cf3 := context.WithTimeout(ctx, h.timeout)
+	defer cf3()
+	return h.stgHdl.UpdateGroupDevices(ctxWt3, nil, id, deviceIDs)
+}
+
+func validateGroupBase(groupBase lib_model.GroupBase) error {
+	if groupBase.Name == "" {
+		return errors.New("empty name")
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package groups_hdl
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	stgHdl := &stgHdlMock{groups: make(map[string]lib_model.Group)}
+	devicesStgHdl := &devicesStgHdlMock{devices: map[string]lib_model.DeviceBase{"1": {}, "2": {}}}
+	h := New(stgHdl, devicesStgHdl, time.Second)
+	var id string
+	t.Run("create", func(t *testing.T) {
+		var err error
+		id, err = h.Create(context.Background(), lib_model.GroupBase{Name: "test"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		group, ok := stgHdl.groups[id]
+		if !ok {
+			t.Fatal("not created")
+		}
+		if group.Name != "test" || group.Created.IsZero() {
+			t.Error("invalid group", group)
+		}
+		if _, err = h.Create(context.Background(), lib_model.GroupBase{}); err == nil {
+			t.Error("expected error")
+		}
+	})
+	t.Run("update", func(t *testing.T) {
+		if err := h.Update(context.Background(), id, lib_model.GroupBase{Name: "test2"}); err != nil {
+			t.Fatal(err)
+		}
+		if group := stgHdl.groups[id]; group.Name != "test2" || group.Updated.IsZero() {
+			t.Error("invalid group", group)
+		}
+		if err := h.Update(context.Background(), "test", lib_model.GroupBase{Name: "test2"}); err == nil {
+			t.Error("expected error")
+		}
+	})
+	t.Run("set devices", func(t *testing.T) {
+		if err := h.SetDevices(context.Background(), id, []string{"1"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.SetDevices(context.Background(), id, []string{"1", "3"}); err == nil {
+			t.Error("expected error")
+		}
+		if err := h.SetDevices(context.Background(), "test", []string{"1"}); err == nil {
+			t.Error("expected error")
+		}
+		if ids := stgHdl.groups[id].DeviceIDs; !reflect.DeepEqual(ids, []string{"1"}) {
+			t.Error("expected [1] got", ids)
+		}
+	})
+	t.Run("add device", func(t *testing.T) {
+		if err := h.AddDevice(context.Background(), id, "2"); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.AddDevice(context.Background(), id, "2"); err != nil {
+			t.Fatal(err)
+		}
+		if ids := stgHdl.groups[id].DeviceIDs; !reflect.DeepEqual(ids, []string{"1", "2"}) {
+			t.Error("expected [1 2] got", ids)
+		}
+		if err := h.AddDevice(context.Background(), id, "3"); err == nil {
+			t.Error("expected error")
+		}
+	})
+	t.Run("remove device", func(t *testing.T) {
+		if err := h.RemoveDevice(context.Background(), id, "1"); err != nil {
+			t.Fatal(err)
+		}
+		if ids := stgHdl.groups[id].DeviceIDs; !reflect.DeepEqual(ids, []string{"2"}) {
+			t.Error("expected [2] got", ids)
+		}
+		err := h.RemoveDevice(context.Background(), id, "1")
+		var nfe *lib_model.NotFoundError
+		if !errors.As(err, &nfe) {
+			t.Error("expected not found error, got", err)
+		}
+	})
+	t.Run("delete", func(t *testing.T) {
+		if err := h.Delete(context.Background(), id); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := stgHdl.groups[id]; ok {
+			t.Error("not deleted")
+		}
+	})
+}
+
+type stgHdlMock struct {
+	groups map[string]lib_model.Group
+}
+
+func (m *stgHdlMock) CreateGroup(_ context.Context, _ driver.Tx, group lib_model.Group) error {
+	m.groups[group.ID] = group
+	return nil
+}
+
+func (m *stgHdlMock) ReadGroup(_ context.Context, id string) (lib_model.Group, error) {
+	group, ok := m.groups[id]
+	if !ok {
+		return lib_model.Group{}, lib_model.NewNotFoundError(errors.New("not found"))
+	}
+	return group, nil
+}
+
+func (m *stgHdlMock) ReadGroups(_ context.Context) ([]lib_model.Group, error) {
+	panic("not implemented")
+}
+
+func (m *stgHdlMock) UpdateGroup(_ context.Context, _ driver.Tx, group lib_model.Group) error {
+	g, ok := m.groups[group.ID]
+	if !ok {
+		return lib_model.NewNotFoundError(errors.New("not found"))
+	}
+	g.GroupBase = group.GroupBase
+	g.Updated = group.Updated
+	m.groups[group.ID] = g
+	return nil
+}
+
+func (m *stgHdlMock) UpdateGroupDevices(_ context.Context, _ driver.Tx, id string, deviceIDs []string) error {
+	g, ok := m.groups[id]
+	if !ok {
+		return lib_model.NewNotFoundError(errors.New("not found"))
+	}
+	g.DeviceIDs = deviceIDs
+	m.groups[id] = g
+	return nil
+}
+
+func (m *stgHdlMock) DeleteGroup(_ context.Context, _ driver.Tx, id string) error {
+	if _, ok := m.groups[id]; !ok {
+		return lib_model.NewNotFoundError(errors.New("not found"))
+	}
+	delete(m.groups, id)
+	return nil
+}
+
+type devicesStgHdlMock struct {
+	devices map[string]lib_model.DeviceBase
+}
+
+func (m *devicesStgHdlMock) BeginTransaction(_ context.Context) (driver.Tx, error) {
+	panic("not implemented")
+}
+
+func (m *devicesStgHdlMock) Create(_ context.Context, _ driver.Tx, _ lib_model.DeviceData) error {
+	panic("not implemented")
+}
+
+func (m *devicesStgHdlMock) Read(_ context.Context, _ string) (lib_model.DeviceBase, error) {
+	panic("not implemented")
+}
+
+func (m *devicesStgHdlMock) ReadAll(_ context.Context, filter lib_model.DevicesFilter) ([]lib_model.DeviceBase, int, error) {
+	var devices []lib_model.DeviceBase
+	for _, id := range filter.IDs {
+		if device, ok := m.devices[id]; ok {
+			device.ID = id
+			devices = append(devices, device)
+		}
+	}
+	return devices, len(devices), nil
+}
+
+func (m *devicesStgHdlMock) Update(_ context.Context, _ driver.Tx, _ lib_model.DeviceData) error {
+	panic("not implemented")
+}
+
+func (m *devicesStgHdlMock) UpdateUserData(_ context.Context, _ driver.Tx, _ string, _ lib_model.DeviceUserData) error {
+	panic("not implemented")
+}
+
+func (m *devicesStgHdlMock) Delete(_ context.Context, _ driver.Tx, _ string) error {
+	panic("not implemented")
+}
@@ -0,0 +1,37 @@
+package http_hdl
+
+import (
+	"github.com/SENERGY-Platform/mgw-device-manager/lib"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+type auditLogQuery struct {
+	Device string    `form:"device"`
+	From   time.Time `form:"from"`
+	To     time.Time `form:"to"`
+	Limit  int       `form:"limit"`
+}
+
+func getAuditLogH(a lib.Api) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		var query auditLogQuery
+		if err := gc.ShouldBindQuery(&query); err != nil {
+			_ = gc.Error(lib_model.NewInvalidInputError(err))
+			return
+		}
+		entries, err := a.GetAuditLog(gc.Request.Context(), lib_model.AuditLogFilter{
+			DeviceID: query.Device,
+			From:     query.From,
+			To:       query.To,
+			Limit:    query.Limit,
+		})
+		if err != nil {
+			_ = gc.Error(err)
+			return
+		}
+		gc.JSON(http.StatusOK, entries)
+	}
+}
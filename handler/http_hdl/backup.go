@@ -0,0 +1,24 @@
+package http_hdl
+
+import (
+	"fmt"
+	"github.com/SENERGY-Platform/mgw-device-manager/lib"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+func getBackupH(a lib.Api) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		rc, size, err := a.GetBackup(gc.Request.Context())
+		if err != nil {
+			_ = gc.Error(err)
+			return
+		}
+		defer rc.Close()
+		gc.DataFromReader(http.StatusOK, size, lib_model.ContentTypeSQLite, rc, map[string]string{
+			"Content-Disposition": fmt.Sprintf("attachment; filename=\"device-manager_%s.db\"", time.Now().UTC().Format("20060102T150405Z")),
+		})
+	}
+}
@@ -1,19 +1,36 @@
 package http_hdl
 
 import (
+	"errors"
 	"github.com/SENERGY-Platform/mgw-device-manager/lib"
 	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
 	"github.com/gin-gonic/gin"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 const devIdParam = "d"
 
 type devicesQuery struct {
-	IDs   string `form:"ids"`
-	State string `form:"state"`
-	Type  string `form:"type"`
-	Ref   string `form:"ref"`
+	IDs     string   `form:"ids"`
+	State   string   `form:"state"`
+	Type    string   `form:"type"`
+	Ref     string   `form:"ref"`
+	Attr    []string `form:"attr"`
+	UsrAttr []string `form:"usr_attr"`
+	Group   string   `form:"group"`
+	Search  string   `form:"search"`
+	Sort    string   `form:"sort"`
+	Order   string   `form:"order"`
+	Limit   int      `form:"limit"`
+	Offset  int      `form:"offset"`
+}
+
+type stateHistoryQuery struct {
+	From time.Time `form:"from"`
+	To   time.Time `form:"to"`
 }
 
 func getDevicesH(a lib.Api) gin.HandlerFunc {
@@ -23,7 +40,42 @@ func getDevicesH(a lib.Api) gin.HandlerFunc {
 			_ = gc.Error(lib_model.NewInvalidInputError(err))
 			return
 		}
-		devices, err := a.GetDevices(gc.Request.Context(), lib_model.DevicesFilter{
+		sortDesc, err := parseSortOrder(query.Order)
+		if err != nil {
+			_ = gc.Error(lib_model.NewInvalidInputError(err))
+			return
+		}
+		devices, total, err := a.GetDevices(gc.Request.Context(), lib_model.DevicesFilter{
+			IDs:            parseStringSlice(query.IDs, ","),
+			State:          query.State,
+			Type:           query.Type,
+			Ref:            query.Ref,
+			Attributes:     parseAttributeFilters(query.Attr),
+			UserAttributes: parseAttributeFilters(query.UsrAttr),
+			Group:          query.Group,
+			Search:         query.Search,
+			SortBy:         query.Sort,
+			SortDesc:       sortDesc,
+			Limit:          query.Limit,
+			Offset:         query.Offset,
+		})
+		if err != nil {
+			_ = gc.Error(err)
+			return
+		}
+		gc.Header(lib_model.HeaderTotalCount, strconv.Itoa(total))
+		gc.JSON(http.StatusOK, devices)
+	}
+}
+
+func getDeviceEventsH(a lib.Api) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		var query devicesQuery
+		if err := gc.ShouldBindQuery(&query); err != nil {
+			_ = gc.Error(lib_model.NewInvalidInputError(err))
+			return
+		}
+		events, err := a.GetDeviceEvents(gc.Request.Context(), lib_model.DevicesFilter{
 			IDs:   parseStringSlice(query.IDs, ","),
 			State: query.State,
 			Type:  query.Type,
@@ -33,7 +85,19 @@ func getDevicesH(a lib.Api) gin.HandlerFunc {
 			_ = gc.Error(err)
 			return
 		}
-		gc.JSON(http.StatusOK, devices)
+		gc.Header("Content-Type", "text/event-stream")
+		gc.Header("Cache-Control", "no-cache")
+		gc.Header("Connection", "keep-alive")
+		gc.Status(http.StatusOK)
+		gc.Writer.Flush()
+		gc.Stream(func(_ io.Writer) bool {
+			event, ok := <-events
+			if !ok {
+				return false
+			}
+			gc.SSEvent(event.Type, event)
+			return true
+		})
 	}
 }
 
@@ -44,19 +108,62 @@ func getDeviceH(a lib.Api) gin.HandlerFunc {
 			_ = gc.Error(err)
 			return
 		}
+		gc.Header(lib_model.HeaderETag, lib_model.DeviceETag(device.DeviceBase))
 		gc.JSON(http.StatusOK, device)
 	}
 }
 
+func getDeviceStateHistoryH(a lib.Api) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		var query stateHistoryQuery
+		if err := gc.ShouldBindQuery(&query); err != nil {
+			_ = gc.Error(lib_model.NewInvalidInputError(err))
+			return
+		}
+		transitions, err := a.GetDeviceStateHistory(gc.Request.Context(), gc.Param(devIdParam), lib_model.DeviceStateHistoryFilter{
+			From: query.From,
+			To:   query.To,
+		})
+		if err != nil {
+			_ = gc.Error(err)
+			return
+		}
+		gc.JSON(http.StatusOK, transitions)
+	}
+}
+
 func patchUpdateDeviceUserDataH(a lib.Api) gin.HandlerFunc {
 	return func(gc *gin.Context) {
+		switch gc.ContentType() {
+		case lib_model.ContentTypeMergePatch:
+			_ = gc.Error(lib_model.NewInvalidInputError(errors.New("JSON merge patch not supported, use " + lib_model.ContentTypeUserDataPatch)))
+			return
+		case lib_model.ContentTypeUserDataPatch:
+			b, err := gc.GetRawData()
+			if err != nil {
+				_ = gc.Error(lib_model.NewInvalidInputError(err))
+				return
+			}
+			patch, err := parseUserDataPatch(b)
+			if err != nil {
+				_ = gc.Error(lib_model.NewInvalidInputError(err))
+				return
+			}
+			err = a.PatchDeviceUserData(gc.Request.Context(), gc.Param(devIdParam), gc.GetHeader(lib_model.HeaderIfMatch), patch)
+			if err != nil {
+				_ = gc.Error(err)
+				return
+			}
+			gc.Status(http.StatusOK)
+			return
+		}
 		var userDataBase lib_model.DeviceUserDataBase
 		err := gc.ShouldBindJSON(&userDataBase)
 		if err != nil {
 			_ = gc.Error(lib_model.NewInvalidInputError(err))
 			return
 		}
-		err = a.UpdateDeviceUserData(gc.Request.Context(), gc.Param(devIdParam), userDataBase)
+		err = a.UpdateDeviceUserData(gc.Request.Context(), gc.Param(devIdParam), gc.GetHeader(lib_model.HeaderIfMatch), userDataBase)
 		if err != nil {
 			_ = gc.Error(err)
 			return
@@ -67,7 +174,7 @@ func patchUpdateDeviceUserDataH(a lib.Api) gin.HandlerFunc {
 
 func deleteDeviceH(a lib.Api) gin.HandlerFunc {
 	return func(gc *gin.Context) {
-		err := a.DeleteDevice(gc.Request.Context(), gc.Param(devIdParam))
+		err := a.DeleteDevice(gc.Request.Context(), gc.Param(devIdParam), gc.GetHeader(lib_model.HeaderIfMatch))
 		if err != nil {
 			_ = gc.Error(err)
 			return
@@ -0,0 +1,219 @@
+package http_hdl
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/mgw-device-manager/lib"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+)
+
+var csvHeader = []string{"id", "ref", "name", "type", "attributes", "created", "updated", "usr_name", "usr_attributes", "usr_updated"}
+
+type exportQuery struct {
+	Format string `form:"format"`
+}
+
+type importQuery struct {
+	Mode string `form:"mode"`
+}
+
+func getExportH(a lib.Api) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		var query exportQuery
+		if err := gc.ShouldBindQuery(&query); err != nil {
+			_ = gc.Error(lib_model.NewInvalidInputError(err))
+			return
+		}
+		switch query.Format {
+		case "", formatJSON, formatCSV:
+		default:
+			_ = gc.Error(lib_model.NewInvalidInputError(errors.New("invalid format")))
+			return
+		}
+		export, err := a.Export(gc.Request.Context())
+		if err != nil {
+			_ = gc.Error(err)
+			return
+		}
+		if query.Format != formatCSV {
+			gc.JSON(http.StatusOK, export)
+			return
+		}
+		gc.Header("Content-Type", lib_model.ContentTypeCSV)
+		gc.Status(http.StatusOK)
+		if err = writeExportCSV(gc.Writer, export); err != nil {
+			_ = gc.Error(lib_model.NewInternalError(err))
+		}
+	}
+}
+
+func postImportH(a lib.Api) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		var query importQuery
+		if err := gc.ShouldBindQuery(&query); err != nil {
+			_ = gc.Error(lib_model.NewInvalidInputError(err))
+			return
+		}
+		var export lib_model.Export
+		var err error
+		if gc.ContentType() == lib_model.ContentTypeCSV {
+			export, err = readExportCSV(gc.Request.Body)
+		} else {
+			err = gc.ShouldBindJSON(&export)
+		}
+		if err != nil {
+			_ = gc.Error(lib_model.NewInvalidInputError(err))
+			return
+		}
+		result, err := a.Import(gc.Request.Context(), query.Mode, export)
+		if err != nil {
+			_ = gc.Error(err)
+			return
+		}
+		gc.JSON(http.StatusOK, result)
+	}
+}
+
+// writeExportCSV writes one device per row, attributes are encoded as JSON arrays.
+func writeExportCSV(w io.Writer, export lib_model.Export) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, device := range export.Devices {
+		attributes, err := formatCSVAttributes(device.Attributes)
+		if err != nil {
+			return err
+		}
+		usrAttributes, err := formatCSVAttributes(device.UserData.Attributes)
+		if err != nil {
+			return err
+		}
+		err = cw.Write([]string{
+			device.ID,
+			device.Ref,
+			device.Name,
+			device.Type,
+			attributes,
+			formatCSVTime(device.Created),
+			formatCSVTime(device.Updated),
+			device.UserData.Name,
+			usrAttributes,
+			formatCSVTime(device.UserData.Updated),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// readExportCSV reads devices written by writeExportCSV, the document is assumed to be of the current export version.
+func readExportCSV(r io.Reader) (lib_model.Export, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(csvHeader)
+	header, err := cr.Read()
+	if err != nil {
+		return lib_model.Export{}, err
+	}
+	if !slices.Equal(header, csvHeader) {
+		return lib_model.Export{}, errors.New("invalid header")
+	}
+	export := lib_model.Export{Version: lib_model.ExportVersion}
+	for {
+		record, err := cr.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return lib_model.Export{}, err
+		}
+		line, _ := cr.FieldPos(0)
+		device, err := parseCSVRecord(record)
+		if err != nil {
+			return lib_model.Export{}, fmt.Errorf("line %d: %s", line, err)
+		}
+		export.Devices = append(export.Devices, device)
+	}
+	return export, nil
+}
+
+func parseCSVRecord(record []string) (lib_model.ExportDevice, error) {
+	device := lib_model.ExportDevice{
+		DeviceData: lib_model.DeviceData{
+			DeviceDataBase: lib_model.DeviceDataBase{
+				ID:   record[0],
+				Ref:  record[1],
+				Name: record[2],
+				Type: record[3],
+			},
+		},
+	}
+	device.UserData.Name = record[7]
+	var err error
+	if device.Attributes, err = parseCSVAttributes(record[4]); err != nil {
+		return lib_model.ExportDevice{}, fmt.Errorf("attributes: %s", err)
+	}
+	if device.UserData.Attributes, err = parseCSVAttributes(record[8]); err != nil {
+		return lib_model.ExportDevice{}, fmt.Errorf("user attributes: %s", err)
+	}
+	if device.Created, err = parseCSVTime(record[5]); err != nil {
+		return lib_model.ExportDevice{}, fmt.Errorf("created: %s", err)
+	}
+	if device.Updated, err = parseCSVTime(record[6]); err != nil {
+		return lib_model.ExportDevice{}, fmt.Errorf("updated: %s", err)
+	}
+	if device.UserData.Updated, err = parseCSVTime(record[9]); err != nil {
+		return lib_model.ExportDevice{}, fmt.Errorf("user data updated: %s", err)
+	}
+	return device, nil
+}
+
+func formatCSVAttributes(attributes []lib_model.DeviceAttribute) (string, error) {
+	if len(attributes) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(attributes)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func parseCSVAttributes(s string) ([]lib_model.DeviceAttribute, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var attributes []lib_model.DeviceAttribute
+	if err := json.Unmarshal([]byte(s), &attributes); err != nil {
+		return nil, err
+	}
+	return attributes, nil
+}
+
+func formatCSVTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func parseCSVTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
@@ -0,0 +1,119 @@
+package http_hdl
+
+import (
+	"github.com/SENERGY-Platform/mgw-device-manager/lib"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+const grpIdParam = "g"
+
+func getGroupsH(a lib.Api) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		groups, err := a.GetGroups(gc.Request.Context())
+		if err != nil {
+			_ = gc.Error(err)
+			return
+		}
+		gc.JSON(http.StatusOK, groups)
+	}
+}
+
+func getGroupH(a lib.Api) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		group, err := a.GetGroup(gc.Request.Context(), gc.Param(grpIdParam))
+		if err != nil {
+			_ = gc.Error(err)
+			return
+		}
+		gc.JSON(http.StatusOK, group)
+	}
+}
+
+func postCreateGroupH(a lib.Api) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		var groupBase lib_model.GroupBase
+		if err := gc.ShouldBindJSON(&groupBase); err != nil {
+			_ = gc.Error(lib_model.NewInvalidInputError(err))
+			return
+		}
+		id, err := a.CreateGroup(gc.Request.Context(), groupBase)
+		if err != nil {
+			_ = gc.Error(err)
+			return
+		}
+		gc.String(http.StatusOK, id)
+	}
+}
+
+func putUpdateGroupH(a lib.Api) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		var groupBase lib_model.GroupBase
+		if err := gc.ShouldBindJSON(&groupBase); err != nil {
+			_ = gc.Error(lib_model.NewInvalidInputError(err))
+			return
+		}
+		if err := a.UpdateGroup(gc.Request.Context(), gc.Param(grpIdParam), groupBase); err != nil {
+			_ = gc.Error(err)
+			return
+		}
+		gc.Status(http.StatusOK)
+	}
+}
+
+func deleteGroupH(a lib.Api) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		if err := a.DeleteGroup(gc.Request.Context(), gc.Param(grpIdParam)); err != nil {
+			_ = gc.Error(err)
+			return
+		}
+		gc.Status(http.StatusOK)
+	}
+}
+
+func getGroupDevicesH(a lib.Api) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		devices, err := a.GetGroupDevices(gc.Request.Context(), gc.Param(grpIdParam))
+		if err != nil {
+			_ = gc.Error(err)
+			return
+		}
+		gc.JSON(http.StatusOK, devices)
+	}
+}
+
+func putSetGroupDevicesH(a lib.Api) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		var deviceIDs []string
+		if err := gc.ShouldBindJSON(&deviceIDs); err != nil {
+			_ = gc.Error(lib_model.NewInvalidInputError(err))
+			return
+		}
+		if err := a.SetGroupDevices(gc.Request.Context(), gc.Param(grpIdParam), deviceIDs); err != nil {
+			_ = gc.Error(err)
+			return
+		}
+		gc.Status(http.StatusOK)
+	}
+}
+
+func putAddGroupDeviceH(a lib.Api) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		if err := a.AddGroupDevice(gc.Request.Context(), gc.Param(grpIdParam), gc.Param(devIdParam)); err != nil {
+			_ = gc.Error(err)
+			return
+		}
+		gc.Status(http.StatusOK)
+	}
+}
+
+func deleteRemoveGroupDeviceH(a lib.Api) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		if err := a.RemoveGroupDevice(gc.Request.Context(), gc.Param(grpIdParam), gc.Param(devIdParam)); err != nil {
+			_ = gc.Error(err)
+			return
+		}
+		gc.Status(http.StatusOK)
+	}
+}
@@ -25,7 +25,7 @@ import (
 
 func getServiceHealthH(a lib.Api) gin.HandlerFunc {
 	return func(gc *gin.Context) {
-		_, err := a.GetDevices(gc.Request.Context(), lib_model.DevicesFilter{})
+		_, _, err := a.GetDevices(gc.Request.Context(), lib_model.DevicesFilter{Limit: 1})
 		if err != nil {
 			_ = gc.Error(err)
 			return
@@ -0,0 +1,34 @@
+package http_hdl
+
+import (
+	"github.com/SENERGY-Platform/mgw-device-manager/util"
+	"github.com/gin-contrib/requestid"
+	"github.com/gin-gonic/gin"
+	"time"
+)
+
+// RequestInfoHandler adds the request ID and the caller identity read from callerHeader to the request context.
+// Must be used after the request ID middleware.
+func RequestInfoHandler(callerHeader string) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		gc.Request = gc.Request.WithContext(util.ContextWithRequestInfo(gc.Request.Context(), util.RequestInfo{
+			ID:     requestid.Get(gc),
+			Caller: gc.GetHeader(callerHeader),
+		}))
+		gc.Next()
+	}
+}
+
+// MetricsHandler passes method, route, status and duration of each request to observe.
+// Must be used before the error handler to observe the final status.
+func MetricsHandler(observe func(method, route string, status int, duration time.Duration)) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		start := time.Now()
+		gc.Next()
+		route := gc.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		observe(gc.Request.Method, route, gc.Writer.Status(), time.Since(start))
+	}
+}
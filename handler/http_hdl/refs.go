@@ -0,0 +1,31 @@
+package http_hdl
+
+import (
+	"github.com/SENERGY-Platform/mgw-device-manager/lib"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+const refParam = "r"
+
+func getRefsH(a lib.Api) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		refs, err := a.GetRefs(gc.Request.Context())
+		if err != nil {
+			_ = gc.Error(err)
+			return
+		}
+		gc.JSON(http.StatusOK, refs)
+	}
+}
+
+func deleteRefDevicesH(a lib.Api) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		err := a.DeleteRefDevices(gc.Request.Context(), gc.Param(refParam))
+		if err != nil {
+			_ = gc.Error(err)
+			return
+		}
+		gc.Status(http.StatusOK)
+	}
+}
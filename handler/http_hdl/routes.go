@@ -20,18 +20,43 @@ import (
 	"github.com/SENERGY-Platform/mgw-device-manager/lib"
 	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
 	"github.com/gin-gonic/gin"
+	"net/http"
 	"sort"
 )
 
 func SetRoutes(e *gin.Engine, a lib.Api) {
 	e.GET(lib_model.DevicesPath, getDevicesH(a))
+	e.GET(lib_model.DevicesPath+"/"+lib_model.EventsPath, getDeviceEventsH(a))
 	e.GET(lib_model.DevicesPath+"/:"+devIdParam, getDeviceH(a))
+	e.GET(lib_model.DevicesPath+"/:"+devIdParam+"/"+lib_model.StateHistoryPath, getDeviceStateHistoryH(a))
 	e.PATCH(lib_model.DevicesPath+"/:"+devIdParam, patchUpdateDeviceUserDataH(a))
 	e.DELETE(lib_model.DevicesPath+"/:"+devIdParam, deleteDeviceH(a))
+	e.GET(lib_model.RefsPath, getRefsH(a))
+	e.DELETE(lib_model.RefsPath+"/:"+refParam+"/"+lib_model.DevicesPath, deleteRefDevicesH(a))
+	e.GET(lib_model.TrashPath, getTrashH(a))
+	e.POST(lib_model.TrashPath+"/:"+devIdParam+"/"+lib_model.RestorePath, postRestoreDeviceH(a))
+	e.GET(lib_model.AuditPath, getAuditLogH(a))
+	e.GET(lib_model.ExportPath, getExportH(a))
+	e.POST(lib_model.ImportPath, postImportH(a))
+	e.GET(lib_model.BackupPath, getBackupH(a))
+	e.GET(lib_model.GroupsPath, getGroupsH(a))
+	e.POST(lib_model.GroupsPath, postCreateGroupH(a))
+	e.GET(lib_model.GroupsPath+"/:"+grpIdParam, getGroupH(a))
+	e.PUT(lib_model.GroupsPath+"/:"+grpIdParam, putUpdateGroupH(a))
+	e.DELETE(lib_model.GroupsPath+"/:"+grpIdParam, deleteGroupH(a))
+	e.GET(lib_model.GroupsPath+"/:"+grpIdParam+"/"+lib_model.DevicesPath, getGroupDevicesH(a))
+	e.PUT(lib_model.GroupsPath+"/:"+grpIdParam+"/"+lib_model.DevicesPath, putSetGroupDevicesH(a))
+	e.PUT(lib_model.GroupsPath+"/:"+grpIdParam+"/"+lib_model.DevicesPath+"/:"+devIdParam, putAddGroupDeviceH(a))
+	e.DELETE(lib_model.GroupsPath+"/:"+grpIdParam+"/"+lib_model.DevicesPath+"/:"+devIdParam, deleteRemoveGroupDeviceH(a))
 	e.GET(lib_model.SrvInfoPath, getSrvInfoH(a))
 	e.GET("health-check", getServiceHealthH(a))
 }
 
+// SetMetricsRoute serves the metrics provided by h.
+func SetMetricsRoute(e *gin.Engine, h http.Handler) {
+	e.GET("metrics", gin.WrapH(h))
+}
+
 func GetRoutes(e *gin.Engine) [][2]string {
 	routes := e.Routes()
 	sort.Slice(routes, func(i, j int) bool {
@@ -47,5 +72,6 @@ func GetRoutes(e *gin.Engine) [][2]string {
 func GetPathFilter() []string {
 	return []string{
 		"/health-check",
+		"/metrics",
 	}
 }
@@ -0,0 +1,28 @@
+package http_hdl
+
+import (
+	"github.com/SENERGY-Platform/mgw-device-manager/lib"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+func getTrashH(a lib.Api) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		devices, err := a.GetTrash(gc.Request.Context())
+		if err != nil {
+			_ = gc.Error(err)
+			return
+		}
+		gc.JSON(http.StatusOK, devices)
+	}
+}
+
+func postRestoreDeviceH(a lib.Api) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		if err := a.RestoreDevice(gc.Request.Context(), gc.Param(devIdParam)); err != nil {
+			_ = gc.Error(err)
+			return
+		}
+		gc.Status(http.StatusOK)
+	}
+}
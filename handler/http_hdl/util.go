@@ -16,7 +16,13 @@
 
 package http_hdl
 
-import "strings"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"strings"
+)
 
 func parseStringSlice(s, sep string) []string {
 	if s != "" {
@@ -24,3 +30,62 @@ func parseStringSlice(s, sep string) []string {
 	}
 	return nil
 }
+
+// parseAttributeFilters parses items formatted as 'key:value' or 'key' for existence checks.
+func parseAttributeFilters(sl []string) []lib_model.AttributeFilter {
+	var filters []lib_model.AttributeFilter
+	for _, s := range sl {
+		key, value, ok := strings.Cut(s, ":")
+		filter := lib_model.AttributeFilter{Key: key}
+		if ok {
+			filter.Value = &value
+		}
+		filters = append(filters, filter)
+	}
+	return filters
+}
+
+func parseSortOrder(s string) (bool, error) {
+	switch s {
+	case "", "asc":
+		return false, nil
+	case "desc":
+		return true, nil
+	default:
+		return false, errors.New("invalid sort order")
+	}
+}
+
+// parseUserDataPatch parses a user data patch as described by lib_model.DeviceUserDataPatch, null values remove members.
+func parseUserDataPatch(b []byte) (lib_model.DeviceUserDataPatch, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(b, &members); err != nil {
+		return lib_model.DeviceUserDataPatch{}, err
+	}
+	if members == nil {
+		return lib_model.DeviceUserDataPatch{}, errors.New("patch must be an object")
+	}
+	for key := range members {
+		if key != "name" && key != "attributes" {
+			return lib_model.DeviceUserDataPatch{}, fmt.Errorf("unknown member '%s'", key)
+		}
+	}
+	var patch lib_model.DeviceUserDataPatch
+	if raw, ok := members["name"]; ok {
+		var name *string
+		if err := json.Unmarshal(raw, &name); err != nil {
+			return lib_model.DeviceUserDataPatch{}, fmt.Errorf("name: %s", err)
+		}
+		if name == nil {
+			name = new(string)
+		}
+		patch.Name = name
+	}
+	if raw, ok := members["attributes"]; ok {
+		if err := json.Unmarshal(raw, &patch.Attributes); err != nil {
+			return lib_model.DeviceUserDataPatch{}, fmt.Errorf("attributes: %s", err)
+		}
+		patch.ClearAttributes = patch.Attributes == nil
+	}
+	return patch, nil
+}
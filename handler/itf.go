@@ -4,27 +4,100 @@ import (
 	"context"
 	"database/sql/driver"
 	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"io"
+	"time"
 )
 
 type DevicesHandler interface {
 	Put(ctx context.Context, deviceDataBase lib_model.DeviceDataBase, state lib_model.DeviceState) error
+	ApplyBatch(ctx context.Context, ref string, messages []lib_model.DeviceMessage) ([]error, error)
 	Get(ctx context.Context, id string) (lib_model.Device, error)
-	GetAll(ctx context.Context, filter lib_model.DevicesFilter) (map[string]lib_model.Device, error)
-	SetUserData(ctx context.Context, id string, userDataBase lib_model.DeviceUserDataBase) error
+	GetAll(ctx context.Context, filter lib_model.DevicesFilter) ([]lib_model.Device, int, error)
+	SetUserData(ctx context.Context, id, ifMatch string, userDataBase lib_model.DeviceUserDataBase) error
+	PatchUserData(ctx context.Context, id, ifMatch string, patch lib_model.DeviceUserDataPatch) error
 	SetStates(ctx context.Context, ref string, state lib_model.DeviceState) error
-	Delete(ctx context.Context, id string) error
+	BeginSync(ctx context.Context, ref string) error
+	EndSync(ctx context.Context, ref string) error
+	GetRefs(ctx context.Context) ([]lib_model.RefInfo, error)
+	DeleteRef(ctx context.Context, ref string) error
+	Delete(ctx context.Context, id, ifMatch string) error
+	GetStateHistory(ctx context.Context, id string, filter lib_model.DeviceStateHistoryFilter) ([]lib_model.DeviceStateTransition, error)
+	GetTrash(ctx context.Context) ([]lib_model.DeletedDevice, error)
+	Restore(ctx context.Context, id string) error
+	GetAuditLog(ctx context.Context, filter lib_model.AuditLogFilter) ([]lib_model.AuditEntry, error)
+	Export(ctx context.Context) (lib_model.Export, error)
+	Import(ctx context.Context, mode lib_model.ImportMode, export lib_model.Export) (lib_model.ImportResult, error)
+}
+
+// BatchResultPublisher publishes the result of a batch of device messages to the sender.
+type BatchResultPublisher interface {
+	PublishBatchResult(ref string, result lib_model.BatchResult)
 }
 
 type DevicesStorageHandler interface {
 	BeginTransaction(ctx context.Context) (driver.Tx, error)
 	Create(ctx context.Context, tx driver.Tx, device lib_model.DeviceData) error
 	Read(ctx context.Context, id string) (lib_model.DeviceBase, error)
-	ReadAll(ctx context.Context, filter lib_model.DevicesFilter) (map[string]lib_model.DeviceBase, error)
+	ReadAll(ctx context.Context, filter lib_model.DevicesFilter) ([]lib_model.DeviceBase, int, error)
 	Update(ctx context.Context, tx driver.Tx, deviceBase lib_model.DeviceData) error
 	UpdateUserData(ctx context.Context, tx driver.Tx, id string, userData lib_model.DeviceUserData) error
 	Delete(ctx context.Context, tx driver.Tx, id string) error
 }
 
+// DeviceStatesStorageHandler can optionally be implemented by a DevicesStorageHandler to persist device states and their history.
+type DeviceStatesStorageHandler interface {
+	ReadStates(ctx context.Context) (map[string]DeviceStateData, error)
+	UpdateState(ctx context.Context, tx driver.Tx, id string, transition lib_model.DeviceStateTransition) error
+	UpdateLastSeen(ctx context.Context, tx driver.Tx, id string, lastSeen time.Time) error
+	ReadStateHistory(ctx context.Context, id string, filter lib_model.DeviceStateHistoryFilter) ([]lib_model.DeviceStateTransition, error)
+	CountDevices(ctx context.Context) ([]DeviceCount, error)
+}
+
+// DeviceTrashStorageHandler can optionally be implemented by a DevicesStorageHandler to retain the user data of deleted devices.
+type DeviceTrashStorageHandler interface {
+	CreateTrashItem(ctx context.Context, tx driver.Tx, device lib_model.DeletedDevice) error
+	ReadTrashItem(ctx context.Context, id string) (lib_model.DeletedDevice, error)
+	ReadTrash(ctx context.Context) ([]lib_model.DeletedDevice, error)
+	DeleteTrashItem(ctx context.Context, tx driver.Tx, id string) error
+	PurgeTrash(ctx context.Context, before time.Time) (int, error)
+}
+
+// DeviceAuditStorageHandler can optionally be implemented by a DevicesStorageHandler to record user-initiated changes.
+type DeviceAuditStorageHandler interface {
+	CreateAuditEntry(ctx context.Context, tx driver.Tx, entry lib_model.AuditEntry) error
+	ReadAuditLog(ctx context.Context, filter lib_model.AuditLogFilter) ([]lib_model.AuditEntry, error)
+}
+
+type GroupsHandler interface {
+	Create(ctx context.Context, groupBase lib_model.GroupBase) (string, error)
+	Get(ctx context.Context, id string) (lib_model.Group, error)
+	GetAll(ctx context.Context) ([]lib_model.Group, error)
+	Update(ctx context.Context, id string, groupBase lib_model.GroupBase) error
+	Delete(ctx context.Context, id string) error
+	SetDevices(ctx context.Context, id string, deviceIDs []string) error
+	AddDevice(ctx context.Context, id, deviceID string) error
+	RemoveDevice(ctx context.Context, id, deviceID string) error
+}
+
+type GroupsStorageHandler interface {
+	CreateGroup(ctx context.Context, tx driver.Tx, group lib_model.Group) error
+	ReadGroup(ctx context.Context, id string) (lib_model.Group, error)
+	ReadGroups(ctx context.Context) ([]lib_model.Group, error)
+	UpdateGroup(ctx context.Context, tx driver.Tx, group lib_model.Group) error
+	UpdateGroupDevices(ctx context.Context, tx driver.Tx, id string, deviceIDs []string) error
+	DeleteGroup(ctx context.Context, tx driver.Tx, id string) error
+}
+
+type BackupHandler interface {
+	Backup(ctx context.Context) (io.ReadCloser, int64, error)
+}
+
+type DeviceEventsHandler interface {
+	Publish(event lib_model.DeviceEvent)
+	Subscribe(filter lib_model.DevicesFilter) (string, <-chan lib_model.DeviceEvent)
+	Unsubscribe(id string)
+}
+
 type MqttClient interface {
 	Subscribe(topic string, qos byte, messageHandler func(m Message)) error
 	Publish(topic string, qos byte, retained bool, payload any) error
@@ -40,3 +113,8 @@ type MessageRelayHandler interface {
 }
 
 type MessageHandler func(m Message)
+
+// MessageKeyFunc returns the key of a message, messages with the same key are handled in order.
+// An empty key requires all previous messages to be handled first. The returned message is handled instead of m and
+// can retain data already decoded to determine the key.
+type MessageKeyFunc func(m Message) (string, Message)
@@ -0,0 +1,56 @@
+package mem_storage_hdl
+
+import (
+	"context"
+	"database/sql/driver"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"slices"
+)
+
+func (h *Handler) CreateAuditEntry(ctx context.Context, txItf driver.Tx, entry lib_model.AuditEntry) error {
+	return h.write(ctx, txItf, func(d *data) error {
+		entry.Timestamp = entry.Timestamp.UTC()
+		entry.Before = copyUserData(entry.Before)
+		entry.After = copyUserData(entry.After)
+		d.audit = append(d.audit, entry)
+		return nil
+	})
+}
+
+func (h *Handler) ReadAuditLog(_ context.Context, filter lib_model.AuditLogFilter) ([]lib_model.AuditEntry, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var entries []lib_model.AuditEntry
+	for _, entry := range h.data.audit {
+		if filter.DeviceID != "" && entry.DeviceID != filter.DeviceID {
+			continue
+		}
+		if !filter.From.IsZero() && entry.Timestamp.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && entry.Timestamp.After(filter.To) {
+			continue
+		}
+		entry.Before = copyUserData(entry.Before)
+		entry.After = copyUserData(entry.After)
+		entries = append(entries, entry)
+	}
+	// reversing the insertion order before the stable sort orders entries with equal timestamps newest first
+	slices.Reverse(entries)
+	slices.SortStableFunc(entries, func(a, b lib_model.AuditEntry) int {
+		return b.Timestamp.Compare(a.Timestamp)
+	})
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
+
+func copyUserData(userData *lib_model.DeviceUserData) *lib_model.DeviceUserData {
+	if userData == nil {
+		return nil
+	}
+	c := *userData
+	c.Attributes = copyAttributes(c.Attributes)
+	return &c
+}
@@ -0,0 +1,109 @@
+package mem_storage_hdl
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"slices"
+	"strings"
+)
+
+func (h *Handler) CreateGroup(ctx context.Context, txItf driver.Tx, group lib_model.Group) error {
+	return h.write(ctx, txItf, func(d *data) error {
+		if _, ok := d.groups[group.ID]; ok {
+			return lib_model.NewInternalError(fmt.Errorf("group '%s' already exists", group.ID))
+		}
+		deviceIDs, err := d.groupMembers(group.DeviceIDs)
+		if err != nil {
+			return err
+		}
+		group.DeviceIDs = deviceIDs
+		d.groups[group.ID] = group
+		return nil
+	})
+}
+
+func (h *Handler) ReadGroup(_ context.Context, id string) (lib_model.Group, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	group, ok := h.data.groups[id]
+	if !ok {
+		return lib_model.Group{}, lib_model.NewNotFoundError(errors.New("not found"))
+	}
+	group.DeviceIDs = slices.Clone(group.DeviceIDs)
+	return group, nil
+}
+
+func (h *Handler) ReadGroups(_ context.Context) ([]lib_model.Group, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var groups []lib_model.Group
+	for _, group := range h.data.groups {
+		group.DeviceIDs = slices.Clone(group.DeviceIDs)
+		groups = append(groups, group)
+	}
+	slices.SortFunc(groups, func(a, b lib_model.Group) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return groups, nil
+}
+
+func (h *Handler) UpdateGroup(ctx context.Context, txItf driver.Tx, group lib_model.Group) error {
+	return h.write(ctx, txItf, func(d *data) error {
+		stored, ok := d.groups[group.ID]
+		if !ok {
+			return lib_model.NewNotFoundError(errors.New("not found"))
+		}
+		stored.GroupBase = group.GroupBase
+		stored.Updated = group.Updated
+		d.groups[group.ID] = stored
+		return nil
+	})
+}
+
+func (h *Handler) UpdateGroupDevices(ctx context.Context, txItf driver.Tx, id string, deviceIDs []string) error {
+	return h.write(ctx, txItf, func(d *data) error {
+		group, ok := d.groups[id]
+		if !ok {
+			return lib_model.NewInternalError(fmt.Errorf("group '%s' does not exist", id))
+		}
+		members, err := d.groupMembers(deviceIDs)
+		if err != nil {
+			return err
+		}
+		group.DeviceIDs = members
+		d.groups[id] = group
+		return nil
+	})
+}
+
+func (h *Handler) DeleteGroup(ctx context.Context, txItf driver.Tx, id string) error {
+	return h.write(ctx, txItf, func(d *data) error {
+		if _, ok := d.groups[id]; !ok {
+			return lib_model.NewNotFoundError(errors.New("not found"))
+		}
+		delete(d.groups, id)
+		return nil
+	})
+}
+
+// groupMembers returns the sorted and deduplicated device IDs, all devices must exist.
+func (d *data) groupMembers(deviceIDs []string) ([]string, error) {
+	if len(deviceIDs) == 0 {
+		return nil, nil
+	}
+	members := slices.Clone(deviceIDs)
+	slices.Sort(members)
+	members = slices.Compact(members)
+	for _, devID := range members {
+		if _, ok := d.devices[devID]; !ok {
+			return nil, lib_model.NewInternalError(fmt.Errorf("device '%s' does not exist", devID))
+		}
+	}
+	return members, nil
+}
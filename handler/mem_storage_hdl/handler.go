@@ -0,0 +1,503 @@
+package mem_storage_hdl
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+type stateData struct {
+	value    lib_model.DeviceState
+	updated  time.Time
+	lastSeen time.Time
+}
+
+// data holds the stored items, values are treated as immutable so a shallow copy of the maps is sufficient to
+// isolate a transaction from the committed data.
+type data struct {
+	devices map[string]lib_model.DeviceBase
+	states  map[string]stateData
+	history map[string][]lib_model.DeviceStateTransition
+	trash   map[string]lib_model.DeletedDevice
+	audit   []lib_model.AuditEntry
+	groups  map[string]lib_model.Group
+}
+
+// Handler keeps all data in memory. Writes are serialized, a transaction holds the write lock until it is committed
+// or rolled back and operates on a copy of the data that replaces the committed data on commit.
+type Handler struct {
+	mu    sync.RWMutex
+	wLock chan struct{}
+	data  *data
+}
+
+type tx struct {
+	hdl  *Handler
+	mu   sync.Mutex
+	data *data
+	done bool
+}
+
+func New() *Handler {
+	return &Handler{
+		wLock: make(chan struct{}, 1),
+		data: &data{
+			devices: make(map[string]lib_model.DeviceBase),
+			states:  make(map[string]stateData),
+			history: make(map[string][]lib_model.DeviceStateTransition),
+			trash:   make(map[string]lib_model.DeletedDevice),
+			groups:  make(map[string]lib_model.Group),
+		},
+	}
+}
+
+func (h *Handler) BeginTransaction(ctx context.Context) (driver.Tx, error) {
+	if err := h.lock(ctx); err != nil {
+		return nil, lib_model.NewInternalError(err)
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return &tx{hdl: h, data: h.data.clone()}, nil
+}
+
+func (h *Handler) Create(ctx context.Context, txItf driver.Tx, device lib_model.DeviceData) error {
+	return h.write(ctx, txItf, func(d *data) error {
+		if _, ok := d.devices[device.ID]; ok {
+			return lib_model.NewInternalError(fmt.Errorf("device '%s' already exists", device.ID))
+		}
+		d.devices[device.ID] = lib_model.DeviceBase{DeviceData: copyDeviceData(device)}
+		return nil
+	})
+}
+
+func (h *Handler) Read(_ context.Context, id string) (lib_model.DeviceBase, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	device, ok := h.data.devices[id]
+	if !ok {
+		return lib_model.DeviceBase{}, lib_model.NewNotFoundError(errors.New("not found"))
+	}
+	return h.data.deviceBase(device), nil
+}
+
+func (h *Handler) ReadAll(_ context.Context, filter lib_model.DevicesFilter) ([]lib_model.DeviceBase, int, error) {
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return nil, 0, lib_model.NewInvalidInputError(errors.New("limit and offset must not be negative"))
+	}
+	cmpFunc, err := genCmpFunc(filter)
+	if err != nil {
+		return nil, 0, lib_model.NewInvalidInputError(err)
+	}
+	terms := tokenize(filter.Search)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var items []item
+	for _, device := range h.data.devices {
+		if !h.data.match(device, filter) {
+			continue
+		}
+		itm := item{device: device, state: h.data.state(device.ID)}
+		if len(terms) > 0 {
+			if itm.rank = searchRank(device, terms); itm.rank == 0 {
+				continue
+			}
+		}
+		items = append(items, itm)
+	}
+	slices.SortFunc(items, cmpFunc)
+	total := len(items)
+	if filter.Offset > 0 {
+		items = items[min(filter.Offset, len(items)):]
+	}
+	if filter.Limit > 0 {
+		items = items[:min(filter.Limit, len(items))]
+	}
+	var devices []lib_model.DeviceBase
+	for _, itm := range items {
+		devices = append(devices, h.data.deviceBase(itm.device))
+	}
+	return devices, total, nil
+}
+
+func (h *Handler) Update(ctx context.Context, txItf driver.Tx, deviceBase lib_model.DeviceData) error {
+	return h.write(ctx, txItf, func(d *data) error {
+		device, ok := d.devices[deviceBase.ID]
+		if !ok {
+			return lib_model.NewNotFoundError(errors.New("not found"))
+		}
+		device.DeviceData = copyDeviceData(deviceBase)
+		d.devices[deviceBase.ID] = device
+		return nil
+	})
+}
+
+func (h *Handler) UpdateUserData(ctx context.Context, txItf driver.Tx, id string, userData lib_model.DeviceUserData) error {
+	return h.write(ctx, txItf, func(d *data) error {
+		device, ok := d.devices[id]
+		if !ok {
+			return lib_model.NewNotFoundError(errors.New("not found"))
+		}
+		userData.Attributes = copyAttributes(userData.Attributes)
+		device.UserData = userData
+		d.devices[id] = device
+		return nil
+	})
+}
+
+func (h *Handler) Delete(ctx context.Context, txItf driver.Tx, id string) error {
+	return h.write(ctx, txItf, func(d *data) error {
+		if _, ok := d.devices[id]; !ok {
+			return lib_model.NewNotFoundError(errors.New("not found"))
+		}
+		delete(d.devices, id)
+		delete(d.states, id)
+		delete(d.history, id)
+		for gID, group := range d.groups {
+			if i := slices.Index(group.DeviceIDs, id); i > -1 {
+				group.DeviceIDs = slices.Delete(slices.Clone(group.DeviceIDs), i, i+1)
+				d.groups[gID] = group
+			}
+		}
+		return nil
+	})
+}
+
+func (h *Handler) ReadStates(_ context.Context) (map[string]handler.DeviceStateData, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	states := make(map[string]handler.DeviceStateData)
+	for id, state := range h.data.states {
+		device := h.data.devices[id]
+		states[id] = handler.DeviceStateData{
+			Ref:      device.Ref,
+			Type:     device.Type,
+			Value:    state.value,
+			Updated:  state.updated,
+			LastSeen: state.lastSeen,
+		}
+	}
+	return states, nil
+}
+
+func (h *Handler) CountDevices(_ context.Context) ([]handler.DeviceCount, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	counts := make(map[handler.DeviceCount]int)
+	for id, device := range h.data.devices {
+		key := handler.DeviceCount{State: h.data.states[id].value, Type: device.Type, Ref: device.Ref}
+		if key.State == "" {
+			key.State = lib_model.NotAvailable
+		}
+		counts[key]++
+	}
+	deviceCounts := make([]handler.DeviceCount, 0, len(counts))
+	for key, n := range counts {
+		key.Count = n
+		deviceCounts = append(deviceCounts, key)
+	}
+	return deviceCounts, nil
+}
+
+func (h *Handler) UpdateState(ctx context.Context, txItf driver.Tx, id string, transition lib_model.DeviceStateTransition) error {
+	return h.write(ctx, txItf, func(d *data) error {
+		if _, ok := d.devices[id]; !ok {
+			return lib_model.NewInternalError(fmt.Errorf("device '%s' does not exist", id))
+		}
+		state := d.states[id]
+		state.value = transition.State
+		state.updated = transition.Timestamp
+		d.states[id] = state
+		transition.Timestamp = transition.Timestamp.UTC()
+		d.history[id] = append(d.history[id], transition)
+		return nil
+	})
+}
+
+func (h *Handler) UpdateLastSeen(ctx context.Context, txItf driver.Tx, id string, lastSeen time.Time) error {
+	return h.write(ctx, txItf, func(d *data) error {
+		if _, ok := d.devices[id]; !ok {
+			return lib_model.NewInternalError(fmt.Errorf("device '%s' does not exist", id))
+		}
+		state := d.states[id]
+		state.lastSeen = lastSeen
+		d.states[id] = state
+		return nil
+	})
+}
+
+func (h *Handler) ReadStateHistory(_ context.Context, id string, filter lib_model.DeviceStateHistoryFilter) ([]lib_model.DeviceStateTransition, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var transitions []lib_model.DeviceStateTransition
+	for _, transition := range h.data.history[id] {
+		if !filter.From.IsZero() && transition.Timestamp.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && transition.Timestamp.After(filter.To) {
+			continue
+		}
+		transitions = append(transitions, transition)
+	}
+	slices.SortStableFunc(transitions, func(a, b lib_model.DeviceStateTransition) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+	return transitions, nil
+}
+
+func (t *tx) Commit() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.hdl.mu.Lock()
+	t.hdl.data = t.data
+	t.hdl.mu.Unlock()
+	t.done = true
+	t.hdl.unlock()
+	return nil
+}
+
+func (t *tx) Rollback() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	t.hdl.unlock()
+	return nil
+}
+
+// write applies f to the data of the given transaction or, if no transaction is given, directly to the committed data.
+// Functions must validate their input before modifying the data, so a failed write leaves the data unchanged.
+func (h *Handler) write(ctx context.Context, txItf driver.Tx, f func(d *data) error) error {
+	if txItf != nil {
+		t, ok := txItf.(*tx)
+		if !ok || t.hdl != h {
+			return lib_model.NewInternalError(errors.New("invalid transaction"))
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.done {
+			return lib_model.NewInternalError(sql.ErrTxDone)
+		}
+		return f(t.data)
+	}
+	if err := h.lock(ctx); err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	defer h.unlock()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return f(h.data)
+}
+
+func (h *Handler) lock(ctx context.Context) error {
+	select {
+	case h.wLock <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h *Handler) unlock() {
+	<-h.wLock
+}
+
+func (d *data) clone() *data {
+	return &data{
+		devices: maps.Clone(d.devices),
+		states:  maps.Clone(d.states),
+		history: maps.Clone(d.history),
+		trash:   maps.Clone(d.trash),
+		audit:   slices.Clip(d.audit),
+		groups:  maps.Clone(d.groups),
+	}
+}
+
+func (d *data) deviceBase(device lib_model.DeviceBase) lib_model.DeviceBase {
+	device.Attributes = copyAttributes(device.Attributes)
+	device.UserData.Attributes = copyAttributes(device.UserData.Attributes)
+	device.Groups = d.deviceGroups(device.ID)
+	return device
+}
+
+func (d *data) deviceGroups(id string) []string {
+	var groupIDs []string
+	for gID, group := range d.groups {
+		if slices.Contains(group.DeviceIDs, id) {
+			groupIDs = append(groupIDs, gID)
+		}
+	}
+	slices.Sort(groupIDs)
+	return groupIDs
+}
+
+func (d *data) state(id string) lib_model.DeviceState {
+	if state := d.states[id]; state.value != "" {
+		return state.value
+	}
+	return lib_model.NotAvailable
+}
+
+func (d *data) match(device lib_model.DeviceBase, filter lib_model.DevicesFilter) bool {
+	if len(filter.IDs) > 0 && !slices.Contains(filter.IDs, device.ID) {
+		return false
+	}
+	if filter.State != "" && d.state(device.ID) != filter.State {
+		return false
+	}
+	if filter.Type != "" && device.Type != filter.Type {
+		return false
+	}
+	if filter.Ref != "" && device.Ref != filter.Ref {
+		return false
+	}
+	if filter.Group != "" && !slices.Contains(d.groups[filter.Group].DeviceIDs, device.ID) {
+		return false
+	}
+	for _, attrFilter := range filter.Attributes {
+		if !matchAttribute(device.Attributes, attrFilter) {
+			return false
+		}
+	}
+	for _, attrFilter := range filter.UserAttributes {
+		if !matchAttribute(device.UserData.Attributes, attrFilter) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAttribute(attributes []lib_model.DeviceAttribute, filter lib_model.AttributeFilter) bool {
+	for _, attr := range attributes {
+		if attr.Key == filter.Key && (filter.Value == nil || attr.Value == *filter.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+type item struct {
+	device lib_model.DeviceBase
+	state  lib_model.DeviceState
+	rank   int
+}
+
+var sortValues = map[lib_model.DevicesSortField]func(itm item) string{
+	lib_model.SortByID:       func(itm item) string { return itm.device.ID },
+	lib_model.SortByName:     func(itm item) string { return itm.device.Name },
+	lib_model.SortByUserName: func(itm item) string { return itm.device.UserData.Name },
+	lib_model.SortByType:     func(itm item) string { return itm.device.Type },
+	lib_model.SortByState:    func(itm item) string { return itm.state },
+}
+
+var sortTimes = map[lib_model.DevicesSortField]func(itm item) time.Time{
+	lib_model.SortByCreated: func(itm item) time.Time { return itm.device.Created },
+	lib_model.SortByUpdated: func(itm item) time.Time { return itm.device.Updated },
+}
+
+// genCmpFunc returns a function ordering items like the SQL storage handler: by the sort field with the device ID as
+// tiebreaker, by search rank if no sort field is set or by device ID.
+func genCmpFunc(filter lib_model.DevicesFilter) (func(a, b item) int, error) {
+	byID := func(a, b item) int {
+		return strings.Compare(a.device.ID, b.device.ID)
+	}
+	var cmpFunc func(a, b item) int
+	if filter.SortBy != "" {
+		if valFunc, ok := sortValues[filter.SortBy]; ok {
+			cmpFunc = func(a, b item) int {
+				return strings.Compare(valFunc(a), valFunc(b))
+			}
+		} else if timeFunc, ok := sortTimes[filter.SortBy]; ok {
+			cmpFunc = func(a, b item) int {
+				return timeFunc(a).Compare(timeFunc(b))
+			}
+		} else {
+			return nil, fmt.Errorf("invalid sort field '%s'", filter.SortBy)
+		}
+		if filter.SortDesc {
+			asc := cmpFunc
+			cmpFunc = func(a, b item) int {
+				return asc(b, a)
+			}
+		}
+	} else if len(tokenize(filter.Search)) > 0 {
+		cmpFunc = func(a, b item) int {
+			return b.rank - a.rank
+		}
+	} else {
+		return byID, nil
+	}
+	return func(a, b item) int {
+		if c := cmpFunc(a, b); c != 0 {
+			return c
+		}
+		return byID(a, b)
+	}, nil
+}
+
+// searchRank returns the number of tokens of the device matched by the search terms or zero if a term does not match.
+// Like the full-text search of the SQL storage handler all terms must match a token by prefix.
+func searchRank(device lib_model.DeviceBase, terms []string) int {
+	fields := []string{device.ID, device.Name, device.UserData.Name, device.Type}
+	for _, attr := range device.Attributes {
+		fields = append(fields, attr.Value)
+	}
+	for _, attr := range device.UserData.Attributes {
+		fields = append(fields, attr.Value)
+	}
+	var tokens []string
+	for _, field := range fields {
+		tokens = append(tokens, tokenize(field)...)
+	}
+	var rank int
+	for _, term := range terms {
+		n := matchTerm(tokens, term)
+		if n == 0 {
+			return 0
+		}
+		rank += n
+	}
+	return rank
+}
+
+func matchTerm(tokens []string, term string) int {
+	var n int
+	for _, token := range tokens {
+		if strings.HasPrefix(token, term) {
+			n++
+		}
+	}
+	return n
+}
+
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func copyDeviceData(deviceData lib_model.DeviceData) lib_model.DeviceData {
+	deviceData.Attributes = copyAttributes(deviceData.Attributes)
+	return deviceData
+}
+
+func copyAttributes(attributes []lib_model.DeviceAttribute) []lib_model.DeviceAttribute {
+	if len(attributes) == 0 {
+		return nil
+	}
+	return slices.Clone(attributes)
+}
@@ -0,0 +1,299 @@
+package mem_storage_hdl
+
+import (
+	"context"
+	"errors"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	_ handler.DevicesStorageHandler      = (*Handler)(nil)
+	_ handler.DeviceStatesStorageHandler = (*Handler)(nil)
+	_ handler.DeviceTrashStorageHandler  = (*Handler)(nil)
+	_ handler.DeviceAuditStorageHandler  = (*Handler)(nil)
+	_ handler.GroupsStorageHandler       = (*Handler)(nil)
+)
+
+func TestHandler(t *testing.T) {
+	h := New()
+	a := lib_model.DeviceData{
+		DeviceDataBase: lib_model.DeviceDataBase{
+			ID:         "1",
+			Ref:        "test",
+			Name:       "kitchen lamp",
+			Type:       "lamp",
+			Attributes: []lib_model.DeviceAttribute{{Key: "room", Value: "kitchen"}},
+		},
+		Created: time.Now(),
+	}
+	t.Run("create device", func(t *testing.T) {
+		if err := h.Create(context.Background(), nil, a); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.Create(context.Background(), nil, a); err == nil {
+			t.Error("expected error")
+		}
+		a.Attributes[0].Value = "changed"
+		device, err := h.Read(context.Background(), a.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if device.Attributes[0].Value != "kitchen" {
+			t.Error("stored attributes not copied")
+		}
+		a.Attributes[0].Value = "kitchen"
+		if !reflect.DeepEqual(a, device.DeviceData) {
+			t.Error("expected\n", a, "got\n", device.DeviceData)
+		}
+	})
+	t.Run("read device does not exist", func(t *testing.T) {
+		_, err := h.Read(context.Background(), "2")
+		var nfe *lib_model.NotFoundError
+		if !errors.As(err, &nfe) {
+			t.Error("expected not found error, got", err)
+		}
+	})
+	t.Run("update user data", func(t *testing.T) {
+		userData := lib_model.DeviceUserData{DeviceUserDataBase: lib_model.DeviceUserDataBase{Name: "lamp"}, Updated: time.Now()}
+		if err := h.UpdateUserData(context.Background(), nil, a.ID, userData); err != nil {
+			t.Fatal(err)
+		}
+		device, err := h.Read(context.Background(), a.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(userData, device.UserData) {
+			t.Error("expected\n", userData, "got\n", device.UserData)
+		}
+		if err = h.UpdateUserData(context.Background(), nil, "2", userData); err == nil {
+			t.Error("expected error")
+		}
+	})
+	t.Run("update state", func(t *testing.T) {
+		timestamp := time.Now()
+		if err := h.UpdateState(context.Background(), nil, a.ID, lib_model.DeviceStateTransition{State: lib_model.Online, Source: lib_model.StateSrcMessage, Timestamp: timestamp}); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.UpdateLastSeen(context.Background(), nil, a.ID, timestamp); err != nil {
+			t.Fatal(err)
+		}
+		states, err := h.ReadStates(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state := states[a.ID]; state.Value != lib_model.Online || state.Ref != a.Ref || !state.LastSeen.Equal(timestamp) {
+			t.Error("invalid state", state)
+		}
+		transitions, err := h.ReadStateHistory(context.Background(), a.ID, lib_model.DeviceStateHistoryFilter{From: timestamp})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(transitions) != 1 || transitions[0].State != lib_model.Online {
+			t.Error("invalid history", transitions)
+		}
+		if err = h.UpdateState(context.Background(), nil, "2", lib_model.DeviceStateTransition{State: lib_model.Online}); err == nil {
+			t.Error("expected error")
+		}
+	})
+	t.Run("count devices", func(t *testing.T) {
+		b := a
+		b.ID = "2"
+		if err := h.Create(context.Background(), nil, b); err != nil {
+			t.Fatal(err)
+		}
+		defer h.Delete(context.Background(), nil, b.ID)
+		counts, err := h.CountDevices(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.SortFunc(counts, func(a, b handler.DeviceCount) int {
+			return strings.Compare(a.State, b.State)
+		})
+		expected := []handler.DeviceCount{
+			{State: lib_model.NotAvailable, Type: a.Type, Ref: a.Ref, Count: 1},
+			{State: lib_model.Online, Type: a.Type, Ref: a.Ref, Count: 1},
+		}
+		if !reflect.DeepEqual(expected, counts) {
+			t.Error("expected\n", expected, "got\n", counts)
+		}
+	})
+	t.Run("delete device", func(t *testing.T) {
+		if err := h.Delete(context.Background(), nil, a.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := h.Read(context.Background(), a.ID); err == nil {
+			t.Error("expected error")
+		}
+		states, err := h.ReadStates(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(states) != 0 {
+			t.Error("states not deleted")
+		}
+		if err = h.Delete(context.Background(), nil, a.ID); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestHandler_BeginTransaction(t *testing.T) {
+	h := New()
+	a := lib_model.DeviceData{DeviceDataBase: lib_model.DeviceDataBase{ID: "1", Type: "test"}}
+	t.Run("rollback", func(t *testing.T) {
+		tx, err := h.BeginTransaction(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = h.Create(context.Background(), tx, a); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = h.Read(context.Background(), a.ID); err == nil {
+			t.Error("uncommitted device visible")
+		}
+		if err = tx.Rollback(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = h.Read(context.Background(), a.ID); err == nil {
+			t.Error("device not rolled back")
+		}
+		if err = h.Create(context.Background(), tx, a); err == nil {
+			t.Error("expected error")
+		}
+	})
+	t.Run("commit", func(t *testing.T) {
+		tx, err := h.BeginTransaction(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+		if err = h.Create(context.Background(), tx, a); err != nil {
+			t.Fatal(err)
+		}
+		if err = h.CreateAuditEntry(context.Background(), tx, lib_model.AuditEntry{Action: lib_model.AuditRestore, DeviceID: a.ID}); err != nil {
+			t.Fatal(err)
+		}
+		if err = tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = h.Read(context.Background(), a.ID); err != nil {
+			t.Error(err)
+		}
+		entries, err := h.ReadAuditLog(context.Background(), lib_model.AuditLogFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Error("expected 1 entry, got", len(entries))
+		}
+		if err = tx.Rollback(); err == nil {
+			t.Error("expected error")
+		}
+	})
+	t.Run("writes wait for transaction", func(t *testing.T) {
+		tx, err := h.BeginTransaction(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cf := context.WithTimeout(context.Background(), time.Millisecond*50)
+		defer cf()
+		if err = h.Update(ctx, nil, a); err == nil {
+			t.Error("expected error")
+		}
+		done := make(chan error)
+		go func() {
+			done <- h.Update(context.Background(), nil, a)
+		}()
+		if err = tx.Rollback(); err != nil {
+			t.Fatal(err)
+		}
+		if err = <-done; err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestHandler_ReadAll(t *testing.T) {
+	h := New()
+	kitchen := "kitchen"
+	devices := []lib_model.DeviceData{
+		{DeviceDataBase: lib_model.DeviceDataBase{ID: "a", Ref: "r1", Name: "desk lamp", Type: "lamp", Attributes: []lib_model.DeviceAttribute{{Key: "room", Value: kitchen}}}, Created: time.Now()},
+		{DeviceDataBase: lib_model.DeviceDataBase{ID: "b", Ref: "r1", Name: "hallway lamp", Type: "lamp"}, Created: time.Now().Add(-time.Hour)},
+		{DeviceDataBase: lib_model.DeviceDataBase{ID: "c", Ref: "r2", Name: "kitchen sensor kitchen", Type: "sensor"}, Created: time.Now().Add(time.Hour)},
+	}
+	for _, device := range devices {
+		if err := h.Create(context.Background(), nil, device); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.UpdateState(context.Background(), nil, "b", lib_model.DeviceStateTransition{State: lib_model.Online, Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.CreateGroup(context.Background(), nil, lib_model.Group{ID: "g", DeviceIDs: []string{"c", "a"}}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		filter lib_model.DevicesFilter
+		ids    []string
+		total  int
+	}{
+		{"all", lib_model.DevicesFilter{}, []string{"a", "b", "c"}, 3},
+		{"ids", lib_model.DevicesFilter{IDs: []string{"c", "a"}}, []string{"a", "c"}, 2},
+		{"type", lib_model.DevicesFilter{Type: "lamp"}, []string{"a", "b"}, 2},
+		{"ref", lib_model.DevicesFilter{Ref: "r2"}, []string{"c"}, 1},
+		{"state", lib_model.DevicesFilter{State: lib_model.Online}, []string{"b"}, 1},
+		{"state not available", lib_model.DevicesFilter{State: lib_model.NotAvailable}, []string{"a", "c"}, 2},
+		{"group", lib_model.DevicesFilter{Group: "g"}, []string{"a", "c"}, 2},
+		{"attribute", lib_model.DevicesFilter{Attributes: []lib_model.AttributeFilter{{Key: "room", Value: &kitchen}}}, []string{"a"}, 1},
+		{"sort created desc", lib_model.DevicesFilter{SortBy: lib_model.SortByCreated, SortDesc: true}, []string{"c", "a", "b"}, 3},
+		{"sort name desc", lib_model.DevicesFilter{SortBy: lib_model.SortByName, SortDesc: true}, []string{"c", "b", "a"}, 3},
+		{"limit and offset", lib_model.DevicesFilter{Limit: 1, Offset: 1}, []string{"b"}, 3},
+		{"offset exceeds total", lib_model.DevicesFilter{Offset: 5}, nil, 3},
+		{"search", lib_model.DevicesFilter{Search: "lam"}, []string{"a", "b"}, 2},
+		{"search ranked", lib_model.DevicesFilter{Search: "kitch"}, []string{"c", "a"}, 2},
+		{"search all terms", lib_model.DevicesFilter{Search: "kitchen lamp"}, []string{"a"}, 1},
+		{"search punctuation only", lib_model.DevicesFilter{Search: "---"}, []string{"a", "b", "c"}, 3},
+		{"search hyphenated", lib_model.DevicesFilter{Search: "desk-lam"}, []string{"a"}, 1},
+		{"search hyphenated no token prefix", lib_model.DevicesFilter{Search: "kit-chen"}, nil, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result, total, err := h.ReadAll(context.Background(), tc.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, device := range result {
+				ids = append(ids, device.ID)
+			}
+			if !reflect.DeepEqual(tc.ids, ids) || tc.total != total {
+				t.Error("expected", tc.ids, tc.total, "got", ids, total)
+			}
+		})
+	}
+	t.Run("groups", func(t *testing.T) {
+		device, err := h.Read(context.Background(), "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual([]string{"g"}, device.Groups) {
+			t.Error("invalid groups", device.Groups)
+		}
+	})
+	t.Run("invalid filter", func(t *testing.T) {
+		var iie *lib_model.InvalidInputError
+		if _, _, err := h.ReadAll(context.Background(), lib_model.DevicesFilter{SortBy: "test"}); !errors.As(err, &iie) {
+			t.Error("expected invalid input error, got", err)
+		}
+		if _, _, err := h.ReadAll(context.Background(), lib_model.DevicesFilter{Limit: -1}); !errors.As(err, &iie) {
+			t.Error("expected invalid input error, got", err)
+		}
+	})
+}
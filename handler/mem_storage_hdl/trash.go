@@ -0,0 +1,77 @@
+package mem_storage_hdl
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"slices"
+	"strings"
+	"time"
+)
+
+func (h *Handler) CreateTrashItem(ctx context.Context, txItf driver.Tx, device lib_model.DeletedDevice) error {
+	return h.write(ctx, txItf, func(d *data) error {
+		device.DeviceData = copyDeviceData(device.DeviceData)
+		device.UserData.Attributes = copyAttributes(device.UserData.Attributes)
+		device.Deleted = device.Deleted.UTC()
+		d.trash[device.ID] = device
+		return nil
+	})
+}
+
+func (h *Handler) ReadTrashItem(_ context.Context, id string) (lib_model.DeletedDevice, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	device, ok := h.data.trash[id]
+	if !ok {
+		return lib_model.DeletedDevice{}, lib_model.NewNotFoundError(errors.New("not found"))
+	}
+	return copyDeletedDevice(device), nil
+}
+
+func (h *Handler) ReadTrash(_ context.Context) ([]lib_model.DeletedDevice, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var devices []lib_model.DeletedDevice
+	for _, device := range h.data.trash {
+		devices = append(devices, copyDeletedDevice(device))
+	}
+	slices.SortFunc(devices, func(a, b lib_model.DeletedDevice) int {
+		if c := b.Deleted.Compare(a.Deleted); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return devices, nil
+}
+
+func (h *Handler) DeleteTrashItem(ctx context.Context, txItf driver.Tx, id string) error {
+	return h.write(ctx, txItf, func(d *data) error {
+		if _, ok := d.trash[id]; !ok {
+			return lib_model.NewNotFoundError(errors.New("not found"))
+		}
+		delete(d.trash, id)
+		return nil
+	})
+}
+
+func (h *Handler) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	var n int
+	err := h.write(ctx, nil, func(d *data) error {
+		for id, device := range d.trash {
+			if device.Deleted.Before(before) {
+				delete(d.trash, id)
+				n++
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+func copyDeletedDevice(device lib_model.DeletedDevice) lib_model.DeletedDevice {
+	device.DeviceData = copyDeviceData(device.DeviceData)
+	device.UserData.Attributes = copyAttributes(device.UserData.Attributes)
+	return device
+}
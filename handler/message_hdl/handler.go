@@ -1,6 +1,7 @@
 package message_hdl
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"github.com/SENERGY-Platform/mgw-device-manager/handler"
@@ -11,21 +12,33 @@ import (
 
 const logPrefix = "[message-hdl]"
 
+const topicUnknown = "unknown"
+
 type Handler struct {
 	devicesHdl handler.DevicesHandler
+	resultPub  handler.BatchResultPublisher
 }
 
 func New(devicesHdl handler.DevicesHandler) *Handler {
 	return &Handler{devicesHdl: devicesHdl}
 }
 
+// SetBatchResultPublisher sets the publisher used to report the results of batch messages to the sender.
+func (h *Handler) SetBatchResultPublisher(p handler.BatchResultPublisher) {
+	h.resultPub = p
+}
+
 func (h *Handler) HandleMessage(m handler.Message) {
 	util.Logger.Debugf("%s handle message (topic=%s payload=%s)", logPrefix, m.Topic(), m.Payload())
 	var ref string
 	switch {
 	case parseTopic(topic.DevicesSub, m.Topic(), &ref):
-		var dm lib_model.DeviceMessage
-		if err := json.Unmarshal(m.Payload(), &dm); err != nil {
+		if isBatch(m.Payload()) {
+			h.handleBatch(ref, m.Payload())
+			return
+		}
+		dm, err := decodeDeviceMessage(m)
+		if err != nil {
 			util.Logger.Errorf("%s unmarshal message: %s", logPrefix, err)
 			return
 		}
@@ -48,11 +61,23 @@ func (h *Handler) HandleMessage(m handler.Message) {
 			}
 			util.Logger.Infof("%s set device (%s)", logPrefix, dm.DeviceID)
 		case lib_model.Delete:
-			if err := h.devicesHdl.Delete(context.Background(), dm.DeviceID); err != nil {
+			if err := h.devicesHdl.Delete(context.Background(), dm.DeviceID, ""); err != nil {
 				util.Logger.Errorf("%s delete device (%s): %s", logPrefix, dm.DeviceID, err)
 				return
 			}
 			util.Logger.Infof("%s delete device (%s)", logPrefix, dm.DeviceID)
+		case lib_model.SyncBegin:
+			if err := h.devicesHdl.BeginSync(context.Background(), ref); err != nil {
+				util.Logger.Errorf("%s begin sync (%s): %s", logPrefix, ref, err)
+				return
+			}
+			util.Logger.Infof("%s begin sync (%s)", logPrefix, ref)
+		case lib_model.SyncEnd:
+			if err := h.devicesHdl.EndSync(context.Background(), ref); err != nil {
+				util.Logger.Errorf("%s end sync (%s): %s", logPrefix, ref, err)
+				return
+			}
+			util.Logger.Infof("%s end sync (%s)", logPrefix, ref)
 		default:
 			util.Logger.Errorf("%s unknown method '%s'", logPrefix, dm.Method)
 		}
@@ -67,3 +92,89 @@ func (h *Handler) HandleMessage(m handler.Message) {
 	}
 	return
 }
+
+// MessageKey returns the device id of set and delete messages. Batch, sync, last will and invalid messages yield an
+// empty key as they must be handled in order with all other messages. The returned message retains the decoded
+// payload so HandleMessage does not decode it again.
+func (h *Handler) MessageKey(m handler.Message) (string, handler.Message) {
+	var ref string
+	if !parseTopic(topic.DevicesSub, m.Topic(), &ref) || isBatch(m.Payload()) {
+		return "", m
+	}
+	dm := &decodedMessage{Message: m}
+	dm.deviceMessage, dm.err = decodeDeviceMessage(m)
+	if dm.err == nil {
+		switch dm.deviceMessage.Method {
+		case lib_model.Set, lib_model.Delete:
+			return dm.deviceMessage.DeviceID, dm
+		}
+	}
+	return "", dm
+}
+
+// MessageTopic returns the subscription topic a message was received on or topicUnknown for unknown topics.
+func (h *Handler) MessageTopic(m handler.Message) string {
+	var ref string
+	switch {
+	case parseTopic(topic.DevicesSub, m.Topic(), &ref):
+		return topic.DevicesSub
+	case parseTopic(topic.LastWillSub, m.Topic(), &ref):
+		return topic.LastWillSub
+	}
+	return topicUnknown
+}
+
+func (h *Handler) handleBatch(ref string, payload []byte) {
+	var messages []lib_model.DeviceMessage
+	if err := json.Unmarshal(payload, &messages); err != nil {
+		util.Logger.Errorf("%s unmarshal batch message: %s", logPrefix, err)
+		h.publishBatchResult(ref, lib_model.BatchResult{Error: err.Error()})
+		return
+	}
+	errs, err := h.devicesHdl.ApplyBatch(context.Background(), ref, messages)
+	if err != nil {
+		util.Logger.Errorf("%s apply batch (%s): %s", logPrefix, ref, err)
+		h.publishBatchResult(ref, lib_model.BatchResult{Failed: len(messages), Error: err.Error()})
+		return
+	}
+	var result lib_model.BatchResult
+	for i, err := range errs {
+		if err != nil {
+			util.Logger.Errorf("%s apply batch (%s): item %d (%s): %s", logPrefix, ref, i, messages[i].DeviceID, err)
+			result.Errors = append(result.Errors, lib_model.BatchItemError{Index: i, DeviceID: messages[i].DeviceID, Error: err.Error()})
+		}
+	}
+	result.Failed = len(result.Errors)
+	result.Applied = len(messages) - result.Failed
+	util.Logger.Infof("%s apply batch (%s): applied=%d failed=%d", logPrefix, ref, result.Applied, result.Failed)
+	h.publishBatchResult(ref, result)
+}
+
+func (h *Handler) publishBatchResult(ref string, result lib_model.BatchResult) {
+	if h.resultPub != nil {
+		h.resultPub.PublishBatchResult(ref, result)
+	}
+}
+
+// decodedMessage is a message received on the devices topic with its decoded payload.
+type decodedMessage struct {
+	handler.Message
+	deviceMessage lib_model.DeviceMessage
+	err           error
+}
+
+// decodeDeviceMessage returns the device message of m, the payload is only decoded if m is not a decodedMessage.
+func decodeDeviceMessage(m handler.Message) (lib_model.DeviceMessage, error) {
+	if dm, ok := m.(*decodedMessage); ok {
+		return dm.deviceMessage, dm.err
+	}
+	var dm lib_model.DeviceMessage
+	err := json.Unmarshal(m.Payload(), &dm)
+	return dm, err
+}
+
+// isBatch reports whether the payload is a JSON array of device messages.
+func isBatch(payload []byte) bool {
+	p := bytes.TrimLeft(payload, " \t\r\n")
+	return len(p) > 0 && p[0] == '['
+}
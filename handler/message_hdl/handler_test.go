@@ -1,13 +1,19 @@
 package message_hdl
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler/devices_hdl"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler/mem_storage_hdl"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler/msg_relay_hdl"
 	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
 	"github.com/SENERGY-Platform/mgw-device-manager/util"
+	"github.com/SENERGY-Platform/mgw-device-manager/util/topic"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestHandler_HandleMessage(t *testing.T) {
@@ -65,6 +71,21 @@ func TestHandler_HandleMessage(t *testing.T) {
 		if mockDHdl.PutC != 1 {
 			t.Error("missing call")
 		}
+		t.Run("decoded by message key", func(t *testing.T) {
+			m := &mockMessage{
+				topic:   "device-manager/device/test",
+				payload: p,
+			}
+			key, km := h.MessageKey(m)
+			if key != "123" {
+				t.Error("expected key '123', got", key)
+			}
+			m.payload = []byte("test")
+			h.HandleMessage(km)
+			if mockDHdl.PutC != 2 {
+				t.Error("payload decoded again")
+			}
+		})
 		t.Run("no device data", func(t *testing.T) {
 			mockDHdl := &mockDeviceHdl{}
 			h := Handler{devicesHdl: mockDHdl}
@@ -138,6 +159,88 @@ func TestHandler_HandleMessage(t *testing.T) {
 			})
 		})
 	})
+	t.Run("batch", func(t *testing.T) {
+		mockDHdl := &mockDeviceHdl{Batches: make(map[string][]lib_model.DeviceMessage), BatchErrs: []error{nil, lib_model.NewNotFoundError(errors.New("not found"))}}
+		mockPub := &mockResultPublisher{}
+		h := Handler{devicesHdl: mockDHdl, resultPub: mockPub}
+		a := []lib_model.DeviceMessage{
+			{
+				Method:   lib_model.Set,
+				DeviceID: "123",
+				Data:     &lib_model.DeviceMessageData{Name: "test", State: lib_model.Online, Type: "test2"},
+			},
+			{
+				Method:   lib_model.Delete,
+				DeviceID: "456",
+			},
+		}
+		p, err := json.Marshal(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.HandleMessage(&mockMessage{
+			topic:   "device-manager/device/test",
+			payload: append([]byte(" \n"), p...),
+		})
+		if mockDHdl.BatchC != 1 || mockDHdl.PutC != 0 || mockDHdl.DeleteC != 0 {
+			t.Error("expected one batch call")
+		}
+		if b := mockDHdl.Batches["test"]; !reflect.DeepEqual(a, b) {
+			t.Error("got", b, "expected", a)
+		}
+		expected := lib_model.BatchResult{Applied: 1, Failed: 1, Errors: []lib_model.BatchItemError{{Index: 1, DeviceID: "456", Error: "not found"}}}
+		if r := mockPub.results["test"]; len(r) != 1 || !reflect.DeepEqual(r[0], expected) {
+			t.Error("got", r, "expected", expected)
+		}
+		t.Run("error", func(t *testing.T) {
+			mockDHdl := &mockDeviceHdl{BatchErr: errors.New("test")}
+			mockPub := &mockResultPublisher{}
+			h := Handler{devicesHdl: mockDHdl, resultPub: mockPub}
+			h.HandleMessage(&mockMessage{
+				topic:   "device-manager/device/test",
+				payload: p,
+			})
+			if mockDHdl.BatchC != 1 {
+				t.Error("missing call")
+			}
+			if r := mockPub.results["test"]; len(r) != 1 || r[0].Error == "" || r[0].Failed != 2 {
+				t.Error("invalid result", r)
+			}
+		})
+		t.Run("invalid payload", func(t *testing.T) {
+			mockDHdl := &mockDeviceHdl{}
+			mockPub := &mockResultPublisher{}
+			h := Handler{devicesHdl: mockDHdl, resultPub: mockPub}
+			h.HandleMessage(&mockMessage{
+				topic:   "device-manager/device/test",
+				payload: []byte("[test"),
+			})
+			if mockDHdl.BatchC != 0 {
+				t.Error("unexpected call")
+			}
+			if r := mockPub.results["test"]; len(r) != 1 || r[0].Error == "" {
+				t.Error("invalid result", r)
+			}
+		})
+	})
+	t.Run("sync", func(t *testing.T) {
+		mockDHdl := &mockDeviceHdl{Syncs: make(map[string]bool)}
+		h := Handler{devicesHdl: mockDHdl}
+		h.HandleMessage(&mockMessage{
+			topic:   "device-manager/device/test",
+			payload: []byte(`{"method":"sync_begin"}`),
+		})
+		if running, ok := mockDHdl.Syncs["test"]; !ok || !running {
+			t.Error("sync not started")
+		}
+		h.HandleMessage(&mockMessage{
+			topic:   "device-manager/device/test",
+			payload: []byte(`{"method":"sync_end"}`),
+		})
+		if mockDHdl.Syncs["test"] {
+			t.Error("sync not ended")
+		}
+	})
 	t.Run("unknown method", func(t *testing.T) {
 		mockDHdl := &mockDeviceHdl{}
 		h := Handler{devicesHdl: mockDHdl}
@@ -161,6 +264,99 @@ func TestHandler_HandleMessage(t *testing.T) {
 	})
 }
 
+func TestHandler_MessageKey(t *testing.T) {
+	h := Handler{}
+	tests := []struct {
+		name    string
+		topic   string
+		payload string
+		key     string
+	}{
+		{"set", "device-manager/device/test", `{"method":"set","device_id":"123","data":{}}`, "123"},
+		{"delete", "device-manager/device/test", `{"method":"delete","device_id":"123"}`, "123"},
+		{"last will", "device-manager/device/test/lw", "", ""},
+		{"sync", "device-manager/device/test", `{"method":"sync_begin"}`, ""},
+		{"batch", "device-manager/device/test", `[{"method":"set","device_id":"123"}]`, ""},
+		{"invalid payload", "device-manager/device/test", "test", ""},
+		{"unknown topic", "test", "", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &mockMessage{topic: tc.topic, payload: []byte(tc.payload)}
+			key, km := h.MessageKey(m)
+			if key != tc.key {
+				t.Errorf("expected '%s', got '%s'", tc.key, key)
+			}
+			if km.Topic() != m.Topic() || !bytes.Equal(km.Payload(), m.Payload()) {
+				t.Error("message changed")
+			}
+		})
+	}
+}
+
+func TestHandler_MessageTopic(t *testing.T) {
+	h := Handler{}
+	tests := []struct {
+		name    string
+		topic   string
+		payload string
+		sub     string
+	}{
+		{"device", "device-manager/device/test", `{"method":"set","device_id":"123","data":{}}`, topic.DevicesSub},
+		{"batch", "device-manager/device/test", `[{"method":"set","device_id":"123"}]`, topic.DevicesSub},
+		{"invalid payload", "device-manager/device/test", "test", topic.DevicesSub},
+		{"last will", "device-manager/device/test/lw", "", topic.LastWillSub},
+		{"unknown topic", "test", "", topicUnknown},
+		{"unknown sub topic", "device-manager/device/test/test", "", topicUnknown},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if sub := h.MessageTopic(&mockMessage{topic: tc.topic, payload: []byte(tc.payload)}); sub != tc.sub {
+				t.Errorf("expected '%s', got '%s'", tc.sub, sub)
+			}
+		})
+	}
+}
+
+func TestHandler_MessageKey_lastWillOrder(t *testing.T) {
+	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
+	devicesHdl := devices_hdl.New(mem_storage_hdl.New(), time.Second)
+	h := New(devicesHdl)
+	relayHdl := msg_relay_hdl.New(10000, 4, h.HandleMessage, h.MessageKey)
+	for i := 0; i < 200; i++ {
+		for _, id := range []string{"1", "2", "3", "4", "5", "6"} {
+			p, err := json.Marshal(lib_model.DeviceMessage{
+				Method:   lib_model.Set,
+				DeviceID: id,
+				Data:     &lib_model.DeviceMessageData{Name: "test", State: lib_model.Online, Type: "test"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = relayHdl.Put(&mockMessage{topic: "device-manager/device/test", payload: p}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := relayHdl.Put(&mockMessage{topic: "device-manager/device/test/lw"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	relayHdl.Start()
+	relayHdl.Stop()
+	devices, _, err := devicesHdl.GetAll(context.Background(), lib_model.DevicesFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 6 {
+		t.Fatal("expected 6 devices, got", len(devices))
+	}
+	for _, device := range devices {
+		if device.State != lib_model.Offline {
+			t.Error("expected device", device.ID, "to be", lib_model.Offline, "got", device.State)
+		}
+	}
+}
+
 type mockDeviceHdl struct {
 	Devices      map[string]lib_model.DeviceDataBase
 	StatesRef    map[string]lib_model.DeviceState
@@ -168,9 +364,14 @@ type mockDeviceHdl struct {
 	PutErr       error
 	SetStatesErr error
 	DeleteErr    error
+	Batches      map[string][]lib_model.DeviceMessage
+	BatchErr     error
+	BatchErrs    []error
 	PutC         int
 	SetStatesC   int
 	DeleteC      int
+	BatchC       int
+	Syncs        map[string]bool
 }
 
 func (m *mockDeviceHdl) Put(ctx context.Context, deviceData lib_model.DeviceDataBase, state lib_model.DeviceState) error {
@@ -183,15 +384,41 @@ func (m *mockDeviceHdl) Put(ctx context.Context, deviceData lib_model.DeviceData
 	return nil
 }
 
+func (m *mockDeviceHdl) ApplyBatch(ctx context.Context, ref string, messages []lib_model.DeviceMessage) ([]error, error) {
+	m.BatchC++
+	if m.BatchErr != nil {
+		return nil, m.BatchErr
+	}
+	m.Batches[ref] = messages
+	errs := make([]error, len(messages))
+	copy(errs, m.BatchErrs)
+	return errs, nil
+}
+
+type mockResultPublisher struct {
+	results map[string][]lib_model.BatchResult
+}
+
+func (m *mockResultPublisher) PublishBatchResult(ref string, result lib_model.BatchResult) {
+	if m.results == nil {
+		m.results = make(map[string][]lib_model.BatchResult)
+	}
+	m.results[ref] = append(m.results[ref], result)
+}
+
 func (m *mockDeviceHdl) Get(ctx context.Context, id string) (lib_model.Device, error) {
 	panic("not implemented")
 }
 
-func (m *mockDeviceHdl) GetAll(ctx context.Context, filter lib_model.DevicesFilter) (map[string]lib_model.Device, error) {
+func (m *mockDeviceHdl) GetAll(ctx context.Context, filter lib_model.DevicesFilter) ([]lib_model.Device, int, error) {
 	panic("not implemented")
 }
 
-func (m *mockDeviceHdl) SetUserData(ctx context.Context, id string, userDataBase lib_model.DeviceUserDataBase) error {
+func (m *mockDeviceHdl) PatchUserData(ctx context.Context, id, ifMatch string, patch lib_model.DeviceUserDataPatch) error {
+	panic("not implemented")
+}
+
+func (m *mockDeviceHdl) SetUserData(ctx context.Context, id, ifMatch string, userDataBase lib_model.DeviceUserDataBase) error {
 	panic("not implemented")
 }
 
@@ -204,7 +431,45 @@ func (m *mockDeviceHdl) SetStates(ctx context.Context, ref string, state lib_mod
 	return nil
 }
 
-func (m *mockDeviceHdl) Delete(ctx context.Context, id string) error {
+func (m *mockDeviceHdl) BeginSync(ctx context.Context, ref string) error {
+	m.Syncs[ref] = true
+	return nil
+}
+
+func (m *mockDeviceHdl) EndSync(ctx context.Context, ref string) error {
+	m.Syncs[ref] = false
+	return nil
+}
+
+func (m *mockDeviceHdl) GetTrash(ctx context.Context) ([]lib_model.DeletedDevice, error) {
+	panic("not implemented")
+}
+
+func (m *mockDeviceHdl) Restore(ctx context.Context, id string) error {
+	panic("not implemented")
+}
+
+func (m *mockDeviceHdl) Export(ctx context.Context) (lib_model.Export, error) {
+	panic("not implemented")
+}
+
+func (m *mockDeviceHdl) Import(ctx context.Context, mode lib_model.ImportMode, export lib_model.Export) (lib_model.ImportResult, error) {
+	panic("not implemented")
+}
+
+func (m *mockDeviceHdl) GetAuditLog(ctx context.Context, filter lib_model.AuditLogFilter) ([]lib_model.AuditEntry, error) {
+	panic("not implemented")
+}
+
+func (m *mockDeviceHdl) GetRefs(ctx context.Context) ([]lib_model.RefInfo, error) {
+	panic("not implemented")
+}
+
+func (m *mockDeviceHdl) DeleteRef(ctx context.Context, ref string) error {
+	panic("not implemented")
+}
+
+func (m *mockDeviceHdl) Delete(ctx context.Context, id, ifMatch string) error {
 	m.DeleteC++
 	if m.DeleteErr != nil {
 		return m.DeleteErr
@@ -212,6 +477,10 @@ func (m *mockDeviceHdl) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (m *mockDeviceHdl) GetStateHistory(ctx context.Context, id string, filter lib_model.DeviceStateHistoryFilter) ([]lib_model.DeviceStateTransition, error) {
+	panic("not implemented")
+}
+
 type mockMessage struct {
 	topic   string
 	payload []byte
@@ -0,0 +1,214 @@
+package metrics_hdl
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "device_manager"
+
+type Handler struct {
+	registry     *prometheus.Registry
+	mqttMessages *prometheus.CounterVec
+	relayDropped prometheus.Counter
+	msgDuration  prometheus.Histogram
+	stgDuration  *prometheus.HistogramVec
+	stgErrors    *prometheus.CounterVec
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+}
+
+func New() *Handler {
+	h := &Handler{
+		registry: prometheus.NewRegistry(),
+		mqttMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "mqtt",
+			Name:      "messages_received_total",
+			Help:      "Number of MQTT messages received per subscription topic.",
+		}, []string{"topic"}),
+		relayDropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "message_relay",
+			Name:      "messages_dropped_total",
+			Help:      "Number of messages dropped by the message relay.",
+		}),
+		msgDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "message_relay",
+			Name:      "message_handling_duration_seconds",
+			Help:      "Duration of handling a relayed message.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+		}),
+		stgDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "storage",
+			Name:      "operation_duration_seconds",
+			Help:      "Duration of storage operations.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 16),
+		}, []string{"operation"}),
+		stgErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "storage",
+			Name:      "operation_errors_total",
+			Help:      "Number of failed storage operations.",
+		}, []string{"operation"}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of handled HTTP requests.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of handling HTTP requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+	}
+	h.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		h.mqttMessages,
+		h.relayDropped,
+		h.msgDuration,
+		h.stgDuration,
+		h.stgErrors,
+		h.httpRequests,
+		h.httpDuration,
+	)
+	return h
+}
+
+// HTTPHandler returns a handler serving the metrics in the Prometheus exposition format.
+func (h *Handler) HTTPHandler() http.Handler {
+	return promhttp.HandlerFor(h.registry, promhttp.HandlerOpts{Registry: h.registry})
+}
+
+// RegisterDevices adds the number of devices per state, type and reference provided by countFunc on each scrape.
+func (h *Handler) RegisterDevices(countFunc func(ctx context.Context) ([]handler.DeviceCount, error)) {
+	h.registry.MustRegister(&devicesCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "devices"),
+			"Number of devices per state, type and reference.",
+			[]string{"state", "type", "ref"},
+			nil,
+		),
+		countFunc: countFunc,
+	})
+}
+
+// RegisterMessageBuffer adds the fill level and size of the message relay buffer.
+func (h *Handler) RegisterMessageBuffer(lenFunc, capFunc func() int) {
+	h.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "message_relay",
+			Name:      "buffer_messages",
+			Help:      "Number of messages waiting in the message relay buffer.",
+		}, func() float64 {
+			return float64(lenFunc())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "message_relay",
+			Name:      "buffer_size",
+			Help:      "Size of the message relay buffer.",
+		}, func() float64 {
+			return float64(capFunc())
+		}),
+	)
+}
+
+// RegisterMessageQueue adds the number of messages waiting in the persistent message relay queue.
+func (h *Handler) RegisterMessageQueue(lenFunc func() int) {
+	h.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "message_relay",
+		Name:      "queue_messages",
+		Help:      "Number of messages waiting in the persistent message relay queue.",
+	}, func() float64 {
+		return float64(lenFunc())
+	}))
+}
+
+// RegisterMqttConnection adds the MQTT connection state, 1 if connected.
+func (h *Handler) RegisterMqttConnection(isConnected func() bool) {
+	h.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "mqtt",
+		Name:      "connected",
+		Help:      "MQTT connection state, 1 if connected.",
+	}, func() float64 {
+		if isConnected() {
+			return 1
+		}
+		return 0
+	}))
+}
+
+func (h *Handler) ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	h.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	h.httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// MessageHandler wraps handleFunc to observe the duration of handling a message.
+func (h *Handler) MessageHandler(handleFunc handler.MessageHandler) handler.MessageHandler {
+	return func(m handler.Message) {
+		start := time.Now()
+		handleFunc(m)
+		h.msgDuration.Observe(time.Since(start).Seconds())
+	}
+}
+
+// MessageRelayHandler wraps messageRelayHdl to count received messages per topic provided by topicFunc and dropped
+// messages.
+func (h *Handler) MessageRelayHandler(messageRelayHdl handler.MessageRelayHandler, topicFunc func(m handler.Message) string) handler.MessageRelayHandler {
+	return &messageRelay{
+		messageRelayHdl: messageRelayHdl,
+		topicFunc:       topicFunc,
+		metricsHdl:      h,
+	}
+}
+
+type messageRelay struct {
+	messageRelayHdl handler.MessageRelayHandler
+	topicFunc       func(m handler.Message) string
+	metricsHdl      *Handler
+}
+
+func (r *messageRelay) Put(m handler.Message) error {
+	r.metricsHdl.mqttMessages.WithLabelValues(r.topicFunc(m)).Inc()
+	err := r.messageRelayHdl.Put(m)
+	if err != nil {
+		r.metricsHdl.relayDropped.Inc()
+	}
+	return err
+}
+
+type devicesCollector struct {
+	desc      *prometheus.Desc
+	countFunc func(ctx context.Context) ([]handler.DeviceCount, error)
+}
+
+func (c *devicesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *devicesCollector) Collect(ch chan<- prometheus.Metric) {
+	counts, err := c.countFunc(context.Background())
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	for _, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count.Count), count.State, count.Type, count.Ref)
+	}
+}
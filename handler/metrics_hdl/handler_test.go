@@ -0,0 +1,129 @@
+package metrics_hdl
+
+import (
+	"context"
+	"errors"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler/mem_storage_hdl"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	h := New()
+	t.Run("devices", func(t *testing.T) {
+		h.RegisterDevices(func(_ context.Context) ([]handler.DeviceCount, error) {
+			return []handler.DeviceCount{
+				{State: lib_model.Online, Type: "t", Ref: "r", Count: 2},
+				{State: lib_model.NotAvailable, Type: "t", Ref: "r", Count: 1},
+			}, nil
+		})
+		expected := `
+# HELP device_manager_devices Number of devices per state, type and reference.
+# TYPE device_manager_devices gauge
+device_manager_devices{ref="r",state="n/a",type="t"} 1
+device_manager_devices{ref="r",state="online",type="t"} 2
+`
+		if err := testutil.GatherAndCompare(h.registry, strings.NewReader(expected), "device_manager_devices"); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("message relay", func(t *testing.T) {
+		relay := h.MessageRelayHandler(&relayMock{}, func(m handler.Message) string {
+			return "test"
+		})
+		_ = relay.Put(&messageMock{topic: "a"})
+		_ = relay.Put(&messageMock{topic: "b"})
+		_ = relay.Put(&messageMock{topic: "full"})
+		if n := testutil.ToFloat64(h.mqttMessages.WithLabelValues("test")); n != 3 {
+			t.Error("expected 3 messages, got", n)
+		}
+		if n := testutil.ToFloat64(h.relayDropped); n != 1 {
+			t.Error("expected 1 dropped message, got", n)
+		}
+	})
+	t.Run("storage", func(t *testing.T) {
+		stgHdl := h.StorageHandler(mem_storage_hdl.New())
+		if _, err := stgHdl.Read(context.Background(), "test"); err == nil {
+			t.Error("expected error")
+		}
+		if _, _, err := stgHdl.ReadAll(context.Background(), lib_model.DevicesFilter{}); err != nil {
+			t.Error(err)
+		}
+		if n := testutil.ToFloat64(h.stgErrors.WithLabelValues("read")); n != 1 {
+			t.Error("expected 1 error, got", n)
+		}
+		if n := testutil.CollectAndCount(h.stgDuration); n != 2 {
+			t.Error("expected 2 operations, got", n)
+		}
+		if _, ok := stgHdl.(handler.DeviceStatesStorageHandler); !ok {
+			t.Error("expected states storage handler")
+		}
+		if _, ok := stgHdl.(handler.DeviceTrashStorageHandler); !ok {
+			t.Error("expected trash storage handler")
+		}
+		if _, ok := stgHdl.(handler.DeviceAuditStorageHandler); !ok {
+			t.Error("expected audit storage handler")
+		}
+		t.Run("optional interfaces", func(t *testing.T) {
+			stgHdl := h.StorageHandler(&devicesStgMock{DevicesStorageHandler: mem_storage_hdl.New()})
+			if _, ok := stgHdl.(handler.DeviceStatesStorageHandler); ok {
+				t.Error("unexpected states storage handler")
+			}
+			if _, ok := stgHdl.(handler.DeviceTrashStorageHandler); ok {
+				t.Error("unexpected trash storage handler")
+			}
+			if _, ok := stgHdl.(handler.DeviceAuditStorageHandler); ok {
+				t.Error("unexpected audit storage handler")
+			}
+			stgHdl = h.StorageHandler(&trashStgMock{devicesStgMock: devicesStgMock{DevicesStorageHandler: mem_storage_hdl.New()}, DeviceTrashStorageHandler: mem_storage_hdl.New()})
+			if _, ok := stgHdl.(handler.DeviceStatesStorageHandler); ok {
+				t.Error("unexpected states storage handler")
+			}
+			if _, ok := stgHdl.(handler.DeviceTrashStorageHandler); !ok {
+				t.Error("expected trash storage handler")
+			}
+		})
+	})
+	t.Run("groups storage", func(t *testing.T) {
+		stgHdl := h.GroupsStorageHandler(mem_storage_hdl.New())
+		if _, err := stgHdl.ReadGroup(context.Background(), "test"); err == nil {
+			t.Error("expected error")
+		}
+		if n := testutil.ToFloat64(h.stgErrors.WithLabelValues("read_group")); n != 1 {
+			t.Error("expected 1 error, got", n)
+		}
+	})
+}
+
+type devicesStgMock struct {
+	handler.DevicesStorageHandler
+}
+
+type trashStgMock struct {
+	devicesStgMock
+	handler.DeviceTrashStorageHandler
+}
+
+type relayMock struct{}
+
+func (m *relayMock) Put(msg handler.Message) error {
+	if msg.Topic() == "full" {
+		return errors.New("buffer full")
+	}
+	return nil
+}
+
+type messageMock struct {
+	topic string
+}
+
+func (m *messageMock) Topic() string {
+	return m.topic
+}
+
+func (m *messageMock) Payload() []byte {
+	return nil
+}
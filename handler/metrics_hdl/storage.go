@@ -0,0 +1,288 @@
+package metrics_hdl
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"time"
+)
+
+// StorageHandler wraps stgHdl to observe the duration and errors of storage operations. The returned handler only
+// implements the optional storage interfaces implemented by stgHdl.
+func (h *Handler) StorageHandler(stgHdl handler.DevicesStorageHandler) handler.DevicesStorageHandler {
+	s := &storage{
+		stgHdl:     stgHdl,
+		metricsHdl: h,
+	}
+	var st *statesStorage
+	if stgHdl, ok := stgHdl.(handler.DeviceStatesStorageHandler); ok {
+		st = &statesStorage{stgHdl: stgHdl, metricsHdl: h}
+	}
+	var tr *trashStorage
+	if stgHdl, ok := stgHdl.(handler.DeviceTrashStorageHandler); ok {
+		tr = &trashStorage{stgHdl: stgHdl, metricsHdl: h}
+	}
+	var au *auditStorage
+	if stgHdl, ok := stgHdl.(handler.DeviceAuditStorageHandler); ok {
+		au = &auditStorage{stgHdl: stgHdl, metricsHdl: h}
+	}
+	switch {
+	case st != nil && tr != nil && au != nil:
+		return &struct {
+			*storage
+			*statesStorage
+			*trashStorage
+			*auditStorage
+		}{s, st, tr, au}
+	case st != nil && tr != nil:
+		return &struct {
+			*storage
+			*statesStorage
+			*trashStorage
+		}{s, st, tr}
+	case st != nil && au != nil:
+		return &struct {
+			*storage
+			*statesStorage
+			*auditStorage
+		}{s, st, au}
+	case tr != nil && au != nil:
+		return &struct {
+			*storage
+			*trashStorage
+			*auditStorage
+		}{s, tr, au}
+	case st != nil:
+		return &struct {
+			*storage
+			*statesStorage
+		}{s, st}
+	case tr != nil:
+		return &struct {
+			*storage
+			*trashStorage
+		}{s, tr}
+	case au != nil:
+		return &struct {
+			*storage
+			*auditStorage
+		}{s, au}
+	}
+	return s
+}
+
+// GroupsStorageHandler wraps stgHdl to observe the duration and errors of storage operations.
+func (h *Handler) GroupsStorageHandler(stgHdl handler.GroupsStorageHandler) handler.GroupsStorageHandler {
+	return &groupsStorage{
+		stgHdl:     stgHdl,
+		metricsHdl: h,
+	}
+}
+
+type storage struct {
+	stgHdl     handler.DevicesStorageHandler
+	metricsHdl *Handler
+}
+
+type statesStorage struct {
+	stgHdl     handler.DeviceStatesStorageHandler
+	metricsHdl *Handler
+}
+
+type trashStorage struct {
+	stgHdl     handler.DeviceTrashStorageHandler
+	metricsHdl *Handler
+}
+
+type auditStorage struct {
+	stgHdl     handler.DeviceAuditStorageHandler
+	metricsHdl *Handler
+}
+
+type groupsStorage struct {
+	stgHdl     handler.GroupsStorageHandler
+	metricsHdl *Handler
+}
+
+func (h *Handler) observeStorage(operation string, start time.Time, err error) {
+	h.stgDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		h.stgErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+func (s *storage) BeginTransaction(ctx context.Context) (driver.Tx, error) {
+	start := time.Now()
+	tx, err := s.stgHdl.BeginTransaction(ctx)
+	s.metricsHdl.observeStorage("begin_transaction", start, err)
+	return tx, err
+}
+
+func (s *storage) Create(ctx context.Context, tx driver.Tx, device lib_model.DeviceData) error {
+	start := time.Now()
+	err := s.stgHdl.Create(ctx, tx, device)
+	s.metricsHdl.observeStorage("create", start, err)
+	return err
+}
+
+func (s *storage) Read(ctx context.Context, id string) (lib_model.DeviceBase, error) {
+	start := time.Now()
+	device, err := s.stgHdl.Read(ctx, id)
+	s.metricsHdl.observeStorage("read", start, err)
+	return device, err
+}
+
+func (s *storage) ReadAll(ctx context.Context, filter lib_model.DevicesFilter) ([]lib_model.DeviceBase, int, error) {
+	start := time.Now()
+	devices, total, err := s.stgHdl.ReadAll(ctx, filter)
+	s.metricsHdl.observeStorage("read_all", start, err)
+	return devices, total, err
+}
+
+func (s *storage) Update(ctx context.Context, tx driver.Tx, deviceBase lib_model.DeviceData) error {
+	start := time.Now()
+	err := s.stgHdl.Update(ctx, tx, deviceBase)
+	s.metricsHdl.observeStorage("update", start, err)
+	return err
+}
+
+func (s *storage) UpdateUserData(ctx context.Context, tx driver.Tx, id string, userData lib_model.DeviceUserData) error {
+	start := time.Now()
+	err := s.stgHdl.UpdateUserData(ctx, tx, id, userData)
+	s.metricsHdl.observeStorage("update_user_data", start, err)
+	return err
+}
+
+func (s *storage) Delete(ctx context.Context, tx driver.Tx, id string) error {
+	start := time.Now()
+	err := s.stgHdl.Delete(ctx, tx, id)
+	s.metricsHdl.observeStorage("delete", start, err)
+	return err
+}
+
+func (s *statesStorage) ReadStates(ctx context.Context) (map[string]handler.DeviceStateData, error) {
+	start := time.Now()
+	states, err := s.stgHdl.ReadStates(ctx)
+	s.metricsHdl.observeStorage("read_states", start, err)
+	return states, err
+}
+
+func (s *statesStorage) UpdateState(ctx context.Context, tx driver.Tx, id string, transition lib_model.DeviceStateTransition) error {
+	start := time.Now()
+	err := s.stgHdl.UpdateState(ctx, tx, id, transition)
+	s.metricsHdl.observeStorage("update_state", start, err)
+	return err
+}
+
+func (s *statesStorage) UpdateLastSeen(ctx context.Context, tx driver.Tx, id string, lastSeen time.Time) error {
+	start := time.Now()
+	err := s.stgHdl.UpdateLastSeen(ctx, tx, id, lastSeen)
+	s.metricsHdl.observeStorage("update_last_seen", start, err)
+	return err
+}
+
+func (s *statesStorage) ReadStateHistory(ctx context.Context, id string, filter lib_model.DeviceStateHistoryFilter) ([]lib_model.DeviceStateTransition, error) {
+	start := time.Now()
+	transitions, err := s.stgHdl.ReadStateHistory(ctx, id, filter)
+	s.metricsHdl.observeStorage("read_state_history", start, err)
+	return transitions, err
+}
+
+func (s *statesStorage) CountDevices(ctx context.Context) ([]handler.DeviceCount, error) {
+	start := time.Now()
+	counts, err := s.stgHdl.CountDevices(ctx)
+	s.metricsHdl.observeStorage("count_devices", start, err)
+	return counts, err
+}
+
+func (s *trashStorage) CreateTrashItem(ctx context.Context, tx driver.Tx, device lib_model.DeletedDevice) error {
+	start := time.Now()
+	err := s.stgHdl.CreateTrashItem(ctx, tx, device)
+	s.metricsHdl.observeStorage("create_trash_item", start, err)
+	return err
+}
+
+func (s *trashStorage) ReadTrashItem(ctx context.Context, id string) (lib_model.DeletedDevice, error) {
+	start := time.Now()
+	device, err := s.stgHdl.ReadTrashItem(ctx, id)
+	s.metricsHdl.observeStorage("read_trash_item", start, err)
+	return device, err
+}
+
+func (s *trashStorage) ReadTrash(ctx context.Context) ([]lib_model.DeletedDevice, error) {
+	start := time.Now()
+	devices, err := s.stgHdl.ReadTrash(ctx)
+	s.metricsHdl.observeStorage("read_trash", start, err)
+	return devices, err
+}
+
+func (s *trashStorage) DeleteTrashItem(ctx context.Context, tx driver.Tx, id string) error {
+	start := time.Now()
+	err := s.stgHdl.DeleteTrashItem(ctx, tx, id)
+	s.metricsHdl.observeStorage("delete_trash_item", start, err)
+	return err
+}
+
+func (s *trashStorage) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	start := time.Now()
+	n, err := s.stgHdl.PurgeTrash(ctx, before)
+	s.metricsHdl.observeStorage("purge_trash", start, err)
+	return n, err
+}
+
+func (s *auditStorage) CreateAuditEntry(ctx context.Context, tx driver.Tx, entry lib_model.AuditEntry) error {
+	start := time.Now()
+	err := s.stgHdl.CreateAuditEntry(ctx, tx, entry)
+	s.metricsHdl.observeStorage("create_audit_entry", start, err)
+	return err
+}
+
+func (s *auditStorage) ReadAuditLog(ctx context.Context, filter lib_model.AuditLogFilter) ([]lib_model.AuditEntry, error) {
+	start := time.Now()
+	entries, err := s.stgHdl.ReadAuditLog(ctx, filter)
+	s.metricsHdl.observeStorage("read_audit_log", start, err)
+	return entries, err
+}
+
+func (s *groupsStorage) CreateGroup(ctx context.Context, tx driver.Tx, group lib_model.Group) error {
+	start := time.Now()
+	err := s.stgHdl.CreateGroup(ctx, tx, group)
+	s.metricsHdl.observeStorage("create_group", start, err)
+	return err
+}
+
+func (s *groupsStorage) ReadGroup(ctx context.Context, id string) (lib_model.Group, error) {
+	start := time.Now()
+	group, err := s.stgHdl.ReadGroup(ctx, id)
+	s.metricsHdl.observeStorage("read_group", start, err)
+	return group, err
+}
+
+func (s *groupsStorage) ReadGroups(ctx context.Context) ([]lib_model.Group, error) {
+	start := time.Now()
+	groups, err := s.stgHdl.ReadGroups(ctx)
+	s.metricsHdl.observeStorage("read_groups", start, err)
+	return groups, err
+}
+
+func (s *groupsStorage) UpdateGroup(ctx context.Context, tx driver.Tx, group lib_model.Group) error {
+	start := time.Now()
+	err := s.stgHdl.UpdateGroup(ctx, tx, group)
+	s.metricsHdl.observeStorage("update_group", start, err)
+	return err
+}
+
+func (s *groupsStorage) UpdateGroupDevices(ctx context.Context, tx driver.Tx, id string, deviceIDs []string) error {
+	start := time.Now()
+	err := s.stgHdl.UpdateGroupDevices(ctx, tx, id, deviceIDs)
+	s.metricsHdl.observeStorage("update_group_devices", start, err)
+	return err
+}
+
+func (s *groupsStorage) DeleteGroup(ctx context.Context, tx driver.Tx, id string) error {
+	start := time.Now()
+	err := s.stgHdl.DeleteGroup(ctx, tx, id)
+	s.metricsHdl.observeStorage("delete_group", start, err)
+	return err
+}
@@ -0,0 +1,22 @@
+package handler
+
+import (
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"time"
+)
+
+type DeviceStateData struct {
+	Ref      string
+	Type     string
+	Value    lib_model.DeviceState
+	Updated  time.Time
+	LastSeen time.Time
+}
+
+// DeviceCount is the number of devices with the same state, type and reference.
+type DeviceCount struct {
+	State lib_model.DeviceState
+	Type  string
+	Ref   string
+	Count int
+}
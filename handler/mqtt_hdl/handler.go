@@ -1,7 +1,10 @@
 package mqtt_hdl
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/SENERGY-Platform/mgw-device-manager/handler"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
 	"github.com/SENERGY-Platform/mgw-device-manager/util"
 	"github.com/SENERGY-Platform/mgw-device-manager/util/topic"
 )
@@ -13,6 +16,7 @@ const (
 	SubscribeString    = "%s subscribe topic (%s)"
 	SubscribedString   = "%s subscribed topic (%s)"
 	SubscribeErrString = "%s subscribe topic (%s): %s"
+	PublishErrString   = "%s publish message (%s): %s"
 )
 
 type Handler struct {
@@ -69,3 +73,32 @@ func (h *Handler) publishRefreshSignal() {
 		util.Logger.Errorf("publish refresh signal: %s", err)
 	}
 }
+
+// PublishBatchResult publishes the result of a batch of device messages to the reference specific result topic.
+func (h *Handler) PublishBatchResult(ref string, result lib_model.BatchResult) {
+	t := fmt.Sprintf(topic.BatchResPub, ref)
+	b, err := json.Marshal(result)
+	if err != nil {
+		util.Logger.Errorf(PublishErrString, LogPrefix, t, err)
+		return
+	}
+	if err = h.client.Publish(t, h.qos, false, b); err != nil {
+		util.Logger.Errorf(PublishErrString, LogPrefix, t, err)
+	}
+}
+
+// PublishEvents publishes device events to device specific topics until the events channel is closed, device IDs are
+// escaped via topic.EscapeLevel.
+func (h *Handler) PublishEvents(events <-chan lib_model.DeviceEvent, qos byte, retain bool) {
+	for event := range events {
+		t := fmt.Sprintf(topic.EventsPub, topic.EscapeLevel(event.DeviceID))
+		b, err := json.Marshal(event)
+		if err != nil {
+			util.Logger.Errorf(PublishErrString, LogPrefix, t, err)
+			continue
+		}
+		if err = h.client.Publish(t, qos, retain, b); err != nil {
+			util.Logger.Errorf(PublishErrString, LogPrefix, t, err)
+		}
+	}
+}
@@ -3,43 +3,239 @@ package msg_relay_hdl
 import (
 	"errors"
 	"github.com/SENERGY-Platform/mgw-device-manager/handler"
+	"github.com/SENERGY-Platform/mgw-device-manager/util"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+const logPrefix = "[msg-relay-hdl]"
+
+const (
+	workerBuffer   = 100
+	replayInterval = time.Millisecond * 100
 )
 
 type Handler struct {
 	messages   chan handler.Message
 	handleFunc handler.MessageHandler
+	keyFunc    handler.MessageKeyFunc
+	workers    []chan handler.Message
+	pending    sync.WaitGroup
+	wg         sync.WaitGroup
+	queue      *Queue
+	queued     chan struct{}
+	stopped    bool
+	mu         sync.Mutex
+	sChan      chan struct{}
+	rChan      chan struct{}
 	dChan      chan struct{}
 }
 
-func New(buffer int, handleFunc handler.MessageHandler) *Handler {
-	return &Handler{
+// New creates a relay handling buffered messages with the given number of workers. Messages are assigned to workers
+// by the key provided by keyFunc, messages with an empty key or without keyFunc are handled after all previous
+// messages and before any following message.
+func New(buffer, workers int, handleFunc handler.MessageHandler, keyFunc handler.MessageKeyFunc) *Handler {
+	if workers < 1 {
+		workers = 1
+	}
+	h := &Handler{
 		messages:   make(chan handler.Message, buffer),
 		handleFunc: handleFunc,
+		keyFunc:    keyFunc,
+		queued:     make(chan struct{}, 1),
+		sChan:      make(chan struct{}),
+		rChan:      make(chan struct{}),
 		dChan:      make(chan struct{}),
 	}
+	if workers > 1 {
+		h.workers = make([]chan handler.Message, workers)
+		for i := range h.workers {
+			h.workers[i] = make(chan handler.Message, workerBuffer)
+		}
+	}
+	return h
+}
+
+// SetQueue enables the queue for messages exceeding the buffer and messages pending on stop. Queued messages are
+// relayed in order as soon as buffer space is available. Must be called before Start.
+func (h *Handler) SetQueue(queue *Queue) {
+	h.queue = queue
 }
 
 func (h *Handler) Put(m handler.Message) error {
+	if h.queue == nil {
+		select {
+		case h.messages <- m:
+		default:
+			return errors.New("buffer full")
+		}
+		return nil
+	}
+	h.mu.Lock()
+	if !h.stopped && h.queue.Len() == 0 {
+		select {
+		case h.messages <- m:
+			h.mu.Unlock()
+			return nil
+		default:
+		}
+	}
+	seq, err := h.queue.write(m)
+	h.mu.Unlock()
+	if err != nil {
+		return err
+	}
 	select {
-	case h.messages <- m:
+	case h.queued <- struct{}{}:
 	default:
-		return errors.New("buffer full")
 	}
-	return nil
+	return h.queue.sync(seq)
+}
+
+// Len returns the number of buffered messages.
+func (h *Handler) Len() int {
+	n := len(h.messages)
+	for _, ch := range h.workers {
+		n += len(ch)
+	}
+	return n
+}
+
+// Cap returns the buffer size.
+func (h *Handler) Cap() int {
+	return cap(h.messages)
 }
 
 func (h *Handler) Start() {
+	for _, ch := range h.workers {
+		h.wg.Add(1)
+		go h.work(ch)
+	}
 	go h.run()
+	if h.queue != nil {
+		go h.replay()
+	} else {
+		close(h.rChan)
+	}
 }
 
+// Stop waits for all relayed messages to be handled. If a queue is set, messages still in the buffer are moved to
+// the queue instead.
 func (h *Handler) Stop() {
+	h.mu.Lock()
+	h.stopped = true
+	close(h.sChan)
 	close(h.messages)
+	h.mu.Unlock()
+	<-h.rChan
 	<-h.dChan
 }
 
 func (h *Handler) run() {
+	var pending []handler.Message
 	for message := range h.messages {
-		h.handleFunc(message)
+		if h.queue != nil && h.isStopped() {
+			pending = append(pending, message)
+			continue
+		}
+		h.dispatch(message)
+	}
+	for _, ch := range h.workers {
+		close(ch)
+	}
+	h.wg.Wait()
+	if len(pending) > 0 {
+		if err := h.queue.Prepend(pending); err != nil {
+			util.Logger.Errorf("%s queue %d pending messages: %s", logPrefix, len(pending), err)
+		} else {
+			util.Logger.Infof("%s queued %d pending messages", logPrefix, len(pending))
+		}
 	}
 	h.dChan <- struct{}{}
 }
+
+// replay moves queued messages to the buffer until the relay is stopped.
+func (h *Handler) replay() {
+	defer close(h.rChan)
+	for !h.isStopped() {
+		full, err := h.replayNext()
+		if err != nil {
+			util.Logger.Errorf("%s replay queued message: %s", logPrefix, err)
+		}
+		if full {
+			select {
+			case <-h.sChan:
+				return
+			case <-time.After(replayInterval):
+			}
+		}
+		if h.queue.Len() == 0 {
+			select {
+			case <-h.sChan:
+				return
+			case <-h.queued:
+			}
+		}
+	}
+}
+
+// replayNext moves the first queued message to the buffer and reports whether the buffer is full. Messages that
+// can not be read are removed.
+func (h *Handler) replayNext() (bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stopped {
+		return false, nil
+	}
+	m, err := h.queue.Front()
+	if err != nil {
+		return false, errors.Join(err, h.queue.Remove())
+	}
+	if m == nil {
+		return false, nil
+	}
+	select {
+	case h.messages <- m:
+	default:
+		return true, nil
+	}
+	return false, h.queue.Remove()
+}
+
+func (h *Handler) isStopped() bool {
+	select {
+	case <-h.sChan:
+		return true
+	default:
+		return false
+	}
+}
+
+func (h *Handler) dispatch(m handler.Message) {
+	if len(h.workers) == 0 {
+		h.handleFunc(m)
+		return
+	}
+	var key string
+	if h.keyFunc != nil {
+		key, m = h.keyFunc(m)
+	}
+	if key == "" {
+		h.pending.Wait()
+		h.handleFunc(m)
+		return
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	h.pending.Add(1)
+	h.workers[hash.Sum32()%uint32(len(h.workers))] <- m
+}
+
+func (h *Handler) work(ch chan handler.Message) {
+	defer h.wg.Done()
+	for message := range ch {
+		h.handleFunc(message)
+		h.pending.Done()
+	}
+}
@@ -2,7 +2,11 @@ package msg_relay_hdl
 
 import (
 	"github.com/SENERGY-Platform/mgw-device-manager/handler"
+	"github.com/SENERGY-Platform/mgw-device-manager/util"
+	"path"
 	"reflect"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 )
@@ -32,7 +36,7 @@ func TestHandler(t *testing.T) {
 			t.Error("expected", msg, "got", m)
 		}
 	}
-	h := New(1, testMsgHdl)
+	h := New(1, 1, testMsgHdl, nil)
 	err := h.Put(msg)
 	if err != nil {
 		t.Error(err)
@@ -48,7 +52,7 @@ func TestHandler(t *testing.T) {
 	h.Stop()
 	t.Run("buffer full", func(t *testing.T) {
 		testMsgHdl = func(m handler.Message) {}
-		h = New(1, testMsgHdl)
+		h = New(1, 1, testMsgHdl, nil)
 		err = h.Put(msg)
 		if err != nil {
 			t.Error(err)
@@ -68,3 +72,136 @@ func TestHandler(t *testing.T) {
 		h.Stop()
 	})
 }
+
+func TestHandler_Workers(t *testing.T) {
+	var mu sync.Mutex
+	var running, maxRunning int
+	handled := make(map[string][]string)
+	var barrier []int
+	testMsgHdl := func(m handler.Message) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		if m.Topic() == "barrier" {
+			barrier = append(barrier, running)
+		}
+		handled[m.Topic()] = append(handled[m.Topic()], string(m.Payload()))
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+	}
+	keyFunc := func(m handler.Message) (string, handler.Message) {
+		if m.Topic() == "barrier" {
+			return "", m
+		}
+		return m.Topic(), m
+	}
+	h := New(1000, 4, testMsgHdl, keyFunc)
+	for i := 0; i < 50; i++ {
+		for _, tpc := range []string{"a", "b", "c", "d", "e", "f"} {
+			if err := h.Put(&mockMessage{topic: tpc, payload: []byte(strconv.Itoa(i))}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if i%10 == 0 {
+			if err := h.Put(&mockMessage{topic: "barrier", payload: []byte(strconv.Itoa(i))}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	h.Start()
+	h.Stop()
+	if h.Len() > 0 {
+		t.Error("messages not consumed")
+	}
+	for tpc, payloads := range handled {
+		if tpc == "barrier" {
+			continue
+		}
+		if len(payloads) != 50 {
+			t.Error("expected 50 messages for", tpc, "got", len(payloads))
+		}
+		for i, p := range payloads {
+			if p != strconv.Itoa(i) {
+				t.Error("invalid order for", tpc, payloads)
+				break
+			}
+		}
+	}
+	if maxRunning < 2 {
+		t.Error("messages not handled concurrently")
+	}
+	for _, n := range barrier {
+		if n != 1 {
+			t.Error("barrier message handled concurrently")
+		}
+	}
+	if len(barrier) != 5 {
+		t.Error("expected 5 barrier messages, got", len(barrier))
+	}
+}
+
+func TestHandler_Queue(t *testing.T) {
+	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
+	p := path.Join(t.TempDir(), "queue")
+	q, err := NewQueue(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var handled []string
+	var h *Handler
+	testMsgHdl := func(m handler.Message) {
+		handled = append(handled, string(m.Payload()))
+		<-h.sChan
+	}
+	h = New(2, 1, testMsgHdl, nil)
+	h.SetQueue(q)
+	for i := 0; i < 5; i++ {
+		if err = h.Put(&mockMessage{topic: "test", payload: []byte(strconv.Itoa(i))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(h.messages) != 2 || q.Len() != 3 {
+		t.Error("expected 2 buffered and 3 queued messages, got", len(h.messages), q.Len())
+	}
+	h.Start()
+	time.Sleep(replayInterval * 3)
+	h.Stop()
+	if !reflect.DeepEqual([]string{"0"}, handled) {
+		t.Error("expected [0], got", handled)
+	}
+	if q.Len() != 4 {
+		t.Error("expected 4 queued messages, got", q.Len())
+	}
+	if err = q.Close(); err != nil {
+		t.Fatal(err)
+	}
+	t.Run("replay", func(t *testing.T) {
+		q, err := NewQueue(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer q.Close()
+		h2 := New(2, 1, func(m handler.Message) {
+			handled = append(handled, string(m.Payload()))
+		}, nil)
+		h2.SetQueue(q)
+		if err = h2.Put(&mockMessage{topic: "test", payload: []byte("5")}); err != nil {
+			t.Fatal(err)
+		}
+		h2.Start()
+		time.Sleep(replayInterval * 5)
+		h2.Stop()
+		expected := []string{"0", "1", "2", "3", "4", "5"}
+		if !reflect.DeepEqual(expected, handled) {
+			t.Error("expected", expected, "got", handled)
+		}
+		if q.Len() != 0 {
+			t.Error("expected 0 queued messages, got", q.Len())
+		}
+	})
+}
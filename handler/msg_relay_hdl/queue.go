@@ -0,0 +1,294 @@
+package msg_relay_hdl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler"
+	"io"
+	"os"
+	"sync"
+)
+
+const (
+	queueReadAhead   = 100
+	queueCompactSize = 1 << 20
+)
+
+// Queue is a file backed message queue. Messages are stored as JSON lines and synced to disk on append, concurrent
+// appends share a single sync. Messages are read ahead in batches, the file is truncated once all messages are removed
+// and rewritten once the removed messages exceed the compact size and the remaining messages.
+type Queue struct {
+	path        string
+	file        *os.File
+	head        int64 // offset of the first message
+	size        int64 // offset after the last message
+	count       int
+	ahead       []queueItem // messages read from head
+	compactSize int64
+	written     uint64 // number of appended messages
+	synced      uint64 // number of appended messages synced to disk
+	mu          sync.Mutex
+	syncMu      sync.Mutex
+}
+
+type queueItem struct {
+	message handler.Message
+	err     error
+	size    int64
+}
+
+type record struct {
+	Topic   string `json:"topic"`
+	Payload []byte `json:"payload"`
+}
+
+type message struct {
+	topic   string
+	payload []byte
+}
+
+func (m *message) Topic() string {
+	return m.topic
+}
+
+func (m *message) Payload() []byte {
+	return m.payload
+}
+
+// NewQueue opens or creates the queue file at path, an incomplete last message is discarded.
+func NewQueue(path string) (*Queue, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0660)
+	if err != nil {
+		return nil, err
+	}
+	q := &Queue{path: path, file: file, compactSize: queueCompactSize}
+	if err = q.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+// Append adds the message to the end of the queue.
+func (q *Queue) Append(m handler.Message) error {
+	seq, err := q.write(m)
+	if err != nil {
+		return err
+	}
+	return q.sync(seq)
+}
+
+// Prepend adds the messages to the front of the queue by rewriting the queue file.
+func (q *Queue) Prepend(messages []handler.Message) error {
+	var buf bytes.Buffer
+	for _, m := range messages {
+		b, err := marshalRecord(m)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.rewrite(buf.Bytes()); err != nil {
+		return err
+	}
+	q.count += len(messages)
+	q.ahead = nil
+	return nil
+}
+
+// Front returns the first message or nil if the queue is empty.
+func (q *Queue) Front() (handler.Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.count == 0 {
+		return nil, nil
+	}
+	if err := q.readAhead(); err != nil {
+		return nil, err
+	}
+	return q.ahead[0].message, q.ahead[0].err
+}
+
+// Remove removes the first message.
+func (q *Queue) Remove() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.count == 0 {
+		return nil
+	}
+	if err := q.readAhead(); err != nil {
+		return err
+	}
+	q.head += q.ahead[0].size
+	q.ahead = q.ahead[1:]
+	q.count--
+	if q.count == 0 {
+		if err := q.file.Truncate(0); err != nil {
+			return err
+		}
+		q.head = 0
+		q.size = 0
+		q.synced = q.written
+		return nil
+	}
+	if q.head >= q.compactSize && q.head >= q.size-q.head {
+		return q.rewrite(nil)
+	}
+	return nil
+}
+
+// Len returns the number of queued messages.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.count
+}
+
+// Close removes already consumed messages from the file and closes it.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.head > 0 {
+		if err := q.rewrite(nil); err != nil {
+			q.file.Close()
+			return err
+		}
+	}
+	if err := q.file.Sync(); err != nil {
+		q.file.Close()
+		return err
+	}
+	return q.file.Close()
+}
+
+// write adds the message to the end of the queue file without syncing it and returns the sequence number required by
+// sync.
+func (q *Queue) write(m handler.Message) (uint64, error) {
+	b, err := marshalRecord(m)
+	if err != nil {
+		return 0, err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err = q.file.WriteAt(b, q.size); err != nil {
+		return 0, err
+	}
+	q.size += int64(len(b))
+	q.count++
+	q.written++
+	return q.written, nil
+}
+
+// sync waits until the message with the given sequence number is synced to disk. Messages written while a sync is in
+// progress are synced together by the next caller.
+func (q *Queue) sync(seq uint64) error {
+	q.syncMu.Lock()
+	defer q.syncMu.Unlock()
+	q.mu.Lock()
+	if q.synced >= seq {
+		q.mu.Unlock()
+		return nil
+	}
+	written, file := q.written, q.file
+	q.mu.Unlock()
+	err := file.Sync()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.synced >= seq {
+		// the file has been rewritten or truncated in the meantime
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	q.synced = written
+	return nil
+}
+
+func (q *Queue) load() error {
+	reader := bufio.NewReader(q.file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		q.size += int64(len(line))
+		q.count++
+	}
+	return q.file.Truncate(q.size)
+}
+
+// readAhead reads up to queueReadAhead messages following the head if no messages have been read yet. Messages that
+// can not be decoded are kept with their error.
+func (q *Queue) readAhead() error {
+	if len(q.ahead) > 0 {
+		return nil
+	}
+	reader := bufio.NewReader(io.NewSectionReader(q.file, q.head, q.size-q.head))
+	for i := 0; i < queueReadAhead && i < q.count; i++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			if len(q.ahead) > 0 {
+				return nil
+			}
+			return err
+		}
+		item := queueItem{size: int64(len(line))}
+		var r record
+		if item.err = json.Unmarshal(line, &r); item.err == nil {
+			item.message = &message{topic: r.Topic, payload: r.Payload}
+		}
+		q.ahead = append(q.ahead, item)
+	}
+	return nil
+}
+
+// rewrite replaces the queue file with the given messages followed by the messages after the head.
+func (q *Queue) rewrite(b []byte) error {
+	tmpPath := q.path + ".tmp"
+	tmpFile, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0660)
+	if err != nil {
+		return err
+	}
+	if err = writeQueueFile(tmpFile, b, io.NewSectionReader(q.file, q.head, q.size-q.head)); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err = os.Rename(tmpPath, q.path); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	q.file.Close()
+	q.file = tmpFile
+	q.size = int64(len(b)) + q.size - q.head
+	q.head = 0
+	q.synced = q.written
+	return nil
+}
+
+func marshalRecord(m handler.Message) ([]byte, error) {
+	b, err := json.Marshal(record{Topic: m.Topic(), Payload: m.Payload()})
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+func writeQueueFile(file *os.File, b []byte, r io.Reader) error {
+	if _, err := file.Write(b); err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		return err
+	}
+	return file.Sync()
+}
@@ -0,0 +1,217 @@
+package msg_relay_hdl
+
+import (
+	"github.com/SENERGY-Platform/mgw-device-manager/handler"
+	"os"
+	"path"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestQueue(t *testing.T) {
+	p := path.Join(t.TempDir(), "queue")
+	q, err := NewQueue(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("empty", func(t *testing.T) {
+		m, err := q.Front()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m != nil {
+			t.Error("expected nil, got", m)
+		}
+		if err = q.Remove(); err != nil {
+			t.Error(err)
+		}
+	})
+	a := &message{topic: "a", payload: []byte(`{"test":1}`)}
+	b := &message{topic: "b", payload: []byte("test\n")}
+	c := &message{topic: "c"}
+	t.Run("append and prepend", func(t *testing.T) {
+		if err := q.Append(b); err != nil {
+			t.Fatal(err)
+		}
+		if err := q.Append(c); err != nil {
+			t.Fatal(err)
+		}
+		if err := q.Prepend([]handler.Message{a}); err != nil {
+			t.Fatal(err)
+		}
+		if q.Len() != 3 {
+			t.Error("expected 3 messages, got", q.Len())
+		}
+	})
+	t.Run("reopen", func(t *testing.T) {
+		if err := q.Close(); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = f.WriteString(`{"topic":"d"`); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		if q, err = NewQueue(p); err != nil {
+			t.Fatal(err)
+		}
+		if q.Len() != 3 {
+			t.Error("expected 3 messages, got", q.Len())
+		}
+	})
+	t.Run("front and remove", func(t *testing.T) {
+		for _, msg := range []*message{a, b, c} {
+			m, err := q.Front()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m == nil || m.Topic() != msg.topic || !reflect.DeepEqual(m.Payload(), msg.payload) {
+				t.Error("expected", msg, "got", m)
+			}
+			if err = q.Remove(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if q.Len() != 0 {
+			t.Error("expected 0 messages, got", q.Len())
+		}
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() != 0 {
+			t.Error("file not truncated")
+		}
+	})
+	t.Run("concurrent append", func(t *testing.T) {
+		var wg sync.WaitGroup
+		errs := make(chan error, 100)
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs <- q.Append(&message{topic: strconv.Itoa(i)})
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Error(err)
+			}
+		}
+		if q.Len() != 100 {
+			t.Error("expected 100 messages, got", q.Len())
+		}
+		if q.synced != q.written {
+			t.Error("expected all messages synced, got", q.synced, "of", q.written)
+		}
+	})
+	t.Run("sync after remove", func(t *testing.T) {
+		seq, err := q.write(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for q.Len() > 0 {
+			if err = q.Remove(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = q.sync(seq); err != nil {
+			t.Error(err)
+		}
+	})
+	if err = q.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQueue_compact(t *testing.T) {
+	p := path.Join(t.TempDir(), "queue")
+	q, err := NewQueue(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.compactSize = 1000
+	n := queueReadAhead*2 + 50
+	for i := 0; i < n; i++ {
+		if err = q.Append(&message{topic: strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := info.Size()
+	for i := 0; i < n-10; i++ {
+		m, err := q.Front()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m == nil || m.Topic() != strconv.Itoa(i) {
+			t.Fatal("expected", i, "got", m)
+		}
+		if err = q.Remove(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if info, err = os.Stat(p); err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() >= size/2 {
+		t.Error("file not compacted, size", info.Size())
+	}
+	t.Run("invalid message", func(t *testing.T) {
+		if err := q.Close(); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = f.WriteString("test\n"); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		if q, err = NewQueue(p); err != nil {
+			t.Fatal(err)
+		}
+		if err = q.Append(&message{topic: "a"}); err != nil {
+			t.Fatal(err)
+		}
+		for i := n - 10; i < n; i++ {
+			m, err := q.Front()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m == nil || m.Topic() != strconv.Itoa(i) {
+				t.Fatal("expected", i, "got", m)
+			}
+			if err = q.Remove(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err = q.Front(); err == nil {
+			t.Error("expected error")
+		}
+		if err = q.Remove(); err != nil {
+			t.Fatal(err)
+		}
+		m, err := q.Front()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m == nil || m.Topic() != "a" {
+			t.Error("expected a, got", m)
+		}
+	})
+	if err = q.Close(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,103 @@
+package storage_hdl
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"time"
+)
+
+func (h *Handler) CreateAuditEntry(ctx context.Context, txItf driver.Tx, entry lib_model.AuditEntry) error {
+	execContext := h.db.ExecContext
+	if txItf != nil {
+		tx := txItf.(*sql.Tx)
+		execContext = tx.ExecContext
+	}
+	before, err := marshalUserData(entry.Before)
+	if err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	after, err := marshalUserData(entry.After)
+	if err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	_, err = execContext(ctx, h.rebind("INSERT INTO audit_log (timestamp, request_id, caller, action, dev_id, before, after) VALUES (?, ?, ?, ?, ?, ?, ?);"), entry.Timestamp.UTC().Format(tLayoutSortable), entry.RequestID, entry.Caller, entry.Action, entry.DeviceID, before, after)
+	if err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	return nil
+}
+
+func (h *Handler) ReadAuditLog(ctx context.Context, filter lib_model.AuditLogFilter) ([]lib_model.AuditEntry, error) {
+	q := "SELECT timestamp, request_id, caller, action, dev_id, before, after FROM audit_log WHERE 1 = 1"
+	var val []any
+	if filter.DeviceID != "" {
+		q += " AND dev_id = ?"
+		val = append(val, filter.DeviceID)
+	}
+	if !filter.From.IsZero() {
+		q += " AND timestamp >= ?"
+		val = append(val, filter.From.UTC().Format(tLayoutSortable))
+	}
+	if !filter.To.IsZero() {
+		q += " AND timestamp <= ?"
+		val = append(val, filter.To.UTC().Format(tLayoutSortable))
+	}
+	q += " ORDER BY timestamp DESC, id DESC"
+	if filter.Limit > 0 {
+		q += " LIMIT ?"
+		val = append(val, filter.Limit)
+	}
+	rows, err := h.db.QueryContext(ctx, h.rebind(q+";"), val...)
+	if err != nil {
+		return nil, lib_model.NewInternalError(err)
+	}
+	defer rows.Close()
+	var entries []lib_model.AuditEntry
+	for rows.Next() {
+		var entry lib_model.AuditEntry
+		var timestamp string
+		var before, after sql.NullString
+		if err = rows.Scan(&timestamp, &entry.RequestID, &entry.Caller, &entry.Action, &entry.DeviceID, &before, &after); err != nil {
+			return nil, lib_model.NewInternalError(err)
+		}
+		if entry.Timestamp, err = time.Parse(tLayoutSortable, timestamp); err != nil {
+			return nil, lib_model.NewInternalError(err)
+		}
+		if entry.Before, err = unmarshalUserData(before); err != nil {
+			return nil, lib_model.NewInternalError(err)
+		}
+		if entry.After, err = unmarshalUserData(after); err != nil {
+			return nil, lib_model.NewInternalError(err)
+		}
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, lib_model.NewInternalError(err)
+	}
+	return entries, nil
+}
+
+func marshalUserData(userData *lib_model.DeviceUserData) (sql.NullString, error) {
+	if userData == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(userData)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func unmarshalUserData(s sql.NullString) (*lib_model.DeviceUserData, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	var userData lib_model.DeviceUserData
+	if err := json.Unmarshal([]byte(s.String), &userData); err != nil {
+		return nil, err
+	}
+	return &userData, nil
+}
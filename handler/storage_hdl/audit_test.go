@@ -0,0 +1,86 @@
+package storage_hdl
+
+import (
+	"context"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHandler_AuditLog(t *testing.T) {
+	testDB, driver, err := initDB(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(testDB, driver)
+	a := lib_model.AuditEntry{
+		Timestamp: time.Now().Add(-time.Hour).UTC(),
+		RequestID: "req1",
+		Caller:    "user",
+		Action:    lib_model.AuditSetUserData,
+		DeviceID:  "1",
+		Before:    &lib_model.DeviceUserData{},
+		After: &lib_model.DeviceUserData{
+			DeviceUserDataBase: lib_model.DeviceUserDataBase{Name: "lamp", Attributes: []lib_model.DeviceAttribute{{Key: "room", Value: "kitchen"}}},
+			Updated:            time.Now().Add(-time.Hour).UTC(),
+		},
+	}
+	b := lib_model.AuditEntry{
+		Timestamp: time.Now().UTC(),
+		RequestID: "req2",
+		Action:    lib_model.AuditDelete,
+		DeviceID:  "2",
+		Before:    &lib_model.DeviceUserData{},
+	}
+	for _, entry := range []lib_model.AuditEntry{a, b} {
+		if err = h.CreateAuditEntry(context.Background(), nil, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Run("read all", func(t *testing.T) {
+		entries, err := h.ReadAuditLog(context.Background(), lib_model.AuditLogFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 {
+			t.Fatal("expected 2 entries, got", len(entries))
+		}
+		if !reflect.DeepEqual(b, entries[0]) {
+			t.Error("expected\n", b, "got\n", entries[0])
+		}
+		if !reflect.DeepEqual(a, entries[1]) {
+			t.Error("expected\n", a, "got\n", entries[1])
+		}
+	})
+	t.Run("filter", func(t *testing.T) {
+		entries, err := h.ReadAuditLog(context.Background(), lib_model.AuditLogFilter{DeviceID: "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].DeviceID != "1" {
+			t.Error("invalid entries", entries)
+		}
+		entries, err = h.ReadAuditLog(context.Background(), lib_model.AuditLogFilter{From: time.Now().Add(-time.Minute)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].DeviceID != "2" {
+			t.Error("invalid entries", entries)
+		}
+		entries, err = h.ReadAuditLog(context.Background(), lib_model.AuditLogFilter{To: time.Now().Add(-time.Minute)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].DeviceID != "1" {
+			t.Error("invalid entries", entries)
+		}
+		entries, err = h.ReadAuditLog(context.Background(), lib_model.AuditLogFilter{Limit: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Error("expected 1 entry, got", len(entries))
+		}
+	})
+}
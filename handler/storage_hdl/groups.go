@@ -0,0 +1,221 @@
+package storage_hdl
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+)
+
+func (h *Handler) CreateGroup(ctx context.Context, txItf driver.Tx, group lib_model.Group) error {
+	var tx *sql.Tx
+	if txItf != nil {
+		tx = txItf.(*sql.Tx)
+	} else {
+		var e error
+		if tx, e = h.db.BeginTx(ctx, nil); e != nil {
+			return lib_model.NewInternalError(e)
+		}
+		defer tx.Rollback()
+	}
+	_, err := tx.ExecContext(ctx, h.rebind("INSERT INTO device_groups (id, name, description, created, updated) VALUES (?, ?, ?, ?, ?);"), group.ID, group.Name, group.Description, timeToString(group.Created), timeToString(group.Updated))
+	if err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	if len(group.DeviceIDs) > 0 {
+		if err = h.insertGroupMembers(ctx, tx, group.ID, group.DeviceIDs); err != nil {
+			return err
+		}
+	}
+	if txItf == nil {
+		if err = tx.Commit(); err != nil {
+			return lib_model.NewInternalError(err)
+		}
+	}
+	return nil
+}
+
+func (h *Handler) ReadGroup(ctx context.Context, id string) (lib_model.Group, error) {
+	row := h.db.QueryRowContext(ctx, h.rebind("SELECT id, name, description, created, updated FROM device_groups WHERE id = ?;"), id)
+	group, err := scanGroup(row.Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return lib_model.Group{}, lib_model.NewNotFoundError(err)
+		}
+		return lib_model.Group{}, lib_model.NewInternalError(err)
+	}
+	rows, err := h.db.QueryContext(ctx, h.rebind("SELECT dev_id FROM device_group_members WHERE group_id = ? ORDER BY dev_id;"), id)
+	if err != nil {
+		return lib_model.Group{}, lib_model.NewInternalError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var devID string
+		if err = rows.Scan(&devID); err != nil {
+			return lib_model.Group{}, lib_model.NewInternalError(err)
+		}
+		group.DeviceIDs = append(group.DeviceIDs, devID)
+	}
+	if err = rows.Err(); err != nil {
+		return lib_model.Group{}, lib_model.NewInternalError(err)
+	}
+	return group, nil
+}
+
+func (h *Handler) ReadGroups(ctx context.Context) ([]lib_model.Group, error) {
+	groupRows, err := h.db.QueryContext(ctx, "SELECT id, name, description, created, updated FROM device_groups ORDER BY name, id;")
+	if err != nil {
+		return nil, lib_model.NewInternalError(err)
+	}
+	defer groupRows.Close()
+	memberRows, err := h.db.QueryContext(ctx, "SELECT group_id, dev_id FROM device_group_members ORDER BY dev_id;")
+	if err != nil {
+		return nil, lib_model.NewInternalError(err)
+	}
+	defer memberRows.Close()
+	var groups []lib_model.Group
+	index := make(map[string]int)
+	for groupRows.Next() {
+		group, err := scanGroup(groupRows.Scan)
+		if err != nil {
+			return nil, lib_model.NewInternalError(err)
+		}
+		index[group.ID] = len(groups)
+		groups = append(groups, group)
+	}
+	if err = groupRows.Err(); err != nil {
+		return nil, lib_model.NewInternalError(err)
+	}
+	for memberRows.Next() {
+		var id, devID string
+		if err = memberRows.Scan(&id, &devID); err != nil {
+			return nil, lib_model.NewInternalError(err)
+		}
+		if i, ok := index[id]; ok {
+			groups[i].DeviceIDs = append(groups[i].DeviceIDs, devID)
+		}
+	}
+	if err = memberRows.Err(); err != nil {
+		return nil, lib_model.NewInternalError(err)
+	}
+	return groups, nil
+}
+
+func (h *Handler) UpdateGroup(ctx context.Context, txItf driver.Tx, group lib_model.Group) error {
+	execContext := h.db.ExecContext
+	if txItf != nil {
+		tx := txItf.(*sql.Tx)
+		execContext = tx.ExecContext
+	}
+	res, err := execContext(ctx, h.rebind("UPDATE device_groups SET name = ?, description = ?, updated = ? WHERE id = ?;"), group.Name, group.Description, timeToString(group.Updated), group.ID)
+	if err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	if n < 1 {
+		return lib_model.NewNotFoundError(errors.New("not found"))
+	}
+	return nil
+}
+
+func (h *Handler) UpdateGroupDevices(ctx context.Context, txItf driver.Tx, id string, deviceIDs []string) error {
+	var tx *sql.Tx
+	if txItf != nil {
+		tx = txItf.(*sql.Tx)
+	} else {
+		var e error
+		if tx, e = h.db.BeginTx(ctx, nil); e != nil {
+			return lib_model.NewInternalError(e)
+		}
+		defer tx.Rollback()
+	}
+	_, err := tx.ExecContext(ctx, h.rebind("DELETE FROM device_group_members WHERE group_id = ?;"), id)
+	if err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	if len(deviceIDs) > 0 {
+		if err = h.insertGroupMembers(ctx, tx, id, deviceIDs); err != nil {
+			return err
+		}
+	}
+	if txItf == nil {
+		if err = tx.Commit(); err != nil {
+			return lib_model.NewInternalError(err)
+		}
+	}
+	return nil
+}
+
+func (h *Handler) DeleteGroup(ctx context.Context, txItf driver.Tx, id string) error {
+	execContext := h.db.ExecContext
+	if txItf != nil {
+		tx := txItf.(*sql.Tx)
+		execContext = tx.ExecContext
+	}
+	res, err := execContext(ctx, h.rebind("DELETE FROM device_groups WHERE id = ?;"), id)
+	if err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	if n < 1 {
+		return lib_model.NewNotFoundError(errors.New("not found"))
+	}
+	return nil
+}
+
+func (h *Handler) readDeviceGroups(ctx context.Context, id string) ([]string, error) {
+	rows, err := h.db.QueryContext(ctx, h.rebind("SELECT group_id FROM device_group_members WHERE dev_id = ? ORDER BY group_id;"), id)
+	if err != nil {
+		return nil, lib_model.NewInternalError(err)
+	}
+	defer rows.Close()
+	var groupIDs []string
+	for rows.Next() {
+		var groupID string
+		if err = rows.Scan(&groupID); err != nil {
+			return nil, lib_model.NewInternalError(err)
+		}
+		groupIDs = append(groupIDs, groupID)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, lib_model.NewInternalError(err)
+	}
+	return groupIDs, nil
+}
+
+func (h *Handler) insertGroupMembers(ctx context.Context, tx *sql.Tx, id string, deviceIDs []string) error {
+	stmt, err := tx.PrepareContext(ctx, h.rebind("INSERT INTO device_group_members (group_id, dev_id) VALUES (?, ?);"))
+	if err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	defer stmt.Close()
+	for _, devID := range removeDuplicates(deviceIDs) {
+		if _, err = stmt.ExecContext(ctx, id, devID); err != nil {
+			return lib_model.NewInternalError(err)
+		}
+	}
+	return nil
+}
+
+func scanGroup(scan func(dest ...any) error) (lib_model.Group, error) {
+	var group lib_model.Group
+	var created, updated string
+	if err := scan(&group.ID, &group.Name, &group.Description, &created, &updated); err != nil {
+		return lib_model.Group{}, err
+	}
+	var err error
+	if group.Created, err = stringToTime(created); err != nil {
+		return lib_model.Group{}, err
+	}
+	if group.Updated, err = stringToTime(updated); err != nil {
+		return lib_model.Group{}, err
+	}
+	return group, nil
+}
@@ -0,0 +1,130 @@
+package storage_hdl
+
+import (
+	"context"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHandler_Groups(t *testing.T) {
+	testDB, driver, err := initDB(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(testDB, driver)
+	for _, id := range []string{"1", "2", "3"} {
+		err = h.Create(context.Background(), nil, lib_model.DeviceData{DeviceDataBase: lib_model.DeviceDataBase{ID: id, Ref: "a", Type: "x"}, Created: time.Now().UTC()})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := lib_model.Group{
+		ID:        "g1",
+		GroupBase: lib_model.GroupBase{Name: "Ground floor", Description: "test"},
+		DeviceIDs: []string{"1", "2"},
+		Created:   time.Now().UTC(),
+	}
+	t.Run("create group", func(t *testing.T) {
+		if err = h.CreateGroup(context.Background(), nil, a); err != nil {
+			t.Fatal(err)
+		}
+		if err = h.CreateGroup(context.Background(), nil, lib_model.Group{ID: "g2", GroupBase: lib_model.GroupBase{Name: "Heating"}, Created: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		if err = h.CreateGroup(context.Background(), nil, lib_model.Group{ID: "g3", DeviceIDs: []string{"test"}, Created: time.Now()}); err == nil {
+			t.Error("expected error for unknown device")
+		}
+	})
+	t.Run("read group", func(t *testing.T) {
+		b, err := h.ReadGroup(context.Background(), "g1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(a, b) {
+			t.Error("expected\n", a, "got\n", b)
+		}
+		if _, err = h.ReadGroup(context.Background(), "test"); err == nil {
+			t.Error("expected error")
+		}
+	})
+	t.Run("read groups", func(t *testing.T) {
+		groups, err := h.ReadGroups(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(groups) != 2 || groups[0].ID != "g1" || groups[1].ID != "g2" {
+			t.Fatal("invalid groups", groups)
+		}
+		if !reflect.DeepEqual(groups[0].DeviceIDs, a.DeviceIDs) || groups[1].DeviceIDs != nil {
+			t.Error("invalid members", groups)
+		}
+	})
+	t.Run("device membership", func(t *testing.T) {
+		if err = h.UpdateGroupDevices(context.Background(), nil, "g2", []string{"2"}); err != nil {
+			t.Fatal(err)
+		}
+		device, err := h.Read(context.Background(), "2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(device.Groups, []string{"g1", "g2"}) {
+			t.Error("expected [g1 g2] got", device.Groups)
+		}
+		devices, total, err := h.ReadAll(context.Background(), lib_model.DevicesFilter{Group: "g1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 2 || len(devices) != 2 || devices[0].ID != "1" || devices[1].ID != "2" {
+			t.Fatal("invalid devices", devices)
+		}
+		if !reflect.DeepEqual(devices[0].Groups, []string{"g1"}) || !reflect.DeepEqual(devices[1].Groups, []string{"g1", "g2"}) {
+			t.Error("invalid groups", devices[0].Groups, devices[1].Groups)
+		}
+	})
+	t.Run("update group", func(t *testing.T) {
+		a.Name = "First floor"
+		a.Updated = time.Now().UTC()
+		if err = h.UpdateGroup(context.Background(), nil, a); err != nil {
+			t.Fatal(err)
+		}
+		b, err := h.ReadGroup(context.Background(), "g1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(a, b) {
+			t.Error("expected\n", a, "got\n", b)
+		}
+		if err = h.UpdateGroup(context.Background(), nil, lib_model.Group{ID: "test"}); err == nil {
+			t.Error("expected error")
+		}
+	})
+	t.Run("delete device", func(t *testing.T) {
+		if err = h.Delete(context.Background(), nil, "2"); err != nil {
+			t.Fatal(err)
+		}
+		b, err := h.ReadGroup(context.Background(), "g1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(b.DeviceIDs, []string{"1"}) {
+			t.Error("expected [1] got", b.DeviceIDs)
+		}
+	})
+	t.Run("delete group", func(t *testing.T) {
+		if err = h.DeleteGroup(context.Background(), nil, "g1"); err != nil {
+			t.Fatal(err)
+		}
+		device, err := h.Read(context.Background(), "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if device.Groups != nil {
+			t.Error("expected no groups got", device.Groups)
+		}
+		if err = h.DeleteGroup(context.Background(), nil, "g1"); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
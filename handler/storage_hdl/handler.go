@@ -5,19 +5,47 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler"
 	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/SENERGY-Platform/mgw-device-manager/util/db"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 )
 
+// tLayout parses stored timestamps, it also accepts values written with variable precision by earlier versions.
 var tLayout = time.RFC3339Nano
 
+const devicesFrom = " FROM devices LEFT JOIN device_states ON devices.id = device_states.dev_id"
+
+const stateExpr = "COALESCE(NULLIF(device_states.state, ''), '" + lib_model.NotAvailable + "')"
+
+var sortColumns = map[lib_model.DevicesSortField]string{
+	lib_model.SortByID:       "devices.id",
+	lib_model.SortByName:     "devices.name",
+	lib_model.SortByUserName: "devices.usr_name",
+	lib_model.SortByType:     "devices.type",
+	lib_model.SortByCreated:  "devices.created",
+	lib_model.SortByUpdated:  "devices.updated",
+	lib_model.SortByState:    stateExpr,
+}
+
+// tLayoutSortable is used for timestamps compared or sorted in queries, values must be in UTC.
+var tLayoutSortable = "2006-01-02T15:04:05.000000000Z07:00"
+
 type Handler struct {
-	db *sql.DB
+	db     *sql.DB
+	driver string
+	fts    *bool
+	ftsMu  sync.Mutex
 }
 
-func New(db *sql.DB) *Handler {
-	return &Handler{db: db}
+// New creates a storage handler, the driver selects the SQL dialect and must match the driver of the database.
+func New(sqlDB *sql.DB, driver string) *Handler {
+	return &Handler{db: sqlDB, driver: driver}
 }
 
 func (h *Handler) BeginTransaction(ctx context.Context) (driver.Tx, error) {
@@ -28,66 +56,94 @@ func (h *Handler) BeginTransaction(ctx context.Context) (driver.Tx, error) {
 	return tx, nil
 }
 
-func (h *Handler) ReadAll(ctx context.Context, filter lib_model.DevicesFilter) (map[string]lib_model.DeviceBase, error) {
-	fc, val := genFilter(filter)
-	q := "SELECT id, ref, name, type, created, updated, usr_name, usr_updated FROM devices"
-	if fc != "" {
-		q += fc
+func (h *Handler) ReadAll(ctx context.Context, filter lib_model.DevicesFilter) ([]lib_model.DeviceBase, int, error) {
+	fts, err := h.fullTextSearch(ctx)
+	if err != nil {
+		return nil, 0, lib_model.NewInternalError(err)
 	}
-	devRows, err := h.db.QueryContext(ctx, q, val...)
+	from, val, search := h.genSearch(filter, fts)
+	fc, fVal := genFilter(filter)
+	val = append(val, fVal...)
+	sc, err := h.genSortAndLimit(filter, search)
 	if err != nil {
-		return nil, lib_model.NewInternalError(err)
+		return nil, 0, lib_model.NewInvalidInputError(err)
+	}
+	var total int
+	if err = h.db.QueryRowContext(ctx, h.rebind("SELECT COUNT(*)"+from+fc+";"), val...).Scan(&total); err != nil {
+		return nil, 0, lib_model.NewInternalError(err)
+	}
+	devRows, err := h.db.QueryContext(ctx, h.rebind("SELECT devices.id, devices.ref, devices.name, devices.type, devices.created, devices.updated, devices.usr_name, devices.usr_updated"+from+fc+sc+";"), val...)
+	if err != nil {
+		return nil, 0, lib_model.NewInternalError(err)
 	}
 	defer devRows.Close()
-	attrRows, err := h.db.QueryContext(ctx, "SELECT dev_id, is_usr, key_name, value FROM device_attributes;")
+	attrRows, err := h.db.QueryContext(ctx, h.rebind("SELECT dev_id, is_usr, key_name, value FROM device_attributes WHERE dev_id IN (SELECT devices.id"+from+fc+sc+");"), val...)
 	if err != nil {
-		return nil, lib_model.NewInternalError(err)
+		return nil, 0, lib_model.NewInternalError(err)
 	}
 	defer attrRows.Close()
-	devices := make(map[string]lib_model.DeviceBase)
+	groupRows, err := h.db.QueryContext(ctx, h.rebind("SELECT dev_id, group_id FROM device_group_members WHERE dev_id IN (SELECT devices.id"+from+fc+sc+") ORDER BY group_id;"), val...)
+	if err != nil {
+		return nil, 0, lib_model.NewInternalError(err)
+	}
+	defer groupRows.Close()
+	var devices []lib_model.DeviceBase
+	index := make(map[string]int)
 	for devRows.Next() {
 		var device lib_model.DeviceBase
 		var created, updated, usrUpdated string
 		if err = devRows.Scan(&device.ID, &device.Ref, &device.Name, &device.Type, &created, &updated, &device.UserData.Name, &usrUpdated); err != nil {
-			return nil, lib_model.NewInternalError(err)
+			return nil, 0, lib_model.NewInternalError(err)
 		}
 		device.Created, err = stringToTime(created)
 		if err != nil {
-			return nil, lib_model.NewInternalError(err)
+			return nil, 0, lib_model.NewInternalError(err)
 		}
 		device.Updated, err = stringToTime(updated)
 		if err != nil {
-			return nil, lib_model.NewInternalError(err)
+			return nil, 0, lib_model.NewInternalError(err)
 		}
 		device.UserData.Updated, err = stringToTime(usrUpdated)
 		if err != nil {
-			return nil, lib_model.NewInternalError(err)
+			return nil, 0, lib_model.NewInternalError(err)
 		}
-		devices[device.ID] = device
+		index[device.ID] = len(devices)
+		devices = append(devices, device)
 	}
 	if err = devRows.Err(); err != nil {
-		return nil, lib_model.NewInternalError(err)
+		return nil, 0, lib_model.NewInternalError(err)
 	}
 	for attrRows.Next() {
 		var id string
 		var isUsr bool
 		var devAttr lib_model.DeviceAttribute
 		if err = attrRows.Scan(&id, &isUsr, &devAttr.Key, &devAttr.Value); err != nil {
-			return nil, lib_model.NewInternalError(err)
+			return nil, 0, lib_model.NewInternalError(err)
 		}
-		if dev, ok := devices[id]; ok {
+		if i, ok := index[id]; ok {
 			if isUsr {
-				dev.UserData.Attributes = append(dev.UserData.Attributes, devAttr)
+				devices[i].UserData.Attributes = append(devices[i].UserData.Attributes, devAttr)
 			} else {
-				dev.Attributes = append(dev.Attributes, devAttr)
+				devices[i].Attributes = append(devices[i].Attributes, devAttr)
 			}
-			devices[id] = dev
 		}
 	}
 	if err = attrRows.Err(); err != nil {
-		return nil, lib_model.NewInternalError(err)
+		return nil, 0, lib_model.NewInternalError(err)
+	}
+	for groupRows.Next() {
+		var id, groupID string
+		if err = groupRows.Scan(&id, &groupID); err != nil {
+			return nil, 0, lib_model.NewInternalError(err)
+		}
+		if i, ok := index[id]; ok {
+			devices[i].Groups = append(devices[i].Groups, groupID)
+		}
 	}
-	return devices, nil
+	if err = groupRows.Err(); err != nil {
+		return nil, 0, lib_model.NewInternalError(err)
+	}
+	return devices, total, nil
 }
 
 func (h *Handler) Create(ctx context.Context, txItf driver.Tx, device lib_model.DeviceData) error {
@@ -101,15 +157,18 @@ func (h *Handler) Create(ctx context.Context, txItf driver.Tx, device lib_model.
 		}
 		defer tx.Rollback()
 	}
-	_, err := tx.ExecContext(ctx, "INSERT INTO devices (id, ref, name, type, created, updated) VALUES (?, ?, ?, ?, ?, ?);", device.ID, device.Ref, device.Name, device.Type, timeToString(device.Created), timeToString(device.Updated))
+	_, err := tx.ExecContext(ctx, h.rebind("INSERT INTO devices (id, ref, name, type, created, updated) VALUES (?, ?, ?, ?, ?, ?);"), device.ID, device.Ref, device.Name, device.Type, timeToString(device.Created), timeToString(device.Updated))
 	if err != nil {
 		return lib_model.NewInternalError(err)
 	}
 	if len(device.Attributes) > 0 {
-		if err = insertAttributes(ctx, tx.PrepareContext, device.ID, false, device.Attributes); err != nil {
+		if err = h.insertAttributes(ctx, tx.PrepareContext, device.ID, false, device.Attributes); err != nil {
 			return err
 		}
 	}
+	if err = h.updateSearchIndex(ctx, tx, device.ID); err != nil {
+		return err
+	}
 	if txItf == nil {
 		if err = tx.Commit(); err != nil {
 			return lib_model.NewInternalError(err)
@@ -119,7 +178,7 @@ func (h *Handler) Create(ctx context.Context, txItf driver.Tx, device lib_model.
 }
 
 func (h *Handler) Read(ctx context.Context, id string) (lib_model.DeviceBase, error) {
-	row := h.db.QueryRowContext(ctx, "SELECT id, ref, name, type, created, updated, usr_name, usr_updated FROM devices WHERE id = ?;", id)
+	row := h.db.QueryRowContext(ctx, h.rebind("SELECT id, ref, name, type, created, updated, usr_name, usr_updated FROM devices WHERE id = ?;"), id)
 	var device lib_model.DeviceBase
 	var created, updated, usrUpdated string
 	err := row.Scan(&device.ID, &device.Ref, &device.Name, &device.Type, &created, &updated, &device.UserData.Name, &usrUpdated)
@@ -129,7 +188,7 @@ func (h *Handler) Read(ctx context.Context, id string) (lib_model.DeviceBase, er
 		}
 		return lib_model.DeviceBase{}, lib_model.NewInternalError(err)
 	}
-	attrRows, err := h.db.QueryContext(ctx, "SELECT is_usr, key_name, value FROM device_attributes WHERE dev_id = ?;", id)
+	attrRows, err := h.db.QueryContext(ctx, h.rebind("SELECT is_usr, key_name, value FROM device_attributes WHERE dev_id = ?;"), id)
 	if err != nil {
 		return lib_model.DeviceBase{}, lib_model.NewInternalError(err)
 	}
@@ -161,6 +220,9 @@ func (h *Handler) Read(ctx context.Context, id string) (lib_model.DeviceBase, er
 	if err = attrRows.Err(); err != nil {
 		return lib_model.DeviceBase{}, lib_model.NewInternalError(err)
 	}
+	if device.Groups, err = h.readDeviceGroups(ctx, id); err != nil {
+		return lib_model.DeviceBase{}, err
+	}
 	return device, nil
 }
 
@@ -175,7 +237,7 @@ func (h *Handler) Update(ctx context.Context, txItf driver.Tx, deviceBase lib_mo
 		}
 		defer tx.Rollback()
 	}
-	res, err := tx.ExecContext(ctx, "UPDATE devices SET ref = ?, name = ?, type = ?, created = ?, updated = ? WHERE `id` = ?", deviceBase.Ref, deviceBase.Name, deviceBase.Type, timeToString(deviceBase.Created), timeToString(deviceBase.Updated), deviceBase.ID)
+	res, err := tx.ExecContext(ctx, h.rebind("UPDATE devices SET ref = ?, name = ?, type = ?, created = ?, updated = ? WHERE id = ?;"), deviceBase.Ref, deviceBase.Name, deviceBase.Type, timeToString(deviceBase.Created), timeToString(deviceBase.Updated), deviceBase.ID)
 	if err != nil {
 		return lib_model.NewInternalError(err)
 	}
@@ -186,15 +248,18 @@ func (h *Handler) Update(ctx context.Context, txItf driver.Tx, deviceBase lib_mo
 	if n < 1 {
 		return lib_model.NewNotFoundError(errors.New("not found"))
 	}
-	_, err = tx.ExecContext(ctx, "DELETE FROM device_attributes WHERE dev_id = ? AND is_usr = ?", deviceBase.ID, false)
+	_, err = tx.ExecContext(ctx, h.rebind("DELETE FROM device_attributes WHERE dev_id = ? AND is_usr = ?"), deviceBase.ID, false)
 	if err != nil {
 		return lib_model.NewInternalError(err)
 	}
 	if len(deviceBase.Attributes) > 0 {
-		if err = insertAttributes(ctx, tx.PrepareContext, deviceBase.ID, false, deviceBase.Attributes); err != nil {
+		if err = h.insertAttributes(ctx, tx.PrepareContext, deviceBase.ID, false, deviceBase.Attributes); err != nil {
 			return err
 		}
 	}
+	if err = h.updateSearchIndex(ctx, tx, deviceBase.ID); err != nil {
+		return err
+	}
 	if txItf == nil {
 		if err = tx.Commit(); err != nil {
 			return lib_model.NewInternalError(err)
@@ -214,7 +279,7 @@ func (h *Handler) UpdateUserData(ctx context.Context, txItf driver.Tx, id string
 		}
 		defer tx.Rollback()
 	}
-	res, err := tx.ExecContext(ctx, "UPDATE devices SET usr_name = ?, usr_updated = ? WHERE `id` = ?", userData.Name, timeToString(userData.Updated), id)
+	res, err := tx.ExecContext(ctx, h.rebind("UPDATE devices SET usr_name = ?, usr_updated = ? WHERE id = ?;"), userData.Name, timeToString(userData.Updated), id)
 	if err != nil {
 		return lib_model.NewInternalError(err)
 	}
@@ -225,15 +290,18 @@ func (h *Handler) UpdateUserData(ctx context.Context, txItf driver.Tx, id string
 	if n < 1 {
 		return lib_model.NewNotFoundError(errors.New("not found"))
 	}
-	_, err = tx.ExecContext(ctx, "DELETE FROM device_attributes WHERE dev_id = ? AND is_usr = ?", id, true)
+	_, err = tx.ExecContext(ctx, h.rebind("DELETE FROM device_attributes WHERE dev_id = ? AND is_usr = ?"), id, true)
 	if err != nil {
 		return lib_model.NewInternalError(err)
 	}
 	if len(userData.Attributes) > 0 {
-		if err = insertAttributes(ctx, tx.PrepareContext, id, true, userData.Attributes); err != nil {
+		if err = h.insertAttributes(ctx, tx.PrepareContext, id, true, userData.Attributes); err != nil {
 			return err
 		}
 	}
+	if err = h.updateSearchIndex(ctx, tx, id); err != nil {
+		return err
+	}
 	if txItf == nil {
 		if err = tx.Commit(); err != nil {
 			return lib_model.NewInternalError(err)
@@ -243,12 +311,17 @@ func (h *Handler) UpdateUserData(ctx context.Context, txItf driver.Tx, id string
 }
 
 func (h *Handler) Delete(ctx context.Context, txItf driver.Tx, id string) error {
-	execContext := h.db.ExecContext
+	var tx *sql.Tx
 	if txItf != nil {
-		tx := txItf.(*sql.Tx)
-		execContext = tx.ExecContext
+		tx = txItf.(*sql.Tx)
+	} else {
+		var e error
+		if tx, e = h.db.BeginTx(ctx, nil); e != nil {
+			return lib_model.NewInternalError(e)
+		}
+		defer tx.Rollback()
 	}
-	res, err := execContext(ctx, "DELETE FROM devices WHERE id = ?", id)
+	res, err := tx.ExecContext(ctx, h.rebind("DELETE FROM devices WHERE id = ?"), id)
 	if err != nil {
 		return lib_model.NewInternalError(err)
 	}
@@ -259,10 +332,150 @@ func (h *Handler) Delete(ctx context.Context, txItf driver.Tx, id string) error
 	if n < 1 {
 		return lib_model.NewNotFoundError(errors.New("not found"))
 	}
+	fts, err := h.fullTextSearch(ctx)
+	if err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	if fts {
+		if _, err = tx.ExecContext(ctx, h.rebind("DELETE FROM devices_search WHERE dev_id = ?;"), id); err != nil {
+			return lib_model.NewInternalError(err)
+		}
+	}
+	if txItf == nil {
+		if err = tx.Commit(); err != nil {
+			return lib_model.NewInternalError(err)
+		}
+	}
+	return nil
+}
+
+func (h *Handler) ReadStates(ctx context.Context) (map[string]handler.DeviceStateData, error) {
+	rows, err := h.db.QueryContext(ctx, "SELECT device_states.dev_id, devices.ref, devices.type, device_states.state, device_states.updated, device_states.last_seen FROM device_states INNER JOIN devices ON device_states.dev_id = devices.id;")
+	if err != nil {
+		return nil, lib_model.NewInternalError(err)
+	}
+	defer rows.Close()
+	states := make(map[string]handler.DeviceStateData)
+	for rows.Next() {
+		var id, updated, lastSeen string
+		var state handler.DeviceStateData
+		if err = rows.Scan(&id, &state.Ref, &state.Type, &state.Value, &updated, &lastSeen); err != nil {
+			return nil, lib_model.NewInternalError(err)
+		}
+		state.Updated, err = stringToTime(updated)
+		if err != nil {
+			return nil, lib_model.NewInternalError(err)
+		}
+		state.LastSeen, err = stringToTime(lastSeen)
+		if err != nil {
+			return nil, lib_model.NewInternalError(err)
+		}
+		states[id] = state
+	}
+	if err = rows.Err(); err != nil {
+		return nil, lib_model.NewInternalError(err)
+	}
+	return states, nil
+}
+
+// CountDevices returns the number of devices per state, type and reference, devices without a state are counted as
+// not available.
+func (h *Handler) CountDevices(ctx context.Context) ([]handler.DeviceCount, error) {
+	rows, err := h.db.QueryContext(ctx, "SELECT "+stateExpr+", devices.type, devices.ref, COUNT(*)"+devicesFrom+" GROUP BY "+stateExpr+", devices.type, devices.ref;")
+	if err != nil {
+		return nil, lib_model.NewInternalError(err)
+	}
+	defer rows.Close()
+	var counts []handler.DeviceCount
+	for rows.Next() {
+		var count handler.DeviceCount
+		if err = rows.Scan(&count.State, &count.Type, &count.Ref, &count.Count); err != nil {
+			return nil, lib_model.NewInternalError(err)
+		}
+		counts = append(counts, count)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, lib_model.NewInternalError(err)
+	}
+	return counts, nil
+}
+
+func (h *Handler) UpdateState(ctx context.Context, txItf driver.Tx, id string, transition lib_model.DeviceStateTransition) error {
+	var tx *sql.Tx
+	if txItf != nil {
+		tx = txItf.(*sql.Tx)
+	} else {
+		var e error
+		if tx, e = h.db.BeginTx(ctx, nil); e != nil {
+			return lib_model.NewInternalError(e)
+		}
+		defer tx.Rollback()
+	}
+	_, err := tx.ExecContext(ctx, h.rebind("INSERT INTO device_states (dev_id, state, updated) VALUES (?, ?, ?) ON CONFLICT (dev_id) DO UPDATE SET state = excluded.state, updated = excluded.updated;"), id, transition.State, timeToString(transition.Timestamp))
+	if err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	_, err = tx.ExecContext(ctx, h.rebind("INSERT INTO device_state_history (dev_id, state, source, timestamp) VALUES (?, ?, ?, ?);"), id, transition.State, transition.Source, transition.Timestamp.UTC().Format(tLayoutSortable))
+	if err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	if txItf == nil {
+		if err = tx.Commit(); err != nil {
+			return lib_model.NewInternalError(err)
+		}
+	}
 	return nil
 }
 
-func insertAttributes(ctx context.Context, pf func(ctx context.Context, query string) (*sql.Stmt, error), id string, isUser bool, attributes []lib_model.DeviceAttribute) error {
+func (h *Handler) UpdateLastSeen(ctx context.Context, txItf driver.Tx, id string, lastSeen time.Time) error {
+	execContext := h.db.ExecContext
+	if txItf != nil {
+		tx := txItf.(*sql.Tx)
+		execContext = tx.ExecContext
+	}
+	_, err := execContext(ctx, h.rebind("INSERT INTO device_states (dev_id, last_seen) VALUES (?, ?) ON CONFLICT (dev_id) DO UPDATE SET last_seen = excluded.last_seen;"), id, timeToString(lastSeen))
+	if err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	return nil
+}
+
+func (h *Handler) ReadStateHistory(ctx context.Context, id string, filter lib_model.DeviceStateHistoryFilter) ([]lib_model.DeviceStateTransition, error) {
+	q := "SELECT state, source, timestamp FROM device_state_history WHERE dev_id = ?"
+	val := []any{id}
+	if !filter.From.IsZero() {
+		q += " AND timestamp >= ?"
+		val = append(val, filter.From.UTC().Format(tLayoutSortable))
+	}
+	if !filter.To.IsZero() {
+		q += " AND timestamp <= ?"
+		val = append(val, filter.To.UTC().Format(tLayoutSortable))
+	}
+	rows, err := h.db.QueryContext(ctx, h.rebind(q+" ORDER BY timestamp;"), val...)
+	if err != nil {
+		return nil, lib_model.NewInternalError(err)
+	}
+	defer rows.Close()
+	var transitions []lib_model.DeviceStateTransition
+	for rows.Next() {
+		var transition lib_model.DeviceStateTransition
+		var timestamp string
+		if err = rows.Scan(&transition.State, &transition.Source, &timestamp); err != nil {
+			return nil, lib_model.NewInternalError(err)
+		}
+		transition.Timestamp, err = time.Parse(tLayoutSortable, timestamp)
+		if err != nil {
+			return nil, lib_model.NewInternalError(err)
+		}
+		transitions = append(transitions, transition)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, lib_model.NewInternalError(err)
+	}
+	return transitions, nil
+}
+
+func (h *Handler) insertAttributes(ctx context.Context, pf func(ctx context.Context, query string) (*sql.Stmt, error), id string, isUser bool, attributes []lib_model.DeviceAttribute) error {
 	stmt, err := pf(ctx, "INSERT INTO device_attributes (dev_id, is_usr, key_name, value) VALUES (?, ?, ?, ?);")
 	if err != nil {
 		return lib_model.NewInternalError(err)
@@ -276,30 +489,203 @@ func insertAttributes(ctx context.Context, pf func(ctx context.Context, query st
 	return nil
 }
 
+// fullTextSearch reports whether the search index is available. SQLite builds without FTS5 support skip the
+// migration creating the index and fall back to pattern matching.
+func (h *Handler) fullTextSearch(ctx context.Context) (bool, error) {
+	if h.driver == db.DriverPostgres {
+		return true, nil
+	}
+	h.ftsMu.Lock()
+	defer h.ftsMu.Unlock()
+	if h.fts == nil {
+		var fts bool
+		err := h.db.QueryRowContext(ctx, "SELECT sqlite_compileoption_used('ENABLE_FTS5') AND EXISTS (SELECT 1 FROM sqlite_master WHERE name = 'devices_search');").Scan(&fts)
+		if err != nil {
+			return false, err
+		}
+		h.fts = &fts
+	}
+	return *h.fts, nil
+}
+
+// updateSearchIndex replaces the full-text search entry of a device with its current data.
+func (h *Handler) updateSearchIndex(ctx context.Context, tx *sql.Tx, id string) error {
+	fts, err := h.fullTextSearch(ctx)
+	if err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	if !fts {
+		return nil
+	}
+	if _, err = tx.ExecContext(ctx, h.rebind("DELETE FROM devices_search WHERE dev_id = ?;"), id); err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	q := "INSERT INTO devices_search (dev_id, name, usr_name, type, attributes) SELECT id, name, usr_name, type, (SELECT group_concat(value, ' ') FROM device_attributes WHERE dev_id = devices.id) FROM devices WHERE id = ?;"
+	if h.driver == db.DriverPostgres {
+		q = "INSERT INTO devices_search (dev_id, document) SELECT id, to_tsvector('simple', concat_ws(' ', id, name, usr_name, type, (SELECT string_agg(value, ' ') FROM device_attributes WHERE dev_id = devices.id))) FROM devices WHERE id = ?;"
+	}
+	if _, err = tx.ExecContext(ctx, h.rebind(q), id); err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	return nil
+}
+
+// genSearch returns the from clause for the given filter, a full-text search is joined as 'search' providing the rank.
+// Lower ranks indicate better matches for both drivers. Without full-text search all matches have the same rank.
+// The returned flag reports whether the search has been joined, which is not the case if the search has no tokens.
+func (h *Handler) genSearch(filter lib_model.DevicesFilter, fts bool) (string, []any, bool) {
+	tokens := searchTokens(filter.Search)
+	if len(tokens) == 0 {
+		return devicesFrom, nil, false
+	}
+	if h.driver == db.DriverPostgres {
+		return devicesFrom + " INNER JOIN (SELECT dev_id, -ts_rank(document, query) AS rank FROM devices_search, to_tsquery('simple', ?) AS query WHERE document @@ query) AS search ON devices.id = search.dev_id", []any{genTsQuery(tokens)}, true
+	}
+	if !fts {
+		c, val := genGlobSearch(tokens)
+		return devicesFrom + " INNER JOIN (SELECT d.id AS dev_id, 0 AS rank FROM devices AS d WHERE " + c + ") AS search ON devices.id = search.dev_id", val, true
+	}
+	return devicesFrom + " INNER JOIN (SELECT dev_id, rank FROM devices_search WHERE devices_search MATCH ?) AS search ON devices.id = search.dev_id", []any{genSearchQuery(tokens)}, true
+}
+
+// searchTokens splits a search like the FTS5 tokenizer splits text. A device matches if each token is a prefix of a
+// token in the fields covered by the search index, this applies to all search implementations.
+func searchTokens(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// genGlobSearch returns a condition matching devices with a token starting with each of the given tokens in the fields
+// covered by the search index. Case is only ignored for ASCII letters and other characters count as token separators.
+func genGlobSearch(tokens []string) (string, []any) {
+	var fc []string
+	var val []any
+	for _, token := range tokens {
+		var p string
+		for _, r := range token {
+			if l := unicode.ToLower(r); r <= unicode.MaxASCII && l >= 'a' && l <= 'z' {
+				p += "[" + string(l) + string(unicode.ToUpper(r)) + "]"
+			} else {
+				p += string(r)
+			}
+		}
+		c := "(%[1]s GLOB ? OR %[1]s GLOB ?)"
+		fc = append(fc, "("+fmt.Sprintf(c, "d.id")+" OR "+fmt.Sprintf(c, "d.name")+" OR "+fmt.Sprintf(c, "d.usr_name")+" OR "+fmt.Sprintf(c, "d.type")+" OR EXISTS (SELECT 1 FROM device_attributes WHERE device_attributes.dev_id = d.id AND "+fmt.Sprintf(c, "device_attributes.value")+"))")
+		for i := 0; i < 5; i++ {
+			val = append(val, p+"*", "*[^0-9A-Za-z]"+p+"*")
+		}
+	}
+	return strings.Join(fc, " AND "), val
+}
+
+// genSearchQuery quotes each token to prevent FTS5 query syntax in user input and enables prefix matching.
+func genSearchQuery(tokens []string) string {
+	var terms []string
+	for _, token := range tokens {
+		terms = append(terms, "\""+token+"\"*")
+	}
+	return strings.Join(terms, " ")
+}
+
+// genTsQuery quotes each token as a lexeme to prevent tsquery syntax in user input and enables prefix matching.
+func genTsQuery(tokens []string) string {
+	var terms []string
+	for _, token := range tokens {
+		terms = append(terms, "'"+token+"':*")
+	}
+	return strings.Join(terms, " & ")
+}
+
 func genFilter(filter lib_model.DevicesFilter) (string, []any) {
 	var fc []string
 	var val []any
 	if len(filter.IDs) > 0 {
 		ids := removeDuplicates(filter.IDs)
-		fc = append(fc, "id IN ("+strings.Repeat("?, ", len(ids)-1)+"?)")
+		fc = append(fc, "devices.id IN ("+strings.Repeat("?, ", len(ids)-1)+"?)")
 		for _, id := range ids {
 			val = append(val, id)
 		}
 	}
+	if filter.State != "" {
+		fc = append(fc, stateExpr+" = ?")
+		val = append(val, filter.State)
+	}
 	if filter.Type != "" {
-		fc = append(fc, "type = ?")
+		fc = append(fc, "devices.type = ?")
 		val = append(val, filter.Type)
 	}
 	if filter.Ref != "" {
-		fc = append(fc, "ref = ?")
+		fc = append(fc, "devices.ref = ?")
 		val = append(val, filter.Ref)
 	}
+	if filter.Group != "" {
+		fc = append(fc, "EXISTS (SELECT 1 FROM device_group_members WHERE device_group_members.dev_id = devices.id AND device_group_members.group_id = ?)")
+		val = append(val, filter.Group)
+	}
+	for _, attrFilter := range filter.Attributes {
+		c, v := genAttributeFilter(attrFilter, false)
+		fc = append(fc, c)
+		val = append(val, v...)
+	}
+	for _, attrFilter := range filter.UserAttributes {
+		c, v := genAttributeFilter(attrFilter, true)
+		fc = append(fc, c)
+		val = append(val, v...)
+	}
 	if len(fc) > 0 {
 		return " WHERE " + strings.Join(fc, " AND "), val
 	}
 	return "", nil
 }
 
+func genAttributeFilter(filter lib_model.AttributeFilter, isUsr bool) (string, []any) {
+	c := "EXISTS (SELECT 1 FROM device_attributes WHERE device_attributes.dev_id = devices.id AND device_attributes.is_usr = ? AND device_attributes.key_name = ?"
+	val := []any{isUsr, filter.Key}
+	if filter.Value != nil {
+		c += " AND device_attributes.value = ?"
+		val = append(val, *filter.Value)
+	}
+	return c + ")", val
+}
+
+func (h *Handler) genSortAndLimit(filter lib_model.DevicesFilter, search bool) (string, error) {
+	var sc string
+	if filter.SortBy != "" {
+		col, ok := sortColumns[filter.SortBy]
+		if !ok {
+			return "", fmt.Errorf("invalid sort field '%s'", filter.SortBy)
+		}
+		sc = " ORDER BY " + col
+		if filter.SortDesc {
+			sc += " DESC"
+		}
+		if filter.SortBy != lib_model.SortByID {
+			sc += ", devices.id"
+		}
+	} else if search {
+		sc = " ORDER BY search.rank, devices.id"
+	} else {
+		sc = " ORDER BY devices.id"
+	}
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return "", errors.New("limit and offset must not be negative")
+	}
+	if filter.Limit > 0 {
+		sc += " LIMIT " + strconv.Itoa(filter.Limit)
+	} else if filter.Offset > 0 {
+		if h.driver == db.DriverPostgres {
+			sc += " LIMIT ALL"
+		} else {
+			sc += " LIMIT -1"
+		}
+	}
+	if filter.Offset > 0 {
+		sc += " OFFSET " + strconv.Itoa(filter.Offset)
+	}
+	return sc, nil
+}
+
 func removeDuplicates(sl []string) []string {
 	if len(sl) < 2 {
 		return sl
@@ -319,7 +705,7 @@ func timeToString(t time.Time) string {
 	if t.IsZero() {
 		return ""
 	}
-	return t.Format(tLayout)
+	return t.UTC().Format(tLayoutSortable)
 }
 
 func stringToTime(s string) (time.Time, error) {
@@ -328,3 +714,7 @@ func stringToTime(s string) (time.Time, error) {
 	}
 	return time.Time{}, nil
 }
+
+func (h *Handler) rebind(query string) string {
+	return db.Rebind(h.driver, query)
+}
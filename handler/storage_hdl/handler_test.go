@@ -4,37 +4,104 @@ import (
 	"context"
 	"database/sql"
 	sql_db_hdl "github.com/SENERGY-Platform/go-service-base/sql-db-hdl"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler"
 	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"github.com/SENERGY-Platform/mgw-device-manager/util"
 	"github.com/SENERGY-Platform/mgw-device-manager/util/db"
+	"github.com/google/uuid"
+	"os"
 	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
 
-func initDB(t *testing.T) (*sql.DB, error) {
+// initDB creates a SQLite database in a temporary directory or, if TEST_POSTGRES_DSN is set, a schema in the given
+// PostgreSQL database that is dropped after the test.
+func initDB(t *testing.T) (*sql.DB, string, error) {
+	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
+	if dsn := os.Getenv("TEST_POSTGRES_DSN"); dsn != "" {
+		testDB, err := initPostgres(t, dsn)
+		return testDB, db.DriverPostgres, err
+	}
 	testDB, err := db.New(t.TempDir())
+	if err != nil {
+		return nil, "", err
+	}
+	t.Cleanup(func() {
+		testDB.Close()
+	})
+	migrator, err := db.NewMigrator(db.SQLiteMigrations, db.DriverSQLite)
+	if err != nil {
+		return nil, "", err
+	}
+	if err = sql_db_hdl.InitDB(context.Background(), testDB, "../../include/storage_schema.sql", time.Second*5, time.Second, migrator); err != nil {
+		return nil, "", err
+	}
+	return testDB, db.DriverSQLite, nil
+}
+
+func initPostgres(t *testing.T, dsn string) (*sql.DB, error) {
+	adminDB, err := db.NewPostgres(dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer adminDB.Close()
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err = adminDB.Exec("CREATE SCHEMA " + schema + ";"); err != nil {
+		return nil, err
+	}
+	t.Cleanup(func() {
+		cleanupDB, err := db.NewPostgres(dsn)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer cleanupDB.Close()
+		if _, err = cleanupDB.Exec("DROP SCHEMA " + schema + " CASCADE;"); err != nil {
+			t.Error(err)
+		}
+	})
+	if strings.Contains(dsn, "://") {
+		if strings.Contains(dsn, "?") {
+			dsn += "&search_path=" + schema
+		} else {
+			dsn += "?search_path=" + schema
+		}
+	} else {
+		dsn += " search_path=" + schema
+	}
+	testDB, err := db.NewPostgres(dsn)
+	if err != nil {
+		return nil, err
+	}
+	t.Cleanup(func() {
+		testDB.Close()
+	})
+	migrator, err := db.NewMigrator(db.PostgresMigrations, db.DriverPostgres)
 	if err != nil {
 		return nil, err
 	}
-	if err = sql_db_hdl.InitDB(context.Background(), testDB, "../../include/storage_schema.sql", time.Second*5, time.Second); err != nil {
+	if err = sql_db_hdl.InitDB(context.Background(), testDB, "../../include/storage_schema.sql", time.Second*5, time.Second*5, migrator); err != nil {
 		return nil, err
 	}
 	return testDB, nil
 }
 
 func TestHandler(t *testing.T) {
-	testDB, err := initDB(t)
+	testDB, driver, err := initDB(t)
 	if err != nil {
 		t.Fatal(err)
 	}
-	h := New(testDB)
+	h := New(testDB, driver)
 	t.Run("list devices db empty", func(t *testing.T) {
-		devices, err := h.ReadAll(context.Background(), lib_model.DevicesFilter{})
+		devices, total, err := h.ReadAll(context.Background(), lib_model.DevicesFilter{})
 		if err != nil {
 			t.Error(err)
 		}
-		if len(devices) != 0 {
-			t.Error("expected empty map")
+		if len(devices) != 0 || total != 0 {
+			t.Error("expected empty slice")
 		}
 	})
 	id := "1"
@@ -52,8 +119,8 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			Created: time.Now().Round(0),
-			Updated: time.Now().Round(0),
+			Created: time.Now().UTC(),
+			Updated: time.Now().UTC(),
 		},
 		UserData: lib_model.DeviceUserData{
 			DeviceUserDataBase: lib_model.DeviceUserDataBase{
@@ -65,7 +132,7 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			Updated: time.Now().Round(0),
+			Updated: time.Now().UTC(),
 		},
 	}
 	t.Run("create device", func(t *testing.T) {
@@ -99,8 +166,8 @@ func TestHandler(t *testing.T) {
 		a.Ref = "test2"
 		a.Name = "test2"
 		a.Type = "test2"
-		a.Created = time.Now().Round(0)
-		a.Updated = time.Now().Round(0)
+		a.Created = time.Now().UTC()
+		a.Updated = time.Now().UTC()
 		a.Attributes = append(a.Attributes, lib_model.DeviceAttribute{
 			Key:   "test2",
 			Value: "test2",
@@ -125,7 +192,7 @@ func TestHandler(t *testing.T) {
 	})
 	t.Run("update user data", func(t *testing.T) {
 		a.UserData.Name = "test2"
-		a.UserData.Updated = time.Now().Round(0)
+		a.UserData.Updated = time.Now().UTC()
 		a.UserData.Attributes = []lib_model.DeviceAttribute{
 			{
 				Key:   "test2",
@@ -150,6 +217,118 @@ func TestHandler(t *testing.T) {
 			t.Error("expected error")
 		}
 	})
+	t.Run("update state", func(t *testing.T) {
+		updated := time.Now().UTC()
+		err = h.UpdateState(context.Background(), nil, id, lib_model.DeviceStateTransition{
+			State:     lib_model.Online,
+			Source:    lib_model.StateSrcMessage,
+			Timestamp: updated.Add(-time.Minute),
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		err = h.UpdateState(context.Background(), nil, id, lib_model.DeviceStateTransition{
+			State:     lib_model.Offline,
+			Source:    lib_model.StateSrcLastWill,
+			Timestamp: updated,
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		states, err := h.ReadStates(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		state, ok := states[id]
+		if !ok {
+			t.Fatal("not in map")
+		}
+		if state.Value != lib_model.Offline {
+			t.Error("expected\n", lib_model.Offline, "got\n", state.Value)
+		}
+		if state.Ref != a.Ref {
+			t.Error("expected\n", a.Ref, "got\n", state.Ref)
+		}
+		if !state.Updated.Equal(updated) {
+			t.Error("expected\n", updated, "got\n", state.Updated)
+		}
+	})
+	t.Run("update last seen", func(t *testing.T) {
+		lastSeen := time.Now().UTC()
+		if err = h.UpdateLastSeen(context.Background(), nil, id, lastSeen); err != nil {
+			t.Error(err)
+		}
+		states, err := h.ReadStates(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		if !states[id].LastSeen.Equal(lastSeen) {
+			t.Error("expected\n", lastSeen, "got\n", states[id].LastSeen)
+		}
+		if states[id].Value != lib_model.Offline {
+			t.Error("expected\n", lib_model.Offline, "got\n", states[id].Value)
+		}
+		if states[id].Type != a.Type {
+			t.Error("expected\n", a.Type, "got\n", states[id].Type)
+		}
+	})
+	t.Run("update state device does not exist", func(t *testing.T) {
+		err = h.UpdateState(context.Background(), nil, "2", lib_model.DeviceStateTransition{State: lib_model.Online, Source: lib_model.StateSrcMessage, Timestamp: time.Now()})
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+	t.Run("count devices", func(t *testing.T) {
+		b := a.DeviceData
+		b.ID = "2"
+		if err = h.Create(context.Background(), nil, b); err != nil {
+			t.Fatal(err)
+		}
+		defer h.Delete(context.Background(), nil, b.ID)
+		counts, err := h.CountDevices(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.SortFunc(counts, func(a, b handler.DeviceCount) int {
+			return strings.Compare(a.State, b.State)
+		})
+		expected := []handler.DeviceCount{
+			{State: lib_model.NotAvailable, Type: a.Type, Ref: a.Ref, Count: 1},
+			{State: lib_model.Offline, Type: a.Type, Ref: a.Ref, Count: 1},
+		}
+		if !reflect.DeepEqual(expected, counts) {
+			t.Error("expected\n", expected, "got\n", counts)
+		}
+	})
+	t.Run("read state history", func(t *testing.T) {
+		transitions, err := h.ReadStateHistory(context.Background(), id, lib_model.DeviceStateHistoryFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(transitions) != 2 {
+			t.Fatal("expected 2 entries, got", len(transitions))
+		}
+		if transitions[0].State != lib_model.Online || transitions[1].State != lib_model.Offline {
+			t.Error("wrong order", transitions)
+		}
+		if transitions[1].Source != lib_model.StateSrcLastWill {
+			t.Error("expected\n", lib_model.StateSrcLastWill, "got\n", transitions[1].Source)
+		}
+		transitions, err = h.ReadStateHistory(context.Background(), id, lib_model.DeviceStateHistoryFilter{From: time.Now().Add(-time.Second * 30)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(transitions) != 1 {
+			t.Fatal("expected 1 entry, got", len(transitions))
+		}
+		transitions, err = h.ReadStateHistory(context.Background(), id, lib_model.DeviceStateHistoryFilter{To: time.Now().Add(-time.Second * 30)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(transitions) != 1 || transitions[0].State != lib_model.Online {
+			t.Error("expected 1 entry", transitions)
+		}
+	})
 	t.Run("delete device", func(t *testing.T) {
 		err = h.Delete(context.Background(), nil, id)
 		if err != nil {
@@ -183,4 +362,214 @@ func TestHandler(t *testing.T) {
 			t.Fatal(err)
 		}
 	})
+	t.Run("states table empty", func(t *testing.T) {
+		states, err := h.ReadStates(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(states) != 0 {
+			t.Error("expected empty table")
+		}
+	})
+}
+
+func TestHandler_ReadAll(t *testing.T) {
+	testDB, driver, err := initDB(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(testDB, driver)
+	devices := []lib_model.DeviceData{
+		{DeviceDataBase: lib_model.DeviceDataBase{ID: "1", Ref: "a", Name: "c", Type: "x", Attributes: []lib_model.DeviceAttribute{{Key: "k", Value: "1"}}}, Created: time.Now().UTC()},
+		{DeviceDataBase: lib_model.DeviceDataBase{ID: "2", Ref: "a", Name: "b", Type: "y", Attributes: []lib_model.DeviceAttribute{{Key: "k", Value: "2"}}}, Created: time.Now().UTC()},
+		{DeviceDataBase: lib_model.DeviceDataBase{ID: "3", Ref: "b", Name: "a", Type: "x", Attributes: []lib_model.DeviceAttribute{{Key: "k", Value: "3"}}}, Created: time.Now().UTC()},
+	}
+	for _, device := range devices {
+		if err = h.Create(context.Background(), nil, device); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = h.UpdateState(context.Background(), nil, "2", lib_model.DeviceStateTransition{State: lib_model.Online, Source: lib_model.StateSrcMessage, Timestamp: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, room := range map[string]string{"1": "kitchen", "3": "bath"} {
+		err = h.UpdateUserData(context.Background(), nil, id, lib_model.DeviceUserData{DeviceUserDataBase: lib_model.DeviceUserDataBase{Attributes: []lib_model.DeviceAttribute{{Key: "room", Value: room}}}, Updated: time.Now().UTC()})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	strPtr := func(s string) *string {
+		return &s
+	}
+	ids := func(devices []lib_model.DeviceBase) []string {
+		var l []string
+		for _, device := range devices {
+			l = append(l, device.ID)
+		}
+		return l
+	}
+	tests := []struct {
+		name   string
+		filter lib_model.DevicesFilter
+		ids    []string
+		total  int
+	}{
+		{"all", lib_model.DevicesFilter{}, []string{"1", "2", "3"}, 3},
+		{"sort by name", lib_model.DevicesFilter{SortBy: lib_model.SortByName}, []string{"3", "2", "1"}, 3},
+		{"sort by id desc", lib_model.DevicesFilter{SortBy: lib_model.SortByID, SortDesc: true}, []string{"3", "2", "1"}, 3},
+		{"sort by state", lib_model.DevicesFilter{SortBy: lib_model.SortByState}, []string{"1", "3", "2"}, 3},
+		{"limit", lib_model.DevicesFilter{Limit: 2}, []string{"1", "2"}, 3},
+		{"offset", lib_model.DevicesFilter{Offset: 2}, []string{"3"}, 3},
+		{"limit and offset", lib_model.DevicesFilter{Limit: 1, Offset: 1, SortBy: lib_model.SortByName}, []string{"2"}, 3},
+		{"type", lib_model.DevicesFilter{Type: "x"}, []string{"1", "3"}, 2},
+		{"state", lib_model.DevicesFilter{State: lib_model.Online}, []string{"2"}, 1},
+		{"state n/a", lib_model.DevicesFilter{State: lib_model.NotAvailable, Limit: 1}, []string{"1"}, 2},
+		{"attribute", lib_model.DevicesFilter{Attributes: []lib_model.AttributeFilter{{Key: "k", Value: strPtr("2")}}}, []string{"2"}, 1},
+		{"attribute exists", lib_model.DevicesFilter{Attributes: []lib_model.AttributeFilter{{Key: "k"}}}, []string{"1", "2", "3"}, 3},
+		{"attribute missing", lib_model.DevicesFilter{Attributes: []lib_model.AttributeFilter{{Key: "room"}}}, nil, 0},
+		{"user attribute", lib_model.DevicesFilter{UserAttributes: []lib_model.AttributeFilter{{Key: "room", Value: strPtr("kitchen")}}}, []string{"1"}, 1},
+		{"user attribute exists", lib_model.DevicesFilter{UserAttributes: []lib_model.AttributeFilter{{Key: "room"}}}, []string{"1", "3"}, 2},
+		{"search", lib_model.DevicesFilter{Search: "kitchen"}, []string{"1"}, 1},
+		{"search prefix", lib_model.DevicesFilter{Search: "ba"}, []string{"3"}, 1},
+		{"search multiple terms", lib_model.DevicesFilter{Search: "x a"}, []string{"3"}, 1},
+		{"search quoted", lib_model.DevicesFilter{Search: "\"x"}, []string{"1", "3"}, 2},
+		{"search no match", lib_model.DevicesFilter{Search: "test"}, nil, 0},
+		{"search sort", lib_model.DevicesFilter{Search: "x", SortBy: lib_model.SortByName}, []string{"3", "1"}, 2},
+		{"search punctuation only", lib_model.DevicesFilter{Search: "---"}, []string{"1", "2", "3"}, 3},
+		{"search quote only", lib_model.DevicesFilter{Search: "\""}, []string{"1", "2", "3"}, 3},
+		{"search hyphenated", lib_model.DevicesFilter{Search: "kit-x"}, []string{"1"}, 1},
+		{"search hyphenated no token prefix", lib_model.DevicesFilter{Search: "kit-chen"}, nil, 0},
+		{"search case", lib_model.DevicesFilter{Search: "KITCH"}, []string{"1"}, 1},
+		{"multiple attributes", lib_model.DevicesFilter{Attributes: []lib_model.AttributeFilter{{Key: "k", Value: strPtr("3")}}, UserAttributes: []lib_model.AttributeFilter{{Key: "room", Value: strPtr("kitchen")}}}, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, total, err := h.ReadAll(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ids(res), tt.ids) {
+				t.Error("expected", tt.ids, "got", ids(res))
+			}
+			if total != tt.total {
+				t.Error("expected total", tt.total, "got", total)
+			}
+			for _, device := range res {
+				if len(device.Attributes) != 1 || device.Attributes[0].Value != device.ID {
+					t.Error("invalid attributes", device.Attributes)
+				}
+			}
+		})
+	}
+	t.Run("invalid sort field", func(t *testing.T) {
+		if _, _, err = h.ReadAll(context.Background(), lib_model.DevicesFilter{SortBy: "test"}); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestHandler_ReadAllSortByTime(t *testing.T) {
+	testDB, driver, err := initDB(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(testDB, driver)
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	offsets := map[string]time.Duration{"1": 450 * time.Millisecond, "2": 0, "3": 400 * time.Millisecond, "4": 100 * time.Millisecond}
+	for id, offset := range offsets {
+		device := lib_model.DeviceData{DeviceDataBase: lib_model.DeviceDataBase{ID: id, Ref: "a", Type: "x"}, Created: base.Add(offset), Updated: base.Add(-offset)}
+		if err = h.Create(context.Background(), nil, device); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name   string
+		filter lib_model.DevicesFilter
+		ids    []string
+	}{
+		{"sort by created", lib_model.DevicesFilter{SortBy: lib_model.SortByCreated}, []string{"2", "4", "3", "1"}},
+		{"sort by created desc", lib_model.DevicesFilter{SortBy: lib_model.SortByCreated, SortDesc: true}, []string{"1", "3", "4", "2"}},
+		{"sort by updated", lib_model.DevicesFilter{SortBy: lib_model.SortByUpdated}, []string{"1", "3", "4", "2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, _, err := h.ReadAll(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, device := range res {
+				ids = append(ids, device.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Error("expected", tt.ids, "got", ids)
+			}
+		})
+	}
+}
+
+func TestHandler_Search(t *testing.T) {
+	testDB, driver, err := initDB(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(testDB, driver)
+	a := lib_model.DeviceData{DeviceDataBase: lib_model.DeviceDataBase{ID: "a", Ref: "r", Name: "sensor light switch", Type: "t", Attributes: []lib_model.DeviceAttribute{{Key: "room", Value: "kitchen"}}}}
+	b := lib_model.DeviceData{DeviceDataBase: lib_model.DeviceDataBase{ID: "b", Ref: "r", Name: "kitchen thermostat", Type: "t", Attributes: []lib_model.DeviceAttribute{{Key: "room", Value: "kitchen"}}}}
+	for _, device := range []lib_model.DeviceData{a, b} {
+		if err = h.Create(context.Background(), nil, device); err != nil {
+			t.Fatal(err)
+		}
+	}
+	search := func(s string) []string {
+		res, _, err := h.ReadAll(context.Background(), lib_model.DevicesFilter{Search: s})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var l []string
+		for _, device := range res {
+			l = append(l, device.ID)
+		}
+		return l
+	}
+	t.Run("ranked", func(t *testing.T) {
+		expected := []string{"b", "a"}
+		if fts, err := h.fullTextSearch(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if !fts {
+			expected = []string{"a", "b"}
+		}
+		if ids := search("kitchen"); !reflect.DeepEqual(ids, expected) {
+			t.Error("expected", expected, "got", ids)
+		}
+	})
+	t.Run("update", func(t *testing.T) {
+		a.Attributes = []lib_model.DeviceAttribute{{Key: "room", Value: "garage"}}
+		if err = h.Update(context.Background(), nil, a); err != nil {
+			t.Fatal(err)
+		}
+		if ids := search("kitchen"); !reflect.DeepEqual(ids, []string{"b"}) {
+			t.Error("expected [b] got", ids)
+		}
+		if ids := search("garage"); !reflect.DeepEqual(ids, []string{"a"}) {
+			t.Error("expected [a] got", ids)
+		}
+	})
+	t.Run("update user data", func(t *testing.T) {
+		if err = h.UpdateUserData(context.Background(), nil, "a", lib_model.DeviceUserData{DeviceUserDataBase: lib_model.DeviceUserDataBase{Name: "Ceiling Lamp"}}); err != nil {
+			t.Fatal(err)
+		}
+		if ids := search("ceiling"); !reflect.DeepEqual(ids, []string{"a"}) {
+			t.Error("expected [a] got", ids)
+		}
+	})
+	t.Run("delete", func(t *testing.T) {
+		if err = h.Delete(context.Background(), nil, "b"); err != nil {
+			t.Fatal(err)
+		}
+		if ids := search("kitchen"); len(ids) > 0 {
+			t.Error("expected no results got", ids)
+		}
+	})
 }
@@ -0,0 +1,128 @@
+package storage_hdl
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"time"
+)
+
+const trashColumns = "id, ref, name, type, attributes, created, updated, usr_name, usr_attributes, usr_updated, deleted"
+
+const trashUpdateColumns = "ref = excluded.ref, name = excluded.name, type = excluded.type, attributes = excluded.attributes, created = excluded.created, updated = excluded.updated, usr_name = excluded.usr_name, usr_attributes = excluded.usr_attributes, usr_updated = excluded.usr_updated, deleted = excluded.deleted"
+
+func (h *Handler) CreateTrashItem(ctx context.Context, txItf driver.Tx, device lib_model.DeletedDevice) error {
+	execContext := h.db.ExecContext
+	if txItf != nil {
+		tx := txItf.(*sql.Tx)
+		execContext = tx.ExecContext
+	}
+	attributes, err := json.Marshal(device.Attributes)
+	if err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	usrAttributes, err := json.Marshal(device.UserData.Attributes)
+	if err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	_, err = execContext(ctx, h.rebind("INSERT INTO devices_trash ("+trashColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO UPDATE SET "+trashUpdateColumns+";"), device.ID, device.Ref, device.Name, device.Type, string(attributes), timeToString(device.Created), timeToString(device.Updated), device.UserData.Name, string(usrAttributes), timeToString(device.UserData.Updated), device.Deleted.UTC().Format(tLayoutSortable))
+	if err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	return nil
+}
+
+func (h *Handler) ReadTrashItem(ctx context.Context, id string) (lib_model.DeletedDevice, error) {
+	row := h.db.QueryRowContext(ctx, h.rebind("SELECT "+trashColumns+" FROM devices_trash WHERE id = ?;"), id)
+	device, err := scanTrashItem(row.Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return lib_model.DeletedDevice{}, lib_model.NewNotFoundError(err)
+		}
+		return lib_model.DeletedDevice{}, lib_model.NewInternalError(err)
+	}
+	return device, nil
+}
+
+func (h *Handler) ReadTrash(ctx context.Context) ([]lib_model.DeletedDevice, error) {
+	rows, err := h.db.QueryContext(ctx, h.rebind("SELECT "+trashColumns+" FROM devices_trash ORDER BY deleted DESC, id;"))
+	if err != nil {
+		return nil, lib_model.NewInternalError(err)
+	}
+	defer rows.Close()
+	var devices []lib_model.DeletedDevice
+	for rows.Next() {
+		device, err := scanTrashItem(rows.Scan)
+		if err != nil {
+			return nil, lib_model.NewInternalError(err)
+		}
+		devices = append(devices, device)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, lib_model.NewInternalError(err)
+	}
+	return devices, nil
+}
+
+func (h *Handler) DeleteTrashItem(ctx context.Context, txItf driver.Tx, id string) error {
+	execContext := h.db.ExecContext
+	if txItf != nil {
+		tx := txItf.(*sql.Tx)
+		execContext = tx.ExecContext
+	}
+	res, err := execContext(ctx, h.rebind("DELETE FROM devices_trash WHERE id = ?;"), id)
+	if err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return lib_model.NewInternalError(err)
+	}
+	if n < 1 {
+		return lib_model.NewNotFoundError(errors.New("not found"))
+	}
+	return nil
+}
+
+func (h *Handler) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	res, err := h.db.ExecContext(ctx, h.rebind("DELETE FROM devices_trash WHERE deleted < ?;"), before.UTC().Format(tLayoutSortable))
+	if err != nil {
+		return 0, lib_model.NewInternalError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, lib_model.NewInternalError(err)
+	}
+	return int(n), nil
+}
+
+func scanTrashItem(scan func(dest ...any) error) (lib_model.DeletedDevice, error) {
+	var device lib_model.DeletedDevice
+	var attributes, created, updated, usrAttributes, usrUpdated, deleted string
+	err := scan(&device.ID, &device.Ref, &device.Name, &device.Type, &attributes, &created, &updated, &device.UserData.Name, &usrAttributes, &usrUpdated, &deleted)
+	if err != nil {
+		return lib_model.DeletedDevice{}, err
+	}
+	if err = json.Unmarshal([]byte(attributes), &device.Attributes); err != nil {
+		return lib_model.DeletedDevice{}, err
+	}
+	if err = json.Unmarshal([]byte(usrAttributes), &device.UserData.Attributes); err != nil {
+		return lib_model.DeletedDevice{}, err
+	}
+	if device.Created, err = stringToTime(created); err != nil {
+		return lib_model.DeletedDevice{}, err
+	}
+	if device.Updated, err = stringToTime(updated); err != nil {
+		return lib_model.DeletedDevice{}, err
+	}
+	if device.UserData.Updated, err = stringToTime(usrUpdated); err != nil {
+		return lib_model.DeletedDevice{}, err
+	}
+	if device.Deleted, err = time.Parse(tLayoutSortable, deleted); err != nil {
+		return lib_model.DeletedDevice{}, err
+	}
+	return device, nil
+}
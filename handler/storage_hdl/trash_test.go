@@ -0,0 +1,78 @@
+package storage_hdl
+
+import (
+	"context"
+	lib_model "github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHandler_Trash(t *testing.T) {
+	testDB, driver, err := initDB(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(testDB, driver)
+	a := lib_model.DeletedDevice{
+		DeviceData: lib_model.DeviceData{
+			DeviceDataBase: lib_model.DeviceDataBase{ID: "1", Ref: "a", Name: "test", Type: "x", Attributes: []lib_model.DeviceAttribute{{Key: "k", Value: "v"}}},
+			Created:        time.Now().UTC(),
+		},
+		UserData: lib_model.DeviceUserData{
+			DeviceUserDataBase: lib_model.DeviceUserDataBase{Name: "lamp", Attributes: []lib_model.DeviceAttribute{{Key: "room", Value: "kitchen"}}},
+			Updated:            time.Now().UTC(),
+		},
+		Deleted: time.Now().Add(-time.Hour).UTC(),
+	}
+	b := lib_model.DeletedDevice{
+		DeviceData: lib_model.DeviceData{DeviceDataBase: lib_model.DeviceDataBase{ID: "2", Ref: "a", Type: "x"}, Created: time.Now().UTC()},
+		Deleted:    time.Now().UTC(),
+	}
+	for _, device := range []lib_model.DeletedDevice{a, b} {
+		if err = h.CreateTrashItem(context.Background(), nil, device); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Run("read trash item", func(t *testing.T) {
+		c, err := h.ReadTrashItem(context.Background(), "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(a, c) {
+			t.Error("expected\n", a, "got\n", c)
+		}
+		if _, err = h.ReadTrashItem(context.Background(), "test"); err == nil {
+			t.Error("expected error")
+		}
+	})
+	t.Run("read trash", func(t *testing.T) {
+		devices, err := h.ReadTrash(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(devices) != 2 || devices[0].ID != "2" || devices[1].ID != "1" {
+			t.Error("invalid devices", devices)
+		}
+	})
+	t.Run("purge trash", func(t *testing.T) {
+		n, err := h.PurgeTrash(context.Background(), time.Now().Add(-time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 1 {
+			t.Error("expected 1 purged item, got", n)
+		}
+		if _, err = h.ReadTrashItem(context.Background(), "1"); err == nil {
+			t.Error("not purged")
+		}
+	})
+	t.Run("delete trash item", func(t *testing.T) {
+		if err = h.DeleteTrashItem(context.Background(), nil, "2"); err != nil {
+			t.Fatal(err)
+		}
+		if err = h.DeleteTrashItem(context.Background(), nil, "2"); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
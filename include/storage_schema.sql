@@ -1,20 +1,14 @@
-CREATE TABLE IF NOT EXISTS devices
+CREATE TABLE IF NOT EXISTS schema_version
 (
-    id          TEXT NOT NULL,
-    ref         TEXT NOT NULL,
-    name        TEXT DEFAULT '',
-    type        TEXT NOT NULL,
-    created     TEXT NOT NULL,
-    updated     TEXT DEFAULT '',
-    usr_name    TEXT DEFAULT '',
-    usr_updated TEXT DEFAULT '',
-    PRIMARY KEY (id)
+    version INTEGER NOT NULL,
+    name    TEXT    NOT NULL,
+    applied TEXT    NOT NULL,
+    PRIMARY KEY (version)
 );
-CREATE TABLE IF NOT EXISTS device_attributes
+CREATE TABLE IF NOT EXISTS schema_skipped
 (
-    dev_id   TEXT    NOT NULL,
-    is_usr   INTEGER NOT NULL,
-    key_name TEXT    NOT NULL,
-    value    TEXT DEFAULT '',
-    FOREIGN KEY (dev_id) REFERENCES devices (id) ON DELETE CASCADE ON UPDATE RESTRICT
+    version INTEGER NOT NULL,
+    name    TEXT    NOT NULL,
+    skipped TEXT    NOT NULL,
+    PRIMARY KEY (version)
 );
\ No newline at end of file
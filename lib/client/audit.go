@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+func (c *Client) GetAuditLog(ctx context.Context, filter model.AuditLogFilter) ([]model.AuditEntry, error) {
+	u, err := url.JoinPath(c.baseUrl, model.AuditPath)
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	if filter.DeviceID != "" {
+		q.Set("device", filter.DeviceID)
+	}
+	if !filter.From.IsZero() {
+		q.Set("from", filter.From.Format(time.RFC3339Nano))
+	}
+	if !filter.To.IsZero() {
+		q.Set("to", filter.To.Format(time.RFC3339Nano))
+	}
+	if filter.Limit > 0 {
+		q.Set("limit", strconv.FormatInt(int64(filter.Limit), 10))
+	}
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	req, err := newRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	var entries []model.AuditEntry
+	err = c.execRequestJSONResp(req, &entries)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
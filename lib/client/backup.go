@@ -0,0 +1,30 @@
+package client
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// GetBackup returns a snapshot of the database, the caller must close the reader.
+func (c *Client) GetBackup(ctx context.Context) (io.ReadCloser, int64, error) {
+	u, err := url.JoinPath(c.baseUrl, model.BackupPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	req, err := newRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, model.NewInternalError(err)
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, 0, getError(resp)
+	}
+	return resp.Body, resp.ContentLength, nil
+}
@@ -0,0 +1,34 @@
+package client
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/mgw-device-manager/lib"
+	"net/http"
+)
+
+var _ lib.Api = (*Client)(nil)
+
+type HttpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type Client struct {
+	baseUrl    string
+	httpClient HttpClient
+}
+
+type ctxKey int
+
+const requestIDKey ctxKey = iota
+
+func New(httpClient HttpClient, baseUrl string) *Client {
+	return &Client{
+		baseUrl:    baseUrl,
+		httpClient: httpClient,
+	}
+}
+
+// ContextWithRequestID returns a copy of ctx carrying a request ID, which is sent as X-Request-ID header with every request made with the returned context.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
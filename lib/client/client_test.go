@@ -0,0 +1,172 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClient_GetDevices(t *testing.T) {
+	devices := []model.Device{
+		{
+			DeviceBase: model.DeviceBase{DeviceData: model.DeviceData{DeviceDataBase: model.DeviceDataBase{ID: "1", Ref: "test", Type: "test"}}},
+			State:      model.Online,
+		},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+model.DevicesPath {
+			t.Error("expected", "/"+model.DevicesPath, "got", r.URL.Path)
+		}
+		if q := r.URL.Query().Get("ids"); q != "1,2" {
+			t.Error("expected", "1,2", "got", q)
+		}
+		if q := r.URL.Query().Get("state"); q != model.Online {
+			t.Error("expected", model.Online, "got", q)
+		}
+		if q := r.URL.Query().Get("sort"); q != model.SortByName {
+			t.Error("expected", model.SortByName, "got", q)
+		}
+		if q := r.URL.Query().Get("limit"); q != "10" {
+			t.Error("expected", "10", "got", q)
+		}
+		if h := r.Header.Get(model.HeaderRequestID); h != "test" {
+			t.Error("expected", "test", "got", h)
+		}
+		w.Header().Set(model.HeaderTotalCount, "20")
+		_ = json.NewEncoder(w).Encode(devices)
+	}))
+	defer srv.Close()
+	c := New(http.DefaultClient, srv.URL)
+	res, total, err := c.GetDevices(ContextWithRequestID(context.Background(), "test"), model.DevicesFilter{IDs: []string{"1", "2"}, State: model.Online, SortBy: model.SortByName, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 20 {
+		t.Error("expected", 20, "got", total)
+	}
+	if !reflect.DeepEqual(devices, res) {
+		t.Error("expected", devices, "got", res)
+	}
+}
+
+func TestClient_PatchDeviceUserData(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Error("expected", http.MethodPatch, "got", r.Method)
+		}
+		if h := r.Header.Get("Content-Type"); h != model.ContentTypeUserDataPatch {
+			t.Error("expected", model.ContentTypeUserDataPatch, "got", h)
+		}
+		var members map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&members); err != nil {
+			t.Error(err)
+		}
+		a := map[string]any{"name": "lamp", "attributes": map[string]any{"room": "kitchen", "floor": nil}}
+		if !reflect.DeepEqual(a, members) {
+			t.Error("expected", a, "got", members)
+		}
+	}))
+	defer srv.Close()
+	c := New(http.DefaultClient, srv.URL)
+	name := "lamp"
+	room := "kitchen"
+	err := c.PatchDeviceUserData(context.Background(), "1", "", model.DeviceUserDataPatch{Name: &name, Attributes: map[string]*string{"room": &room, "floor": nil}})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	var sc int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(sc)
+		_, _ = w.Write([]byte("test error"))
+	}))
+	defer srv.Close()
+	c := New(http.DefaultClient, srv.URL)
+	t.Run("not found", func(t *testing.T) {
+		sc = http.StatusNotFound
+		_, err := c.GetDevice(context.Background(), "1")
+		var nfe *model.NotFoundError
+		if !errors.As(err, &nfe) {
+			t.Error("expected NotFoundError, got", err)
+		}
+		if err.Error() != "test error" {
+			t.Error("expected", "test error", "got", err.Error())
+		}
+	})
+	t.Run("invalid input", func(t *testing.T) {
+		sc = http.StatusBadRequest
+		err := c.UpdateDeviceUserData(context.Background(), "1", "", model.DeviceUserDataBase{})
+		var iie *model.InvalidInputError
+		if !errors.As(err, &iie) {
+			t.Error("expected InvalidInputError, got", err)
+		}
+	})
+	t.Run("resource busy", func(t *testing.T) {
+		sc = http.StatusConflict
+		err := c.DeleteDevice(context.Background(), "1", "")
+		var rbe *model.ResourceBusyError
+		if !errors.As(err, &rbe) {
+			t.Error("expected ResourceBusyError, got", err)
+		}
+	})
+	t.Run("precondition failed", func(t *testing.T) {
+		sc = http.StatusPreconditionFailed
+		err := c.DeleteDevice(context.Background(), "1", "\"test\"")
+		var pfe *model.PreconditionFailedError
+		if !errors.As(err, &pfe) {
+			t.Error("expected PreconditionFailedError, got", err)
+		}
+	})
+	t.Run("internal", func(t *testing.T) {
+		sc = http.StatusInternalServerError
+		err := c.DeleteDevice(context.Background(), "1", "")
+		var ie *model.InternalError
+		if !errors.As(err, &ie) {
+			t.Error("expected InternalError, got", err)
+		}
+	})
+}
+
+func TestClient_GetDeviceEvents(t *testing.T) {
+	event := model.DeviceEvent{
+		Type:     model.EventStateChanged,
+		DeviceID: "1",
+		Ref:      "test",
+		State:    model.Online,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p := "/" + model.DevicesPath + "/" + model.EventsPath; r.URL.Path != p {
+			t.Error("expected", p, "got", r.URL.Path)
+		}
+		if q := r.URL.Query().Get("ref"); q != "test" {
+			t.Error("expected", "test", "got", q)
+		}
+		b, _ := json.Marshal(event)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprintf(w, "event:%s\ndata:%s\n\n", event.Type, b)
+	}))
+	defer srv.Close()
+	c := New(http.DefaultClient, srv.URL)
+	events, err := c.GetDeviceEvents(context.Background(), model.DevicesFilter{Ref: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok := <-events
+	if !ok {
+		t.Fatal("channel closed")
+	}
+	if !reflect.DeepEqual(event, e) {
+		t.Error("expected", event, "got", e)
+	}
+	if _, ok = <-events; ok {
+		t.Error("channel not closed")
+	}
+}
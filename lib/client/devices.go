@@ -0,0 +1,197 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (c *Client) GetDevice(ctx context.Context, id string) (model.Device, error) {
+	u, err := url.JoinPath(c.baseUrl, model.DevicesPath, url.PathEscape(id))
+	if err != nil {
+		return model.Device{}, err
+	}
+	req, err := newRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return model.Device{}, err
+	}
+	var device model.Device
+	err = c.execRequestJSONResp(req, &device)
+	if err != nil {
+		return model.Device{}, err
+	}
+	return device, nil
+}
+
+func (c *Client) GetDevices(ctx context.Context, filter model.DevicesFilter) ([]model.Device, int, error) {
+	u, err := url.JoinPath(c.baseUrl, model.DevicesPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	if q := genDevicesQuery(filter); q != "" {
+		u += "?" + q
+	}
+	req, err := newRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	var devices []model.Device
+	header, err := c.execRequestJSONRespHeader(req, &devices)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := strconv.Atoi(header.Get(model.HeaderTotalCount))
+	if err != nil {
+		return nil, 0, model.NewInternalError(err)
+	}
+	return devices, total, nil
+}
+
+func (c *Client) GetDeviceStateHistory(ctx context.Context, id string, filter model.DeviceStateHistoryFilter) ([]model.DeviceStateTransition, error) {
+	u, err := url.JoinPath(c.baseUrl, model.DevicesPath, url.PathEscape(id), model.StateHistoryPath)
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	if !filter.From.IsZero() {
+		q.Set("from", filter.From.Format(time.RFC3339Nano))
+	}
+	if !filter.To.IsZero() {
+		q.Set("to", filter.To.Format(time.RFC3339Nano))
+	}
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	req, err := newRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	var transitions []model.DeviceStateTransition
+	err = c.execRequestJSONResp(req, &transitions)
+	if err != nil {
+		return nil, err
+	}
+	return transitions, nil
+}
+
+func (c *Client) DeleteDevice(ctx context.Context, id, ifMatch string) error {
+	u, err := url.JoinPath(c.baseUrl, model.DevicesPath, url.PathEscape(id))
+	if err != nil {
+		return err
+	}
+	req, err := newRequest(ctx, http.MethodDelete, u, nil)
+	if err != nil {
+		return err
+	}
+	if ifMatch != "" {
+		req.Header.Set(model.HeaderIfMatch, ifMatch)
+	}
+	return c.execRequest(req)
+}
+
+func (c *Client) UpdateDeviceUserData(ctx context.Context, id, ifMatch string, userDataBase model.DeviceUserDataBase) error {
+	u, err := url.JoinPath(c.baseUrl, model.DevicesPath, url.PathEscape(id))
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(userDataBase)
+	if err != nil {
+		return err
+	}
+	req, err := newRequest(ctx, http.MethodPatch, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if ifMatch != "" {
+		req.Header.Set(model.HeaderIfMatch, ifMatch)
+	}
+	return c.execRequest(req)
+}
+
+// PatchDeviceUserData changes individual fields of the user data via a user data patch.
+func (c *Client) PatchDeviceUserData(ctx context.Context, id, ifMatch string, patch model.DeviceUserDataPatch) error {
+	u, err := url.JoinPath(c.baseUrl, model.DevicesPath, url.PathEscape(id))
+	if err != nil {
+		return err
+	}
+	if patch.ClearAttributes && len(patch.Attributes) > 0 {
+		return errors.New("clearing attributes can not be combined with attribute changes")
+	}
+	members := make(map[string]any)
+	if patch.Name != nil {
+		members["name"] = *patch.Name
+	}
+	if patch.ClearAttributes {
+		members["attributes"] = nil
+	} else if len(patch.Attributes) > 0 {
+		members["attributes"] = patch.Attributes
+	}
+	body, err := json.Marshal(members)
+	if err != nil {
+		return err
+	}
+	req, err := newRequest(ctx, http.MethodPatch, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", model.ContentTypeUserDataPatch)
+	if ifMatch != "" {
+		req.Header.Set(model.HeaderIfMatch, ifMatch)
+	}
+	return c.execRequest(req)
+}
+
+func genDevicesQuery(filter model.DevicesFilter) string {
+	q := url.Values{}
+	if len(filter.IDs) > 0 {
+		q.Set("ids", strings.Join(filter.IDs, ","))
+	}
+	if filter.State != "" {
+		q.Set("state", filter.State)
+	}
+	if filter.Type != "" {
+		q.Set("type", filter.Type)
+	}
+	if filter.Ref != "" {
+		q.Set("ref", filter.Ref)
+	}
+	for _, attrFilter := range filter.Attributes {
+		q.Add("attr", encodeAttributeFilter(attrFilter))
+	}
+	for _, attrFilter := range filter.UserAttributes {
+		q.Add("usr_attr", encodeAttributeFilter(attrFilter))
+	}
+	if filter.Group != "" {
+		q.Set("group", filter.Group)
+	}
+	if filter.Search != "" {
+		q.Set("search", filter.Search)
+	}
+	if filter.SortBy != "" {
+		q.Set("sort", filter.SortBy)
+	}
+	if filter.SortDesc {
+		q.Set("order", "desc")
+	}
+	if filter.Limit > 0 {
+		q.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	if filter.Offset > 0 {
+		q.Set("offset", strconv.Itoa(filter.Offset))
+	}
+	return q.Encode()
+}
+
+func encodeAttributeFilter(filter model.AttributeFilter) string {
+	if filter.Value != nil {
+		return filter.Key + ":" + *filter.Value
+	}
+	return filter.Key
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"net/http"
+	"net/url"
+)
+
+// GetDeviceEvents opens a server-sent events stream, the returned channel is closed when ctx is done or the stream ends.
+func (c *Client) GetDeviceEvents(ctx context.Context, filter model.DevicesFilter) (<-chan model.DeviceEvent, error) {
+	u, err := url.JoinPath(c.baseUrl, model.DevicesPath, model.EventsPath)
+	if err != nil {
+		return nil, err
+	}
+	if q := genDevicesQuery(filter); q != "" {
+		u += "?" + q
+	}
+	req, err := newRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, model.NewInternalError(err)
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, getError(resp)
+	}
+	events := make(chan model.DeviceEvent)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		var data []byte
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if len(line) == 0 {
+				if len(data) == 0 {
+					continue
+				}
+				var event model.DeviceEvent
+				err := json.Unmarshal(data, &event)
+				data = data[:0]
+				if err != nil {
+					continue
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+			if v, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+				if len(data) > 0 {
+					data = append(data, '\n')
+				}
+				data = append(data, bytes.TrimPrefix(v, []byte(" "))...)
+			}
+		}
+	}()
+	return events, nil
+}
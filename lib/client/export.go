@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"net/http"
+	"net/url"
+)
+
+func (c *Client) Export(ctx context.Context) (model.Export, error) {
+	u, err := url.JoinPath(c.baseUrl, model.ExportPath)
+	if err != nil {
+		return model.Export{}, err
+	}
+	req, err := newRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return model.Export{}, err
+	}
+	var export model.Export
+	err = c.execRequestJSONResp(req, &export)
+	if err != nil {
+		return model.Export{}, err
+	}
+	return export, nil
+}
+
+func (c *Client) Import(ctx context.Context, mode model.ImportMode, export model.Export) (model.ImportResult, error) {
+	u, err := url.JoinPath(c.baseUrl, model.ImportPath)
+	if err != nil {
+		return model.ImportResult{}, err
+	}
+	body, err := json.Marshal(export)
+	if err != nil {
+		return model.ImportResult{}, err
+	}
+	req, err := newRequest(ctx, http.MethodPost, u+"?"+url.Values{"mode": {mode}}.Encode(), body)
+	if err != nil {
+		return model.ImportResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var result model.ImportResult
+	err = c.execRequestJSONResp(req, &result)
+	if err != nil {
+		return model.ImportResult{}, err
+	}
+	return result, nil
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+func (c *Client) GetGroups(ctx context.Context) ([]model.Group, error) {
+	u, err := url.JoinPath(c.baseUrl, model.GroupsPath)
+	if err != nil {
+		return nil, err
+	}
+	req, err := newRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	var groups []model.Group
+	err = c.execRequestJSONResp(req, &groups)
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (c *Client) GetGroup(ctx context.Context, id string) (model.Group, error) {
+	u, err := url.JoinPath(c.baseUrl, model.GroupsPath, url.PathEscape(id))
+	if err != nil {
+		return model.Group{}, err
+	}
+	req, err := newRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return model.Group{}, err
+	}
+	var group model.Group
+	err = c.execRequestJSONResp(req, &group)
+	if err != nil {
+		return model.Group{}, err
+	}
+	return group, nil
+}
+
+func (c *Client) CreateGroup(ctx context.Context, groupBase model.GroupBase) (string, error) {
+	u, err := url.JoinPath(c.baseUrl, model.GroupsPath)
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(groupBase)
+	if err != nil {
+		return "", err
+	}
+	req, err := newRequest(ctx, http.MethodPost, u, body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", model.NewInternalError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return "", getError(resp)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", model.NewInternalError(err)
+	}
+	return string(b), nil
+}
+
+func (c *Client) UpdateGroup(ctx context.Context, id string, groupBase model.GroupBase) error {
+	u, err := url.JoinPath(c.baseUrl, model.GroupsPath, url.PathEscape(id))
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(groupBase)
+	if err != nil {
+		return err
+	}
+	req, err := newRequest(ctx, http.MethodPut, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.execRequest(req)
+}
+
+func (c *Client) DeleteGroup(ctx context.Context, id string) error {
+	u, err := url.JoinPath(c.baseUrl, model.GroupsPath, url.PathEscape(id))
+	if err != nil {
+		return err
+	}
+	req, err := newRequest(ctx, http.MethodDelete, u, nil)
+	if err != nil {
+		return err
+	}
+	return c.execRequest(req)
+}
+
+func (c *Client) GetGroupDevices(ctx context.Context, id string) ([]model.Device, error) {
+	u, err := url.JoinPath(c.baseUrl, model.GroupsPath, url.PathEscape(id), model.DevicesPath)
+	if err != nil {
+		return nil, err
+	}
+	req, err := newRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	var devices []model.Device
+	err = c.execRequestJSONResp(req, &devices)
+	if err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+func (c *Client) SetGroupDevices(ctx context.Context, id string, deviceIDs []string) error {
+	u, err := url.JoinPath(c.baseUrl, model.GroupsPath, url.PathEscape(id), model.DevicesPath)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(deviceIDs)
+	if err != nil {
+		return err
+	}
+	req, err := newRequest(ctx, http.MethodPut, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.execRequest(req)
+}
+
+func (c *Client) AddGroupDevice(ctx context.Context, id, deviceID string) error {
+	u, err := url.JoinPath(c.baseUrl, model.GroupsPath, url.PathEscape(id), model.DevicesPath, url.PathEscape(deviceID))
+	if err != nil {
+		return err
+	}
+	req, err := newRequest(ctx, http.MethodPut, u, nil)
+	if err != nil {
+		return err
+	}
+	return c.execRequest(req)
+}
+
+func (c *Client) RemoveGroupDevice(ctx context.Context, id, deviceID string) error {
+	u, err := url.JoinPath(c.baseUrl, model.GroupsPath, url.PathEscape(id), model.DevicesPath, url.PathEscape(deviceID))
+	if err != nil {
+		return err
+	}
+	req, err := newRequest(ctx, http.MethodDelete, u, nil)
+	if err != nil {
+		return err
+	}
+	return c.execRequest(req)
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"net/http"
+	"net/url"
+)
+
+func (c *Client) GetRefs(ctx context.Context) ([]model.RefInfo, error) {
+	u, err := url.JoinPath(c.baseUrl, model.RefsPath)
+	if err != nil {
+		return nil, err
+	}
+	req, err := newRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	var refs []model.RefInfo
+	err = c.execRequestJSONResp(req, &refs)
+	if err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+func (c *Client) DeleteRefDevices(ctx context.Context, ref string) error {
+	u, err := url.JoinPath(c.baseUrl, model.RefsPath, url.PathEscape(ref), model.DevicesPath)
+	if err != nil {
+		return err
+	}
+	req, err := newRequest(ctx, http.MethodDelete, u, nil)
+	if err != nil {
+		return err
+	}
+	return c.execRequest(req)
+}
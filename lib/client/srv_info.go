@@ -0,0 +1,25 @@
+package client
+
+import (
+	"context"
+	srv_info_lib "github.com/SENERGY-Platform/go-service-base/srv-info-hdl/lib"
+	"github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"net/http"
+	"net/url"
+)
+
+func (c *Client) GetSrvInfo(ctx context.Context) srv_info_lib.SrvInfo {
+	u, err := url.JoinPath(c.baseUrl, model.SrvInfoPath)
+	if err != nil {
+		return srv_info_lib.SrvInfo{}
+	}
+	req, err := newRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return srv_info_lib.SrvInfo{}
+	}
+	var info srv_info_lib.SrvInfo
+	if err = c.execRequestJSONResp(req, &info); err != nil {
+		return srv_info_lib.SrvInfo{}
+	}
+	return info
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"net/http"
+	"net/url"
+)
+
+func (c *Client) GetTrash(ctx context.Context) ([]model.DeletedDevice, error) {
+	u, err := url.JoinPath(c.baseUrl, model.TrashPath)
+	if err != nil {
+		return nil, err
+	}
+	req, err := newRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	var devices []model.DeletedDevice
+	err = c.execRequestJSONResp(req, &devices)
+	if err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+func (c *Client) RestoreDevice(ctx context.Context, id string) error {
+	u, err := url.JoinPath(c.baseUrl, model.TrashPath, url.PathEscape(id), model.RestorePath)
+	if err != nil {
+		return err
+	}
+	req, err := newRequest(ctx, http.MethodPost, u, nil)
+	if err != nil {
+		return err
+	}
+	return c.execRequest(req)
+}
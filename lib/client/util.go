@@ -0,0 +1,80 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"io"
+	"net/http"
+	"strings"
+)
+
+func newRequest(ctx context.Context, method, url string, body []byte) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, r)
+	if err != nil {
+		return nil, model.NewInternalError(err)
+	}
+	if rID, ok := ctx.Value(requestIDKey).(string); ok && rID != "" {
+		req.Header.Set(model.HeaderRequestID, rID)
+	}
+	return req, nil
+}
+
+func (c *Client) execRequest(req *http.Request) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return model.NewInternalError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return getError(resp)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (c *Client) execRequestJSONResp(req *http.Request, v any) error {
+	_, err := c.execRequestJSONRespHeader(req, v)
+	return err
+}
+
+func (c *Client) execRequestJSONRespHeader(req *http.Request, v any) (http.Header, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, model.NewInternalError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, getError(resp)
+	}
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return nil, model.NewInternalError(err)
+	}
+	return resp.Header, nil
+}
+
+func getError(resp *http.Response) error {
+	b, err := io.ReadAll(resp.Body)
+	if err != nil || len(b) == 0 {
+		b = []byte(resp.Status)
+	}
+	e := errors.New(strings.TrimSpace(string(b)))
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return model.NewNotFoundError(e)
+	case http.StatusBadRequest:
+		return model.NewInvalidInputError(e)
+	case http.StatusConflict:
+		return model.NewResourceBusyError(e)
+	case http.StatusPreconditionFailed:
+		return model.NewPreconditionFailedError(e)
+	default:
+		return model.NewInternalError(e)
+	}
+}
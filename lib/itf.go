@@ -4,12 +4,33 @@ import (
 	"context"
 	srv_info_lib "github.com/SENERGY-Platform/go-service-base/srv-info-hdl/lib"
 	"github.com/SENERGY-Platform/mgw-device-manager/lib/model"
+	"io"
 )
 
 type Api interface {
 	GetDevice(ctx context.Context, id string) (model.Device, error)
-	GetDevices(ctx context.Context, filter model.DevicesFilter) (map[string]model.Device, error)
-	DeleteDevice(ctx context.Context, id string) error
-	UpdateDeviceUserData(ctx context.Context, id string, userDataBase model.DeviceUserDataBase) error
+	GetDevices(ctx context.Context, filter model.DevicesFilter) ([]model.Device, int, error)
+	DeleteDevice(ctx context.Context, id, ifMatch string) error
+	UpdateDeviceUserData(ctx context.Context, id, ifMatch string, userDataBase model.DeviceUserDataBase) error
+	PatchDeviceUserData(ctx context.Context, id, ifMatch string, patch model.DeviceUserDataPatch) error
+	GetDeviceStateHistory(ctx context.Context, id string, filter model.DeviceStateHistoryFilter) ([]model.DeviceStateTransition, error)
+	GetDeviceEvents(ctx context.Context, filter model.DevicesFilter) (<-chan model.DeviceEvent, error)
+	GetRefs(ctx context.Context) ([]model.RefInfo, error)
+	DeleteRefDevices(ctx context.Context, ref string) error
+	GetTrash(ctx context.Context) ([]model.DeletedDevice, error)
+	RestoreDevice(ctx context.Context, id string) error
+	GetAuditLog(ctx context.Context, filter model.AuditLogFilter) ([]model.AuditEntry, error)
+	Export(ctx context.Context) (model.Export, error)
+	Import(ctx context.Context, mode model.ImportMode, export model.Export) (model.ImportResult, error)
+	GetBackup(ctx context.Context) (io.ReadCloser, int64, error)
+	GetGroups(ctx context.Context) ([]model.Group, error)
+	GetGroup(ctx context.Context, id string) (model.Group, error)
+	CreateGroup(ctx context.Context, groupBase model.GroupBase) (string, error)
+	UpdateGroup(ctx context.Context, id string, groupBase model.GroupBase) error
+	DeleteGroup(ctx context.Context, id string) error
+	GetGroupDevices(ctx context.Context, id string) ([]model.Device, error)
+	SetGroupDevices(ctx context.Context, id string, deviceIDs []string) error
+	AddGroupDevice(ctx context.Context, id, deviceID string) error
+	RemoveGroupDevice(ctx context.Context, id, deviceID string) error
 	srv_info_lib.Api
 }
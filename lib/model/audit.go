@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+type AuditAction = string
+
+type AuditEntry struct {
+	Timestamp time.Time       `json:"timestamp"`
+	RequestID string          `json:"request_id"`
+	Caller    string          `json:"caller"`
+	Action    AuditAction     `json:"action"`
+	DeviceID  string          `json:"device_id"`
+	Before    *DeviceUserData `json:"before"` // user data before the change, nil if the device did not exist
+	After     *DeviceUserData `json:"after"`  // user data after the change, nil if the device was deleted
+}
+
+type AuditLogFilter struct {
+	DeviceID string
+	From     time.Time
+	To       time.Time
+	Limit    int
+}
@@ -4,20 +4,80 @@ const (
 	Online       DeviceState = "online"
 	Offline      DeviceState = "offline"
 	NotAvailable DeviceState = "n/a"
+	Stale        DeviceState = "stale"
 )
 
 const (
-	Set    DeviceMethod = "set"
-	Delete DeviceMethod = "delete"
+	StateSrcMessage  DeviceStateSource = "message"
+	StateSrcLastWill DeviceStateSource = "last_will"
+	StateSrcRefresh  DeviceStateSource = "refresh"
+	StateSrcTTL      DeviceStateSource = "ttl"
+	StateSrcSync     DeviceStateSource = "sync"
 )
 
 const (
-	DevicesPath = "devices"
-	SrvInfoPath = "info"
+	EventCreated      DeviceEventType = "created"
+	EventUpdated      DeviceEventType = "updated"
+	EventDeleted      DeviceEventType = "deleted"
+	EventStateChanged DeviceEventType = "state_changed"
 )
 
 const (
-	HeaderRequestID = "X-Request-ID"
-	HeaderApiVer    = "X-Api-Version"
-	HeaderSrvName   = "X-Service"
+	SortByID       DevicesSortField = "id"
+	SortByName     DevicesSortField = "name"
+	SortByUserName DevicesSortField = "user_name"
+	SortByType     DevicesSortField = "type"
+	SortByCreated  DevicesSortField = "created"
+	SortByUpdated  DevicesSortField = "updated"
+	SortByState    DevicesSortField = "state"
+)
+
+const (
+	AuditSetUserData AuditAction = "set_user_data"
+	AuditDelete      AuditAction = "delete"
+	AuditRestore     AuditAction = "restore"
+)
+
+const (
+	Set       DeviceMethod = "set"
+	Delete    DeviceMethod = "delete"
+	SyncBegin DeviceMethod = "sync_begin"
+	SyncEnd   DeviceMethod = "sync_end"
+)
+
+const (
+	DevicesPath      = "devices"
+	SrvInfoPath      = "info"
+	StateHistoryPath = "state-history"
+	EventsPath       = "events"
+	RefsPath         = "refs"
+	GroupsPath       = "groups"
+	TrashPath        = "trash"
+	RestorePath      = "restore"
+	AuditPath        = "audit"
+	ExportPath       = "export"
+	ImportPath       = "import"
+	BackupPath       = "backup"
+)
+
+const (
+	ImportUserData ImportMode = "user_data" // apply user data to existing devices
+	ImportMerge    ImportMode = "merge"     // create missing and update existing devices
+	ImportReplace  ImportMode = "replace"   // like ImportMerge and delete devices not present in the import
+)
+
+const (
+	ContentTypeMergePatch    = "application/merge-patch+json"
+	ContentTypeUserDataPatch = "application/vnd.mgw-device-manager.user-data-patch+json" // see DeviceUserDataPatch
+	ContentTypeCSV           = "text/csv"
+	ContentTypeSQLite        = "application/vnd.sqlite3"
+)
+
+const (
+	HeaderRequestID  = "X-Request-ID"
+	HeaderApiVer     = "X-Api-Version"
+	HeaderSrvName    = "X-Service"
+	HeaderTotalCount = "X-Total-Count"
+	HeaderETag       = "ETag"
+	HeaderIfMatch    = "If-Match"
 )
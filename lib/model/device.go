@@ -1,19 +1,29 @@
 package model
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
 
 type DeviceState = string
 
 type DeviceMethod = string
 
+type DeviceStateSource = string
+
 type Device struct {
 	DeviceBase
-	State DeviceState `json:"state"`
+	State          DeviceState `json:"state"`
+	StateUpdated   time.Time   `json:"state_updated"`
+	StateLastKnown bool        `json:"state_last_known"` // true if the state was restored from storage and not yet confirmed by the connector
+	LastSeen       time.Time   `json:"last_seen"`
 }
 
 type DeviceBase struct {
 	DeviceData
 	UserData DeviceUserData `json:"user_data,omitempty"`
+	Groups   []string       `json:"groups"` // ids of the groups the device is a member of
 }
 
 type DeviceData struct {
@@ -40,14 +50,56 @@ type DeviceUserData struct {
 	Updated time.Time `json:"updated"`
 }
 
+// DeviceUserDataPatch holds the changes of a user data patch sent as ContentTypeUserDataPatch. The patch is modelled on
+// JSON merge patch (RFC 7396) but attributes are addressed by key instead of replacing the attributes array:
+// {"name": "lamp", "attributes": {"room": "kitchen", "floor": null}}. A null name clears the name, null attributes
+// remove all attributes and other members are rejected.
+type DeviceUserDataPatch struct {
+	Name            *string            // nil leaves the name unchanged
+	Attributes      map[string]*string // nil values remove attributes
+	ClearAttributes bool               // remove all attributes, can not be combined with Attributes
+}
+
 type DeviceAttribute struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
 }
 
+type DeviceStateTransition struct {
+	State     DeviceState       `json:"state"`
+	Source    DeviceStateSource `json:"source"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+type DeviceStateHistoryFilter struct {
+	From time.Time
+	To   time.Time
+}
+
+type DevicesSortField = string
+
 type DevicesFilter struct {
-	IDs   []string
-	State string
-	Type  string
-	Ref   string
+	IDs            []string
+	State          string
+	Type           string
+	Ref            string
+	Attributes     []AttributeFilter
+	UserAttributes []AttributeFilter
+	Group          string
+	Search         string // full-text search, results are ordered by relevance if no sort field is set
+	SortBy         DevicesSortField
+	SortDesc       bool
+	Limit          int
+	Offset         int
+}
+
+type AttributeFilter struct {
+	Key   string
+	Value *string // if nil only the existence of the key is checked
+}
+
+// DeviceETag returns a strong entity tag derived from the creation and update timestamps of a device.
+func DeviceETag(device DeviceBase) string {
+	sum := sha256.Sum256([]byte(device.Created.UTC().Format(time.RFC3339Nano) + "|" + device.Updated.UTC().Format(time.RFC3339Nano) + "|" + device.UserData.Updated.UTC().Format(time.RFC3339Nano)))
+	return "\"" + hex.EncodeToString(sum[:8]) + "\""
 }
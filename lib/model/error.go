@@ -20,6 +20,10 @@ type ResourceBusyError struct {
 	cError
 }
 
+type PreconditionFailedError struct {
+	cError
+}
+
 func (e *cError) Error() string {
 	return e.err.Error()
 }
@@ -43,3 +47,7 @@ func NewInvalidInputError(err error) error {
 func NewResourceBusyError(err error) error {
 	return &ResourceBusyError{cError{err: err}}
 }
+
+func NewPreconditionFailedError(err error) error {
+	return &PreconditionFailedError{cError{err: err}}
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+type DeviceEventType = string
+
+type DeviceEvent struct {
+	Type       DeviceEventType `json:"type"`
+	DeviceID   string          `json:"device_id"`
+	Ref        string          `json:"ref"`
+	DeviceType string          `json:"device_type"`
+	State      DeviceState     `json:"state"`
+	Device     *Device         `json:"device,omitempty"` // not set for state_changed events
+	Timestamp  time.Time       `json:"timestamp"`
+}
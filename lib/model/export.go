@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+type ImportMode = string
+
+// ExportVersion is the version of the export document format, imports of other versions are rejected.
+const ExportVersion = 1
+
+type Export struct {
+	Version int            `json:"version"`
+	Created time.Time      `json:"created"`
+	Devices []ExportDevice `json:"devices"`
+}
+
+type ExportDevice struct {
+	DeviceData
+	UserData DeviceUserData `json:"user_data"`
+}
+
+type ImportResult struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Deleted int `json:"deleted"`
+	Skipped int `json:"skipped"` // devices not present in storage if the mode is ImportUserData
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+type Group struct {
+	ID string `json:"id"`
+	GroupBase
+	DeviceIDs []string  `json:"device_ids"`
+	Created   time.Time `json:"created"`
+	Updated   time.Time `json:"updated"`
+}
+
+type GroupBase struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
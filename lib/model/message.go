@@ -12,3 +12,18 @@ type DeviceMessageData struct {
 	Type       string            `json:"device_type"`
 	Attributes []DeviceAttribute `json:"attributes"`
 }
+
+// BatchResult is published for each batch of device messages. Error is set if the batch could not be applied at all,
+// otherwise Errors holds an entry for each message that failed.
+type BatchResult struct {
+	Applied int              `json:"applied"`
+	Failed  int              `json:"failed"`
+	Error   string           `json:"error,omitempty"`
+	Errors  []BatchItemError `json:"errors,omitempty"`
+}
+
+type BatchItemError struct {
+	Index    int    `json:"index"`
+	DeviceID string `json:"device_id"`
+	Error    string `json:"error"`
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+type RefInfo struct {
+	Ref          string              `json:"ref"`
+	Devices      int                 `json:"devices"`
+	States       map[DeviceState]int `json:"states"`
+	Types        map[string]int      `json:"types"`
+	LastActivity time.Time           `json:"last_activity"`
+	LastWill     bool                `json:"last_will"` // true if the last will fired and no device message has been received since
+}
@@ -0,0 +1,9 @@
+package model
+
+import "time"
+
+type DeletedDevice struct {
+	DeviceData
+	UserData DeviceUserData `json:"user_data"`
+	Deleted  time.Time      `json:"deleted"`
+}
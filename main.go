@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/SENERGY-Platform/gin-middleware"
@@ -12,9 +13,15 @@ import (
 	sb_util "github.com/SENERGY-Platform/go-service-base/util"
 	"github.com/SENERGY-Platform/go-service-base/watchdog"
 	"github.com/SENERGY-Platform/mgw-device-manager/api"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler/backup_hdl"
 	"github.com/SENERGY-Platform/mgw-device-manager/handler/devices_hdl"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler/events_hdl"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler/groups_hdl"
 	"github.com/SENERGY-Platform/mgw-device-manager/handler/http_hdl"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler/mem_storage_hdl"
 	"github.com/SENERGY-Platform/mgw-device-manager/handler/message_hdl"
+	"github.com/SENERGY-Platform/mgw-device-manager/handler/metrics_hdl"
 	"github.com/SENERGY-Platform/mgw-device-manager/handler/mqtt_hdl"
 	"github.com/SENERGY-Platform/mgw-device-manager/handler/msg_relay_hdl"
 	"github.com/SENERGY-Platform/mgw-device-manager/handler/storage_hdl"
@@ -25,6 +32,7 @@ import (
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/gin-contrib/requestid"
 	"github.com/gin-gonic/gin"
+	"io/fs"
 	"net"
 	"net/http"
 	"net/url"
@@ -36,6 +44,14 @@ import (
 
 var version string
 
+type storageHandler interface {
+	handler.DevicesStorageHandler
+	handler.DeviceStatesStorageHandler
+	handler.DeviceTrashStorageHandler
+	handler.DeviceAuditStorageHandler
+	handler.GroupsStorageHandler
+}
+
 func main() {
 	srvInfoHdl := srv_info_hdl.New("device-manager", version)
 
@@ -78,21 +94,86 @@ func main() {
 	}
 
 	sql_db_hdl.Logger = util.Logger
-	db, err := db.New(config.Database.Path)
-	if err != nil {
-		util.Logger.Error(err)
+	var migrations fs.FS
+	switch config.Database.Driver {
+	case db.DriverSQLite:
+		migrations = db.SQLiteMigrations
+	case db.DriverPostgres:
+		migrations = db.PostgresMigrations
+	case db.DriverMemory:
+	default:
+		util.Logger.Errorf("unsupported database driver '%s'", config.Database.Driver)
 		ec = 1
 		return
 	}
-	defer db.Close()
+	var migrator *db.Migrator
+	if migrations != nil {
+		if migrator, err = db.NewMigrator(migrations, config.Database.Driver); err != nil {
+			util.Logger.Error(err)
+			ec = 1
+			return
+		}
+	}
+	isSQLite := config.Database.Driver == db.DriverSQLite
+	if isSQLite && config.Database.RestorePath != "" {
+		if _, err = os.Stat(config.Database.RestorePath); err == nil {
+			util.Logger.Infof("restoring database from '%s'", config.Database.RestorePath)
+			if err = db.Restore(config.Database.Path, config.Database.RestorePath, migrator.Latest()); err != nil {
+				util.Logger.Errorf("restore database: %s", err)
+				ec = 1
+				return
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			util.Logger.Error(err)
+			ec = 1
+			return
+		}
+	}
+	var sqlDB *sql.DB
+	var storageHdl storageHandler
+	if migrator != nil {
+		if sqlDB, err = openDB(config.Database); err != nil {
+			util.Logger.Error(err)
+			ec = 1
+			return
+		}
+		defer sqlDB.Close()
+		storageHdl = storage_hdl.New(sqlDB, config.Database.Driver)
+	} else {
+		util.Logger.Warning("using in-memory storage, data is lost on shutdown")
+		storageHdl = mem_storage_hdl.New()
+	}
 
-	deviceHdl := devices_hdl.New(storage_hdl.New(db), time.Duration(config.Database.Timeout))
+	metricsHdl := metrics_hdl.New()
+
+	eventsHdl := events_hdl.New(config.EventBuffer)
+
+	devicesStgHdl := metricsHdl.StorageHandler(storageHdl)
+
+	deviceHdl := devices_hdl.New(devicesStgHdl, time.Duration(config.Database.Timeout))
+	deviceHdl.SetEventsHandler(eventsHdl)
+	deviceHdl.SetSyncDelete(config.SyncDelete)
+
+	groupsHdl := groups_hdl.New(metricsHdl.GroupsStorageHandler(storageHdl), devicesStgHdl, time.Duration(config.Database.Timeout))
 
 	messageHdl := message_hdl.New(deviceHdl)
 
-	messageRelayHdl := msg_relay_hdl.New(config.MessageBuffer, messageHdl.HandleMessage)
+	messageRelayHdl := msg_relay_hdl.New(config.MessageBuffer, config.MessageWorkers, metricsHdl.MessageHandler(messageHdl.HandleMessage), messageHdl.MessageKey)
 
-	mqttHdl := mqtt_hdl.New(config.MqttClient.QOSLevel, messageRelayHdl)
+	if config.MessageQueue != "" {
+		messageQueue, err := msg_relay_hdl.NewQueue(config.MessageQueue)
+		if err != nil {
+			util.Logger.Error(err)
+			ec = 1
+			return
+		}
+		defer messageQueue.Close()
+		messageRelayHdl.SetQueue(messageQueue)
+		metricsHdl.RegisterMessageQueue(messageQueue.Len)
+	}
+
+	mqttHdl := mqtt_hdl.New(config.MqttClient.QOSLevel, metricsHdl.MessageRelayHandler(messageRelayHdl, messageHdl.MessageTopic))
+	messageHdl.SetBatchResultPublisher(mqttHdl)
 
 	mqttClientOpt := mqtt.NewClientOptions()
 	mqttClientOpt.SetConnectionAttemptHandler(func(_ *url.URL, tlsCfg *tls.Config) *tls.Config {
@@ -111,7 +192,26 @@ func main() {
 
 	mqttHdl.SetMqttClient(mqttClient)
 
-	mApi := api.New(deviceHdl, srvInfoHdl)
+	metricsHdl.RegisterDevices(deviceHdl.CountDevices)
+	metricsHdl.RegisterMessageBuffer(messageRelayHdl.Len, messageRelayHdl.Cap)
+	metricsHdl.RegisterMqttConnection(mqttClient.IsConnected)
+
+	if config.MqttEvents.Enabled {
+		evSubID, events := eventsHdl.Subscribe(lib_model.DevicesFilter{})
+		util.Logger.Infof("%s publish events (subscription %s)", mqtt_hdl.LogPrefix, evSubID)
+		go mqttHdl.PublishEvents(events, config.MqttEvents.QOSLevel, config.MqttEvents.Retain)
+		wtchdg.RegisterStopFunc(func() error {
+			eventsHdl.Unsubscribe(evSubID)
+			return nil
+		})
+	}
+
+	var backupHdl handler.BackupHandler
+	if isSQLite {
+		backupHdl = backup_hdl.New(sqlDB, config.Database.Path)
+	}
+
+	mApi := api.New(deviceHdl, groupsHdl, eventsHdl, backupHdl, srvInfoHdl)
 
 	gin.SetMode(gin.ReleaseMode)
 	httpHandler := gin.New()
@@ -121,10 +221,11 @@ func main() {
 	}
 	httpHandler.Use(gin_mw.StaticHeaderHandler(staticHeader), requestid.New(requestid.WithCustomHeaderStrKey(lib_model.HeaderRequestID)), gin_mw.LoggerHandler(util.Logger, http_hdl.GetPathFilter(), func(gc *gin.Context) string {
 		return requestid.Get(gc)
-	}), gin_mw.ErrorHandler(util.GetStatusCode, ", "), gin.Recovery())
+	}), http_hdl.MetricsHandler(metricsHdl.ObserveHTTPRequest), http_hdl.RequestInfoHandler(config.AuditCallerHdr), gin_mw.ErrorHandler(util.GetStatusCode, ", "), gin.Recovery())
 	httpHandler.UseRawPath = true
 
 	http_hdl.SetRoutes(httpHandler, mApi)
+	http_hdl.SetMetricsRoute(httpHandler, metricsHdl.HTTPHandler())
 	util.Logger.Debugf("routes: %s", sb_util.ToJsonStr(http_hdl.GetRoutes(httpHandler)))
 
 	listener, err := net.Listen("tcp", ":"+strconv.FormatInt(int64(config.ServerPort), 10))
@@ -162,12 +263,24 @@ func main() {
 		return nil
 	})
 
-	if err = sql_db_hdl.InitDB(dbCtx, db, config.Database.SchemaPath, time.Second*5, time.Duration(config.Database.Timeout)); err != nil {
+	if sqlDB != nil {
+		if err = sql_db_hdl.InitDB(dbCtx, sqlDB, config.Database.SchemaPath, time.Second*5, time.Duration(config.Database.Timeout), migrator); err != nil {
+			util.Logger.Error(err)
+			ec = 1
+			return
+		}
+	}
+
+	if err = deviceHdl.RestoreStates(dbCtx); err != nil {
 		util.Logger.Error(err)
 		ec = 1
 		return
 	}
 
+	go deviceHdl.RunSweeper(dbCtx, time.Duration(config.DeviceTTL.CheckInterval), newTTLConfig(config.DeviceTTL))
+
+	go deviceHdl.RunTrashPurger(dbCtx, time.Duration(config.Trash.CheckInterval), time.Duration(config.Trash.PurgePeriod))
+
 	go func() {
 		defer srvCF()
 		util.Logger.Info("starting http server ...")
@@ -193,3 +306,26 @@ func main() {
 
 	ec = wtchdg.Join()
 }
+
+func openDB(c util.DatabaseConfig) (*sql.DB, error) {
+	if c.Driver == db.DriverPostgres {
+		return db.NewPostgres(c.DSN)
+	}
+	return db.New(c.Path)
+}
+
+func newTTLConfig(c util.DeviceTTLConfig) devices_hdl.TTLConfig {
+	ttlConf := devices_hdl.TTLConfig{
+		Default: time.Duration(c.Default),
+		Types:   make(map[string]time.Duration),
+		Refs:    make(map[string]time.Duration),
+		Stale:   c.Stale,
+	}
+	for typ, ttl := range c.Types {
+		ttlConf.Types[typ] = time.Duration(ttl)
+	}
+	for ref, ttl := range c.Refs {
+		ttlConf.Refs[ref] = time.Duration(ttl)
+	}
+	return ttlConf
+}
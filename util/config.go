@@ -9,9 +9,12 @@ import (
 )
 
 type DatabaseConfig struct {
-	Timeout    int64  `json:"timeout" env_var:"DB_TIMEOUT"`
-	Path       string `json:"path" env_var:"DB_PATH"`
-	SchemaPath string `json:"schema_path" env_var:"DB_SCHEMA_PATH"`
+	Timeout     int64  `json:"timeout" env_var:"DB_TIMEOUT"`
+	Driver      string `json:"driver" env_var:"DB_DRIVER"`
+	DSN         string `json:"dsn" env_var:"DB_DSN"`
+	Path        string `json:"path" env_var:"DB_PATH"`
+	SchemaPath  string `json:"schema_path" env_var:"DB_SCHEMA_PATH"`
+	RestorePath string `json:"restore_path" env_var:"DB_RESTORE_PATH"`
 }
 
 type MqttClientConfig struct {
@@ -25,6 +28,25 @@ type MqttClientConfig struct {
 	QOSLevel          byte   `json:"qos_level" env_var:"MQTT_QOS_LEVEL"`
 }
 
+type MqttEventsConfig struct {
+	Enabled  bool `json:"enabled" env_var:"MQTT_EVENTS_ENABLED"`
+	QOSLevel byte `json:"qos_level" env_var:"MQTT_EVENTS_QOS_LEVEL"`
+	Retain   bool `json:"retain" env_var:"MQTT_EVENTS_RETAIN"`
+}
+
+type DeviceTTLConfig struct {
+	Default       int64            `json:"default" env_var:"DEVICE_TTL_DEFAULT"`
+	Types         map[string]int64 `json:"types" env_var:"DEVICE_TTL_TYPES"`
+	Refs          map[string]int64 `json:"refs" env_var:"DEVICE_TTL_REFS"`
+	CheckInterval int64            `json:"check_interval" env_var:"DEVICE_TTL_CHECK_INTERVAL"`
+	Stale         bool             `json:"stale" env_var:"DEVICE_TTL_STALE"`
+}
+
+type TrashConfig struct {
+	PurgePeriod   int64 `json:"purge_period" env_var:"TRASH_PURGE_PERIOD"`
+	CheckInterval int64 `json:"check_interval" env_var:"TRASH_CHECK_INTERVAL"`
+}
+
 type LoggerConfig struct {
 	Level        level.Level `json:"level" env_var:"LOGGER_LEVEL"`
 	Utc          bool        `json:"utc" env_var:"LOGGER_UTC"`
@@ -39,11 +61,19 @@ type Config struct {
 	Logger          LoggerConfig     `json:"logger" env_var:"LOGGER_CONFIG"`
 	Database        DatabaseConfig   `json:"database" env_var:"DATABASE_CONFIG"`
 	MqttClient      MqttClientConfig `json:"mqtt_client" env_var:"MQTT_CLIENT_CONFIG"`
+	MqttEvents      MqttEventsConfig `json:"mqtt_events" env_var:"MQTT_EVENTS_CONFIG"`
 	MGWDeploymentID string           `json:"mgw_deployment_id" env_var:"MGW_DID"`
 	MQTTLog         bool             `json:"mqtt_log" env_var:"MQTT_LOG"`
 	MQTTDebugLog    bool             `json:"mqtt_debug_log" env_var:"MQTT_DEBUG_LOG"`
 	ServerPort      uint             `json:"server_port" env_var:"SERVER_PORT"`
 	MessageBuffer   int              `json:"message_buffer" env_var:"MESSAGE_BUFFER"`
+	MessageWorkers  int              `json:"message_workers" env_var:"MESSAGE_WORKERS"`
+	MessageQueue    string           `json:"message_queue" env_var:"MESSAGE_QUEUE"`
+	EventBuffer     int              `json:"event_buffer" env_var:"EVENT_BUFFER"`
+	DeviceTTL       DeviceTTLConfig  `json:"device_ttl" env_var:"DEVICE_TTL_CONFIG"`
+	SyncDelete      bool             `json:"sync_delete" env_var:"SYNC_DELETE"`
+	Trash           TrashConfig      `json:"trash" env_var:"TRASH_CONFIG"`
+	AuditCallerHdr  string           `json:"audit_caller_header" env_var:"AUDIT_CALLER_HEADER"`
 }
 
 var defaultMqttClientConfig = MqttClientConfig{
@@ -66,12 +96,26 @@ func NewConfig(path string) (*Config, error) {
 		},
 		Database: DatabaseConfig{
 			Timeout:    5000000000,
+			Driver:     "sqlite3",
 			Path:       "/opt/device-manager/data",
 			SchemaPath: "include/storage_schema.sql",
 		},
-		MqttClient:    defaultMqttClientConfig,
-		ServerPort:    80,
-		MessageBuffer: 50000,
+		MqttClient: defaultMqttClientConfig,
+		MqttEvents: MqttEventsConfig{
+			QOSLevel: 1,
+		},
+		ServerPort:     80,
+		MessageBuffer:  50000,
+		MessageWorkers: 4,
+		EventBuffer:    1000,
+		DeviceTTL: DeviceTTLConfig{
+			CheckInterval: 10000000000, // 10s
+		},
+		Trash: TrashConfig{
+			PurgePeriod:   2592000000000000, // 30d
+			CheckInterval: 3600000000000,    // 1h
+		},
+		AuditCallerHdr: "X-User-ID",
 	}
 	err := config_hdl.Load(&cfg, nil, map[reflect.Type]envldr.Parser{reflect.TypeOf(level.Off): sb_logger.LevelParser}, nil, path)
 	return &cfg, err
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/mgw-device-manager/util"
+	"io"
+	"os"
+	"path"
+	"time"
+)
+
+// Backup writes a consistent snapshot of the database to dst, the file must not exist.
+func Backup(ctx context.Context, db *sql.DB, dst string) error {
+	_, err := db.ExecContext(ctx, "VACUUM INTO ?;", dst)
+	return err
+}
+
+// Restore replaces the database in directory p with the snapshot after checking its integrity and that its schema
+// version does not exceed the latest supported version. The replaced database is kept as '<name>.<timestamp>.bak' and
+// the snapshot is renamed to '<snapshot>.restored' to prevent repeated restores, failing to rename the snapshot only
+// logs a warning. Must be called before the database is opened.
+func Restore(p, snapshot string, latest int) error {
+	if err := checkSnapshot(snapshot, latest); err != nil {
+		return fmt.Errorf("check snapshot: %s", err)
+	}
+	dbPath := path.Join(p, fileName)
+	tmpPath := dbPath + ".restore"
+	if err := copyFile(snapshot, tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("copy snapshot: %s", err)
+	}
+	if _, err := os.Stat(dbPath); err == nil {
+		if err = os.Rename(dbPath, fmt.Sprintf("%s.%s.bak", dbPath, time.Now().UTC().Format("20060102T150405Z"))); err != nil {
+			_ = os.Remove(tmpPath)
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	for _, suffix := range []string{"-journal", "-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(tmpPath, dbPath); err != nil {
+		return err
+	}
+	if err := os.Rename(snapshot, snapshot+".restored"); err != nil {
+		util.Logger.Warningf("rename restored snapshot: %s", err)
+	}
+	return nil
+}
+
+// checkSnapshot runs an integrity check and verifies the snapshot holds a migrated schema not newer than latest.
+func checkSnapshot(snapshot string, latest int) error {
+	if _, err := os.Stat(snapshot); err != nil {
+		return err
+	}
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro", snapshot))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	rows, err := db.Query("PRAGMA integrity_check;")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var results []string
+	for rows.Next() {
+		var res string
+		if err = rows.Scan(&res); err != nil {
+			return err
+		}
+		results = append(results, res)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if len(results) != 1 || results[0] != "ok" {
+		return fmt.Errorf("integrity check failed: %v", results)
+	}
+	var version sql.NullInt64
+	if err = db.QueryRow("SELECT MAX(version) FROM schema_version;").Scan(&version); err != nil {
+		return err
+	}
+	if !version.Valid {
+		return errors.New("no schema version")
+	}
+	if int(version.Int64) > latest {
+		return fmt.Errorf("schema version %d newer than supported version %d", version.Int64, latest)
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+	dstFile, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer dstFile.Close()
+	if _, err = io.Copy(dstFile, srcFile); err != nil {
+		return err
+	}
+	return dstFile.Sync()
+}
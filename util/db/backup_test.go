@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/mgw-device-manager/util"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestBackupRestore(t *testing.T) {
+	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
+	srcDir := t.TempDir()
+	srcDB, err := New(srcDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srcDB.Close()
+	for _, q := range []string{
+		"CREATE TABLE schema_version (version INTEGER NOT NULL);",
+		"INSERT INTO schema_version (version) VALUES (1);",
+		"CREATE TABLE a (id TEXT NOT NULL);",
+		"INSERT INTO a (id) VALUES ('test');",
+	} {
+		if _, err = srcDB.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snapshot := path.Join(t.TempDir(), "snapshot.db")
+	if err = Backup(context.Background(), srcDB, snapshot); err != nil {
+		t.Fatal(err)
+	}
+	t.Run("snapshot newer", func(t *testing.T) {
+		dstDir := t.TempDir()
+		if err := os.WriteFile(path.Join(dstDir, fileName), []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := Restore(dstDir, snapshot, 0); err == nil {
+			t.Error("expected error")
+		}
+		entries, err := os.ReadDir(dstDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].Name() != fileName {
+			t.Error("database must not be touched, got", entries)
+		}
+	})
+	t.Run("restore", func(t *testing.T) {
+		dstDir := t.TempDir()
+		if err := os.WriteFile(path.Join(dstDir, fileName), []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := Restore(dstDir, snapshot, 1); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(snapshot + ".restored"); err != nil {
+			t.Error(err)
+		}
+		entries, err := os.ReadDir(dstDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 {
+			t.Error("expected database and backup of replaced database, got", entries)
+		}
+		dstDB, err := New(dstDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer dstDB.Close()
+		var id string
+		if err = dstDB.QueryRow("SELECT id FROM a;").Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		if id != "test" {
+			t.Error("expected test, got", id)
+		}
+	})
+	t.Run("rename snapshot fails", func(t *testing.T) {
+		snapshot2 := path.Join(t.TempDir(), "snapshot.db")
+		if err := Backup(context.Background(), srcDB, snapshot2); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(path.Join(snapshot2+".restored", "test"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		dstDir := t.TempDir()
+		if err := Restore(dstDir, snapshot2, 1); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(path.Join(dstDir, fileName)); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("invalid snapshot", func(t *testing.T) {
+		dstDir := t.TempDir()
+		invalid := path.Join(t.TempDir(), "invalid.db")
+		if err := os.WriteFile(invalid, []byte("invalid"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := Restore(dstDir, invalid, 1); err == nil {
+			t.Error("expected error")
+		}
+		if _, err := os.Stat(path.Join(dstDir, fileName)); err == nil {
+			t.Error("database must not be replaced")
+		}
+	})
+	t.Run("missing schema", func(t *testing.T) {
+		emptyDB, err := New(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer emptyDB.Close()
+		if _, err = emptyDB.Exec("CREATE TABLE a (id TEXT NOT NULL);"); err != nil {
+			t.Fatal(err)
+		}
+		emptySnapshot := path.Join(t.TempDir(), "snapshot.db")
+		if err = Backup(context.Background(), emptyDB, emptySnapshot); err != nil {
+			t.Fatal(err)
+		}
+		if err = Restore(t.TempDir(), emptySnapshot, 1); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
@@ -19,13 +19,27 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 	"path"
 	"time"
 )
 
+const fileName = "sqlite3.db"
+
 func New(p string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on&mode=rwc", path.Join(p, "sqlite3.db")))
+	db, err := sql.Open(DriverSQLite, fmt.Sprintf("file:%s?_foreign_keys=on&mode=rwc", path.Join(p, fileName)))
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(25)
+	db.SetConnMaxLifetime(5 * time.Minute)
+	return db, nil
+}
+
+func NewPostgres(dsn string) (*sql.DB, error) {
+	db, err := sql.Open(DriverPostgres, dsn)
 	if err != nil {
 		return nil, err
 	}
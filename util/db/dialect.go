@@ -0,0 +1,36 @@
+package db
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	DriverSQLite   = "sqlite3"
+	DriverPostgres = "postgres"
+	DriverMemory   = "memory" // no database, data is kept in memory by mem_storage_hdl
+)
+
+// Rebind replaces '?' placeholders outside of string literals with the positional placeholders required by the driver.
+func Rebind(driver, query string) string {
+	if driver != DriverPostgres {
+		return query
+	}
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+	n := 0
+	inLiteral := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			inLiteral = !inLiteral
+		case r == '?' && !inLiteral:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
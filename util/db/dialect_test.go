@@ -0,0 +1,32 @@
+package db
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	q := "SELECT id FROM devices WHERE name = ? AND type = 'a?b' AND ref = ?;"
+	if s := Rebind(DriverSQLite, q); s != q {
+		t.Error("expected unchanged query, got", s)
+	}
+	if s := Rebind(DriverPostgres, q); s != "SELECT id FROM devices WHERE name = $1 AND type = 'a?b' AND ref = $2;" {
+		t.Error("invalid query", s)
+	}
+}
+
+func TestMigrationsInSync(t *testing.T) {
+	sqliteMigrations, err := readMigrations(SQLiteMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	postgresMigrations, err := readMigrations(PostgresMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sqliteMigrations) != len(postgresMigrations) {
+		t.Fatalf("expected %d postgres migrations, got %d", len(sqliteMigrations), len(postgresMigrations))
+	}
+	for i, mig := range sqliteMigrations {
+		if postgresMigrations[i].name != mig.name {
+			t.Errorf("expected postgres migration %d to be named '%s', got '%s'", mig.version, mig.name, postgresMigrations[i].name)
+		}
+	}
+}
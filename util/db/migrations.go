@@ -0,0 +1,264 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/mgw-device-manager/util"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations
+var migrationsFS embed.FS
+
+var SQLiteMigrations = mustSub(migrationsFS, "migrations/sqlite")
+
+var PostgresMigrations = mustSub(migrationsFS, "migrations/postgres")
+
+const requiresPrefix = "-- requires: "
+
+// requirements maps features named in a '-- requires: <feature>' first line of a migration to a query reporting
+// whether the database supports the feature. Migrations requiring an unsupported feature are skipped and recorded in
+// the schema_skipped table, they are applied as soon as the feature is supported.
+var requirements = map[string]string{
+	"fts5": "SELECT sqlite_compileoption_used('ENABLE_FTS5');",
+}
+
+type migration struct {
+	version  int
+	name     string
+	query    string
+	requires string
+}
+
+// Migrator applies numbered up-migrations and tracks the current version in the schema_version table.
+// Files must be named '<version>_<name>.sql' with versions starting at 1 and incrementing without gaps.
+type Migrator struct {
+	migrations []migration
+	driver     string
+}
+
+func NewMigrator(fSys fs.FS, driver string) (*Migrator, error) {
+	migrations, err := readMigrations(fSys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{migrations: migrations, driver: driver}, nil
+}
+
+// Latest returns the schema version reached after applying all migrations.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].version
+}
+
+// Required returns an error if the database schema version is newer than the latest known migration.
+func (m *Migrator) Required(ctx context.Context, db *sql.DB, timeout time.Duration) (bool, error) {
+	ctxWt, cf := context.WithTimeout(ctx, timeout)
+	defer cf()
+	version, err := getVersion(ctxWt, db)
+	if err != nil {
+		return false, err
+	}
+	if version > m.Latest() {
+		return false, fmt.Errorf("database schema version %d newer than supported version %d", version, m.Latest())
+	}
+	if version < m.Latest() {
+		return true, nil
+	}
+	skipped, err := m.supportedSkipped(ctx, db, timeout)
+	if err != nil {
+		return false, err
+	}
+	return len(skipped) > 0, nil
+}
+
+// Run applies previously skipped migrations with supported requirements and all pending migrations, each in a
+// separate transaction.
+func (m *Migrator) Run(ctx context.Context, db *sql.DB, timeout time.Duration) error {
+	skipped, err := m.supportedSkipped(ctx, db, timeout)
+	if err != nil {
+		return err
+	}
+	for _, mig := range skipped {
+		util.Logger.Infof("apply skipped database migration %d (%s)", mig.version, mig.name)
+		err = execStmts(ctx, db, timeout, stmt{query: mig.query}, stmt{query: Rebind(m.driver, "DELETE FROM schema_skipped WHERE version = ?;"), args: []any{mig.version}})
+		if err != nil {
+			return fmt.Errorf("apply skipped database migration %d (%s): %s", mig.version, mig.name, err)
+		}
+	}
+	ctxWt, cf := context.WithTimeout(ctx, timeout)
+	defer cf()
+	version, err := getVersion(ctxWt, db)
+	if err != nil {
+		return err
+	}
+	for _, mig := range m.migrations {
+		if mig.version <= version {
+			continue
+		}
+		timestamp := time.Now().UTC().Format(time.RFC3339Nano)
+		versionStmt := stmt{query: Rebind(m.driver, "INSERT INTO schema_version (version, name, applied) VALUES (?, ?, ?);"), args: []any{mig.version, mig.name, timestamp}}
+		if mig.requires != "" {
+			ok, err := supports(ctx, db, timeout, mig.requires)
+			if err != nil {
+				return fmt.Errorf("apply database migration %d (%s): %s", mig.version, mig.name, err)
+			}
+			if !ok {
+				util.Logger.Warningf("skip database migration %d (%s): %s not supported", mig.version, mig.name, mig.requires)
+				err = execStmts(ctx, db, timeout, versionStmt, stmt{query: Rebind(m.driver, "INSERT INTO schema_skipped (version, name, skipped) VALUES (?, ?, ?);"), args: []any{mig.version, mig.name, timestamp}})
+				if err != nil {
+					return fmt.Errorf("skip database migration %d (%s): %s", mig.version, mig.name, err)
+				}
+				continue
+			}
+		}
+		util.Logger.Infof("apply database migration %d (%s)", mig.version, mig.name)
+		if err = execStmts(ctx, db, timeout, stmt{query: mig.query}, versionStmt); err != nil {
+			return fmt.Errorf("apply database migration %d (%s): %s", mig.version, mig.name, err)
+		}
+	}
+	return nil
+}
+
+// supportedSkipped returns the skipped migrations whose requirement is supported by now.
+func (m *Migrator) supportedSkipped(ctx context.Context, db *sql.DB, timeout time.Duration) ([]migration, error) {
+	ctxWt, cf := context.WithTimeout(ctx, timeout)
+	defer cf()
+	rows, err := db.QueryContext(ctxWt, "SELECT version FROM schema_skipped ORDER BY version;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var versions []int
+	for rows.Next() {
+		var version int
+		if err = rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	var migrations []migration
+	for _, version := range versions {
+		if version < 1 || version > len(m.migrations) {
+			return nil, fmt.Errorf("unknown skipped database migration %d", version)
+		}
+		mig := m.migrations[version-1]
+		if mig.requires != "" {
+			ok, err := supports(ctx, db, timeout, mig.requires)
+			if err != nil {
+				return nil, fmt.Errorf("check skipped database migration %d (%s): %s", mig.version, mig.name, err)
+			}
+			if !ok {
+				continue
+			}
+		}
+		migrations = append(migrations, mig)
+	}
+	return migrations, nil
+}
+
+type stmt struct {
+	query string
+	args  []any
+}
+
+func execStmts(ctx context.Context, db *sql.DB, timeout time.Duration, stmts ...stmt) error {
+	ctxWt, cf := context.WithTimeout(ctx, timeout)
+	defer cf()
+	tx, err := db.BeginTx(ctxWt, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, s := range stmts {
+		if _, err = tx.ExecContext(ctxWt, s.query, s.args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func supports(ctx context.Context, db *sql.DB, timeout time.Duration, feature string) (bool, error) {
+	ctxWt, cf := context.WithTimeout(ctx, timeout)
+	defer cf()
+	var ok bool
+	if err := db.QueryRowContext(ctxWt, requirements[feature]).Scan(&ok); err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+func getVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version sql.NullInt64
+	if err := db.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_version;").Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+func readMigrations(fSys fs.FS) ([]migration, error) {
+	entries, err := fs.ReadDir(fSys, ".")
+	if err != nil {
+		return nil, err
+	}
+	var migrations []migration
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		vStr, name, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name '%s'", entry.Name())
+		}
+		version, err := strconv.Atoi(vStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration file name '%s': %s", entry.Name(), err)
+		}
+		b, err := fs.ReadFile(fSys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		mig := migration{
+			version: version,
+			name:    name,
+			query:   string(b),
+		}
+		if line, _, _ := strings.Cut(mig.query, "\n"); strings.HasPrefix(line, requiresPrefix) {
+			mig.requires = strings.TrimSpace(strings.TrimPrefix(line, requiresPrefix))
+			if _, ok := requirements[mig.requires]; !ok {
+				return nil, fmt.Errorf("invalid migration file '%s': unknown requirement '%s'", entry.Name(), mig.requires)
+			}
+		}
+		migrations = append(migrations, mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	for i, mig := range migrations {
+		if mig.version != i+1 {
+			return nil, errors.New("migration versions must start at 1 and increment without gaps")
+		}
+	}
+	return migrations, nil
+}
+
+func mustSub(fSys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fSys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS devices
+(
+    id          TEXT COLLATE "C" NOT NULL,
+    ref         TEXT             NOT NULL,
+    name        TEXT COLLATE "C" DEFAULT '',
+    type        TEXT COLLATE "C" NOT NULL,
+    created     TEXT COLLATE "C" NOT NULL,
+    updated     TEXT COLLATE "C" DEFAULT '',
+    usr_name    TEXT COLLATE "C" DEFAULT '',
+    usr_updated TEXT             DEFAULT '',
+    PRIMARY KEY (id)
+);
+CREATE TABLE IF NOT EXISTS device_attributes
+(
+    dev_id   TEXT    NOT NULL,
+    is_usr   BOOLEAN NOT NULL,
+    key_name TEXT    NOT NULL,
+    value    TEXT DEFAULT '',
+    FOREIGN KEY (dev_id) REFERENCES devices (id) ON DELETE CASCADE ON UPDATE RESTRICT
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS devices_search
+(
+    dev_id   TEXT     NOT NULL,
+    document TSVECTOR NOT NULL,
+    PRIMARY KEY (dev_id)
+);
+CREATE INDEX IF NOT EXISTS devices_search_document ON devices_search USING GIN (document);
+INSERT INTO devices_search (dev_id, document)
+SELECT id, to_tsvector('simple', concat_ws(' ', id, name, usr_name, type, (SELECT string_agg(value, ' ') FROM device_attributes WHERE dev_id = devices.id)))
+FROM devices;
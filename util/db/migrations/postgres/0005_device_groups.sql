@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS device_groups
+(
+    id          TEXT COLLATE "C" NOT NULL,
+    name        TEXT COLLATE "C" NOT NULL,
+    description TEXT DEFAULT '',
+    created     TEXT             NOT NULL,
+    updated     TEXT DEFAULT '',
+    PRIMARY KEY (id)
+);
+CREATE TABLE IF NOT EXISTS device_group_members
+(
+    group_id TEXT COLLATE "C" NOT NULL,
+    dev_id   TEXT COLLATE "C" NOT NULL,
+    PRIMARY KEY (group_id, dev_id),
+    FOREIGN KEY (group_id) REFERENCES device_groups (id) ON DELETE CASCADE ON UPDATE RESTRICT,
+    FOREIGN KEY (dev_id) REFERENCES devices (id) ON DELETE CASCADE ON UPDATE RESTRICT
+);
+CREATE INDEX IF NOT EXISTS device_group_members_dev_id ON device_group_members (dev_id);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS devices_trash
+(
+    id             TEXT COLLATE "C" NOT NULL,
+    ref            TEXT             NOT NULL,
+    name           TEXT DEFAULT '',
+    type           TEXT             NOT NULL,
+    attributes     TEXT DEFAULT '',
+    created        TEXT             NOT NULL,
+    updated        TEXT DEFAULT '',
+    usr_name       TEXT DEFAULT '',
+    usr_attributes TEXT DEFAULT '',
+    usr_updated    TEXT DEFAULT '',
+    deleted        TEXT COLLATE "C" NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS devices_trash_deleted ON devices_trash (deleted);
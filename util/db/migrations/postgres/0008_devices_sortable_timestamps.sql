@@ -0,0 +1,12 @@
+UPDATE devices
+SET created = to_char(regexp_replace(created, '\.[0-9]+', '')::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS') || '.' ||
+              rpad(coalesce(substring(created from '\.([0-9]+)'), ''), 9, '0') || 'Z'
+WHERE created <> '';
+UPDATE devices
+SET updated = to_char(regexp_replace(updated, '\.[0-9]+', '')::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS') || '.' ||
+              rpad(coalesce(substring(updated from '\.([0-9]+)'), ''), 9, '0') || 'Z'
+WHERE updated <> '';
+UPDATE devices
+SET usr_updated = to_char(regexp_replace(usr_updated, '\.[0-9]+', '')::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS') || '.' ||
+                  rpad(coalesce(substring(usr_updated from '\.([0-9]+)'), ''), 9, '0') || 'Z'
+WHERE usr_updated <> '';
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS device_states
+(
+    dev_id    TEXT NOT NULL,
+    state     TEXT DEFAULT '',
+    updated   TEXT DEFAULT '',
+    last_seen TEXT DEFAULT '',
+    PRIMARY KEY (dev_id),
+    FOREIGN KEY (dev_id) REFERENCES devices (id) ON DELETE CASCADE ON UPDATE RESTRICT
+);
+CREATE TABLE IF NOT EXISTS device_state_history
+(
+    dev_id    TEXT NOT NULL,
+    state     TEXT DEFAULT '',
+    source    TEXT NOT NULL,
+    timestamp TEXT NOT NULL,
+    FOREIGN KEY (dev_id) REFERENCES devices (id) ON DELETE CASCADE ON UPDATE RESTRICT
+);
+CREATE INDEX IF NOT EXISTS device_state_history_dev_id_timestamp ON device_state_history (dev_id, timestamp);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS device_attributes_dev_id_key_name ON device_attributes (dev_id, is_usr, key_name);
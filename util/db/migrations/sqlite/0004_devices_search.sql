@@ -0,0 +1,12 @@
+-- requires: fts5
+CREATE VIRTUAL TABLE IF NOT EXISTS devices_search USING fts5
+(
+    dev_id,
+    name,
+    usr_name,
+    type,
+    attributes
+);
+INSERT INTO devices_search (dev_id, name, usr_name, type, attributes)
+SELECT id, name, usr_name, type, (SELECT group_concat(value, ' ') FROM device_attributes WHERE dev_id = devices.id)
+FROM devices;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS device_groups
+(
+    id          TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    description TEXT DEFAULT '',
+    created     TEXT NOT NULL,
+    updated     TEXT DEFAULT '',
+    PRIMARY KEY (id)
+);
+CREATE TABLE IF NOT EXISTS device_group_members
+(
+    group_id TEXT NOT NULL,
+    dev_id   TEXT NOT NULL,
+    PRIMARY KEY (group_id, dev_id),
+    FOREIGN KEY (group_id) REFERENCES device_groups (id) ON DELETE CASCADE ON UPDATE RESTRICT,
+    FOREIGN KEY (dev_id) REFERENCES devices (id) ON DELETE CASCADE ON UPDATE RESTRICT
+);
+CREATE INDEX IF NOT EXISTS device_group_members_dev_id ON device_group_members (dev_id);
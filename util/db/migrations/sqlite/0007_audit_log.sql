@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS audit_log
+(
+    id         INTEGER NOT NULL,
+    timestamp  TEXT    NOT NULL,
+    request_id TEXT DEFAULT '',
+    caller     TEXT DEFAULT '',
+    action     TEXT    NOT NULL,
+    dev_id     TEXT    NOT NULL,
+    before     TEXT,
+    after      TEXT,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS audit_log_timestamp ON audit_log (timestamp);
+CREATE INDEX IF NOT EXISTS audit_log_dev_id_timestamp ON audit_log (dev_id, timestamp);
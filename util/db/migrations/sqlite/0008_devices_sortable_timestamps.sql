@@ -0,0 +1,12 @@
+UPDATE devices
+SET created = strftime('%Y-%m-%dT%H:%M:%S', substr(created, 1, 19) || CASE WHEN substr(created, -1) = 'Z' THEN 'Z' ELSE substr(created, -6) END) || '.' ||
+              substr(CASE WHEN substr(created, 20, 1) = '.' THEN substr(created, 21, length(created) - CASE WHEN substr(created, -1) = 'Z' THEN 21 ELSE 26 END) ELSE '' END || '000000000', 1, 9) || 'Z'
+WHERE created <> '';
+UPDATE devices
+SET updated = strftime('%Y-%m-%dT%H:%M:%S', substr(updated, 1, 19) || CASE WHEN substr(updated, -1) = 'Z' THEN 'Z' ELSE substr(updated, -6) END) || '.' ||
+              substr(CASE WHEN substr(updated, 20, 1) = '.' THEN substr(updated, 21, length(updated) - CASE WHEN substr(updated, -1) = 'Z' THEN 21 ELSE 26 END) ELSE '' END || '000000000', 1, 9) || 'Z'
+WHERE updated <> '';
+UPDATE devices
+SET usr_updated = strftime('%Y-%m-%dT%H:%M:%S', substr(usr_updated, 1, 19) || CASE WHEN substr(usr_updated, -1) = 'Z' THEN 'Z' ELSE substr(usr_updated, -6) END) || '.' ||
+                  substr(CASE WHEN substr(usr_updated, 20, 1) = '.' THEN substr(usr_updated, 21, length(usr_updated) - CASE WHEN substr(usr_updated, -1) = 'Z' THEN 21 ELSE 26 END) ELSE '' END || '000000000', 1, 9) || 'Z'
+WHERE usr_updated <> '';
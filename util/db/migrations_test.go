@@ -0,0 +1,190 @@
+package db
+
+import (
+	"context"
+	sql_db_hdl "github.com/SENERGY-Platform/go-service-base/sql-db-hdl"
+	"github.com/SENERGY-Platform/mgw-device-manager/util"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestMigrator(t *testing.T) {
+	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
+	testDB, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer testDB.Close()
+	fSys := fstest.MapFS{
+		"0001_a.sql": {Data: []byte("CREATE TABLE a (id TEXT NOT NULL); CREATE TABLE b (id TEXT NOT NULL);")},
+	}
+	m, err := NewMigrator(fSys, DriverSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sql_db_hdl.InitDB(context.Background(), testDB, "../../include/storage_schema.sql", time.Second, time.Second, m); err != nil {
+		t.Fatal(err)
+	}
+	version, err := getVersion(context.Background(), testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 1 {
+		t.Error("expected version 1, got", version)
+	}
+	t.Run("failed migration is rolled back", func(t *testing.T) {
+		fSys["0002_b.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE c (id TEXT NOT NULL); CREATE TABLE a (id TEXT NOT NULL);")}
+		m, err = NewMigrator(fSys, DriverSQLite)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = sql_db_hdl.InitDB(context.Background(), testDB, "../../include/storage_schema.sql", time.Second, time.Second, m); err == nil {
+			t.Error("expected error")
+		}
+		if _, err = testDB.Exec("SELECT * FROM c;"); err == nil {
+			t.Error("expected error")
+		}
+		version, err = getVersion(context.Background(), testDB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != 1 {
+			t.Error("expected version 1, got", version)
+		}
+	})
+	t.Run("database newer", func(t *testing.T) {
+		m, err = NewMigrator(fstest.MapFS{}, DriverSQLite)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = m.Required(context.Background(), testDB, time.Second); err == nil {
+			t.Error("expected error")
+		}
+	})
+	t.Run("unsupported requirement", func(t *testing.T) {
+		requirements["test"] = "SELECT 0;"
+		defer delete(requirements, "test")
+		fSys["0002_b.sql"] = &fstest.MapFile{Data: []byte("-- requires: test\nCREATE TABLE c (id TEXT NOT NULL);")}
+		m, err = NewMigrator(fSys, DriverSQLite)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = sql_db_hdl.InitDB(context.Background(), testDB, "../../include/storage_schema.sql", time.Second, time.Second, m); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = testDB.Exec("SELECT * FROM c;"); err == nil {
+			t.Error("expected error")
+		}
+		version, err = getVersion(context.Background(), testDB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != 2 {
+			t.Error("expected version 2, got", version)
+		}
+		t.Run("requirement supported later", func(t *testing.T) {
+			requirements["test"] = "SELECT 1;"
+			if ok, err := m.Required(context.Background(), testDB, time.Second); err != nil {
+				t.Fatal(err)
+			} else if !ok {
+				t.Error("expected skipped migration to be required")
+			}
+			if err = sql_db_hdl.InitDB(context.Background(), testDB, "../../include/storage_schema.sql", time.Second, time.Second, m); err != nil {
+				t.Fatal(err)
+			}
+			if _, err = testDB.Exec("SELECT * FROM c;"); err != nil {
+				t.Error(err)
+			}
+			var n int
+			if err = testDB.QueryRow("SELECT COUNT(*) FROM schema_skipped;").Scan(&n); err != nil {
+				t.Fatal(err)
+			}
+			if n != 0 {
+				t.Error("expected no skipped migrations, got", n)
+			}
+			if ok, err := m.Required(context.Background(), testDB, time.Second); err != nil {
+				t.Fatal(err)
+			} else if ok {
+				t.Error("expected no required migrations")
+			}
+		})
+	})
+	t.Run("embedded", func(t *testing.T) {
+		if _, err = NewMigrator(SQLiteMigrations, DriverSQLite); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func Test_readMigrations(t *testing.T) {
+	t.Run("gap", func(t *testing.T) {
+		_, err := readMigrations(fstest.MapFS{
+			"0001_a.sql": {},
+			"0003_b.sql": {},
+		})
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+	t.Run("unknown requirement", func(t *testing.T) {
+		_, err := readMigrations(fstest.MapFS{"0001_a.sql": {Data: []byte("-- requires: test\nSELECT 1;")}})
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+	t.Run("invalid name", func(t *testing.T) {
+		_, err := readMigrations(fstest.MapFS{"a.sql": {}})
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestMigration_devicesSortableTimestamps(t *testing.T) {
+	util.InitLogger(util.LoggerConfig{Terminal: true, Level: 4})
+	testDB, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer testDB.Close()
+	fSys := fstest.MapFS{}
+	for _, name := range []string{"0001_devices.sql", "0002_device_states.sql", "0003_device_attributes_index.sql", "0004_devices_search.sql", "0005_device_groups.sql", "0006_devices_trash.sql", "0007_audit_log.sql"} {
+		b, err := fs.ReadFile(SQLiteMigrations, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fSys[name] = &fstest.MapFile{Data: b}
+	}
+	m, err := NewMigrator(fSys, DriverSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sql_db_hdl.InitDB(context.Background(), testDB, "../../include/storage_schema.sql", time.Second, time.Second, m); err != nil {
+		t.Fatal(err)
+	}
+	_, err = testDB.Exec("INSERT INTO devices (id, ref, name, type, created, updated, usr_updated) VALUES ('a', 'r', '', 't', '2024-01-02T03:04:05.45Z', '2024-01-02T05:04:05.4+02:00', ''), ('b', 'r', '', 't', '2024-01-02T03:04:05Z', '2024-01-01T23:59:59.999999999-01:00', '2024-01-02T03:04:05.123456789Z');")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, err = NewMigrator(SQLiteMigrations, DriverSQLite); err != nil {
+		t.Fatal(err)
+	}
+	if err = sql_db_hdl.InitDB(context.Background(), testDB, "../../include/storage_schema.sql", time.Second, time.Second, m); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][3]string{
+		"a": {"2024-01-02T03:04:05.450000000Z", "2024-01-02T03:04:05.400000000Z", ""},
+		"b": {"2024-01-02T03:04:05.000000000Z", "2024-01-02T00:59:59.999999999Z", "2024-01-02T03:04:05.123456789Z"},
+	}
+	for id, values := range expected {
+		var v [3]string
+		if err = testDB.QueryRow("SELECT created, updated, usr_updated FROM devices WHERE id = ?;", id).Scan(&v[0], &v[1], &v[2]); err != nil {
+			t.Fatal(err)
+		}
+		if v != values {
+			t.Error("expected", values, "got", v)
+		}
+	}
+}
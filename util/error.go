@@ -23,6 +23,10 @@ func GetStatusCode(err error) int {
 	if errors.As(err, &rbe) {
 		return http.StatusConflict
 	}
+	var pfe *lib_model.PreconditionFailedError
+	if errors.As(err, &pfe) {
+		return http.StatusPreconditionFailed
+	}
 	var ie *lib_model.InternalError
 	if errors.As(err, &ie) {
 		return http.StatusInternalServerError
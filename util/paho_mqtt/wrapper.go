@@ -60,6 +60,10 @@ func (w *Wrapper) Connect() mqtt.Token {
 	return w.client.Connect()
 }
 
+func (w *Wrapper) IsConnected() bool {
+	return w.client.IsConnectionOpen()
+}
+
 func (w *Wrapper) Disconnect(quiesce uint) {
 	w.client.Disconnect(quiesce)
 }
@@ -0,0 +1,20 @@
+package util
+
+import "context"
+
+type requestInfoCtxKey struct{}
+
+type RequestInfo struct {
+	ID     string
+	Caller string
+}
+
+func ContextWithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoCtxKey{}, info)
+}
+
+// RequestInfoFromContext returns the request info of user-initiated calls, e.g. received via the http api.
+func RequestInfoFromContext(ctx context.Context) (RequestInfo, bool) {
+	info, ok := ctx.Value(requestInfoCtxKey{}).(RequestInfo)
+	return info, ok
+}
@@ -1,7 +1,18 @@
 package topic
 
+import "strings"
+
 const (
 	DevicesSub  = "device-manager/device/+"
 	LastWillSub = "device-manager/device/+/lw"
 	RefreshPub  = "device-manager/refresh"
+	EventsPub   = "device-manager/events/%s"
+	BatchResPub = "device-manager/device/%s/batch-result"
 )
+
+var levelEscaper = strings.NewReplacer("%", "%25", "/", "%2F", "+", "%2B", "#", "%23")
+
+// EscapeLevel percent-encodes the level separator and wildcards so s can be used as a single topic level.
+func EscapeLevel(s string) string {
+	return levelEscaper.Replace(s)
+}
@@ -0,0 +1,17 @@
+package topic
+
+import "testing"
+
+func TestEscapeLevel(t *testing.T) {
+	tests := map[string]string{
+		"test":      "test",
+		"a/b":       "a%2Fb",
+		"+/#":       "%2B%2F%23",
+		"100%/test": "100%25%2Ftest",
+	}
+	for s, expected := range tests {
+		if e := EscapeLevel(s); e != expected {
+			t.Error("expected", expected, "got", e)
+		}
+	}
+}